@@ -0,0 +1,191 @@
+// Command gocc encrypts, decrypts and inspects containers from the command
+// line.
+//
+// Usage:
+//
+//	gocc encrypt [-cipher name] [-kdf name] < plaintext > container.json
+//	gocc decrypt < container.json > plaintext
+//	gocc inspect < container.json
+//
+// The password is read from the GOCC_PASSWORD environment variable or, when
+// that is unset, prompted for on the terminal. It is never accepted as an
+// argument, where other users could see it in the process list.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/muzonff/go-crypto-container/container"
+	"golang.org/x/term"
+)
+
+const passwordEnv = "GOCC_PASSWORD"
+
+// errUsage reports a command line that has already been explained on stderr.
+var errUsage = errors.New("usage error")
+
+const usage = `usage:
+  gocc encrypt [-cipher name] [-kdf name] < plaintext > container.json
+  gocc decrypt < container.json > plaintext
+  gocc inspect < container.json
+
+The password is read from $GOCC_PASSWORD or prompted for on the terminal.
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run executes the subcommand in args and returns the process exit code.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	var err error
+	switch args[0] {
+	case "encrypt":
+		err = encrypt(args[1:], stdin, stdout, stderr)
+	case "decrypt":
+		err = decrypt(args[1:], stdin, stdout, stderr)
+	case "inspect":
+		err = inspect(args[1:], stdin, stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "gocc: unknown command %q\n%s", args[0], usage)
+		return 2
+	}
+	if errors.Is(err, errUsage) {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "gocc %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+func encrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("encrypt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	cipherName := fs.String("cipher", container.CipherAES256CTR, "cipher `name`")
+	kdf := fs.String("kdf", container.KDFPBKDF2, "key derivation function `name`")
+	if err := fs.Parse(args); err != nil {
+		return errUsage
+	}
+	if err := noArgs(fs.Args(), stderr); err != nil {
+		return err
+	}
+
+	password, err := readPassword(true)
+	if err != nil {
+		return err
+	}
+	plaintext, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	containerJSON, err := container.CreateContainerBytes(plaintext, password,
+		container.WithCipher(*cipherName), container.WithKDF(*kdf))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "%s\n", containerJSON)
+	return err
+}
+
+func decrypt(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if err := noArgs(args, stderr); err != nil {
+		return err
+	}
+	containerJSON, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	password, err := readPassword(false)
+	if err != nil {
+		return err
+	}
+	plaintext, err := container.Decrypt(string(containerJSON), password)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(stdout, plaintext)
+	return err
+}
+
+func inspect(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	if err := noArgs(args, stderr); err != nil {
+		return err
+	}
+	containerJSON, err := io.ReadAll(stdin)
+	if err != nil {
+		return err
+	}
+	header, err := container.ParseHeader(string(containerJSON))
+	if err != nil {
+		return err
+	}
+	out, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(stdout, "%s\n", out)
+	return err
+}
+
+// noArgs reports errUsage if any positional arguments were given.
+func noArgs(args []string, stderr io.Writer) error {
+	if len(args) == 0 {
+		return nil
+	}
+	fmt.Fprintf(stderr, "gocc: unexpected arguments: %v\n%s", args, usage)
+	return errUsage
+}
+
+// readPassword returns $GOCC_PASSWORD if it is set and otherwise prompts for
+// the password on the terminal, twice when confirm is set.
+func readPassword(confirm bool) (string, error) {
+	if password, ok := os.LookupEnv(passwordEnv); ok {
+		return password, nil
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no password: set %s or run from a terminal", passwordEnv)
+	}
+	defer tty.Close()
+
+	password, err := prompt(tty, "Password: ")
+	if err != nil {
+		return "", err
+	}
+	if confirm {
+		again, err := prompt(tty, "Confirm password: ")
+		if err != nil {
+			return "", err
+		}
+		if again != password {
+			return "", errors.New("passwords do not match")
+		}
+	}
+	return password, nil
+}
+
+func prompt(tty *os.File, label string) (string, error) {
+	fmt.Fprint(tty, label)
+	password, err := term.ReadPassword(int(tty.Fd()))
+	fmt.Fprintln(tty)
+	if err != nil {
+		return "", fmt.Errorf("reading password: %w", err)
+	}
+	return string(password), nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/muzonff/go-crypto-container/container"
+)
+
+// gocc runs the command with stdin and returns its exit code, stdout and stderr.
+func gocc(t *testing.T, stdin string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// TestEncryptDecryptInspect checks if encrypt output can be inspected and decrypted back to the input.
+func TestEncryptDecryptInspect(t *testing.T) {
+	t.Setenv(passwordEnv, "password123")
+	plaintext := "hello from the command line\n"
+
+	code, containerJSON, stderr := gocc(t, plaintext, "encrypt", "-cipher", container.CipherAES256GCM)
+	if code != 0 {
+		t.Fatalf("Expected encrypt to succeed, got exit code %d: %s", code, stderr)
+	}
+
+	code, out, stderr := gocc(t, containerJSON, "inspect")
+	if code != 0 {
+		t.Fatalf("Expected inspect to succeed, got exit code %d: %s", code, stderr)
+	}
+	var header container.Header
+	if err := json.Unmarshal([]byte(out), &header); err != nil {
+		t.Fatalf("Failed to unmarshal inspect output: %v", err)
+	}
+	if header.EncryptionInfo.Cipher != container.CipherAES256GCM {
+		t.Errorf("Expected cipher %q, got %q", container.CipherAES256GCM, header.EncryptionInfo.Cipher)
+	}
+
+	code, out, stderr = gocc(t, containerJSON, "decrypt")
+	if code != 0 {
+		t.Fatalf("Expected decrypt to succeed, got exit code %d: %s", code, stderr)
+	}
+	if out != plaintext {
+		t.Errorf("Expected decrypted text to be %q, got %q", plaintext, out)
+	}
+
+	t.Setenv(passwordEnv, "wrongpassword")
+	if code, _, stderr := gocc(t, containerJSON, "decrypt"); code != 1 || !strings.Contains(stderr, container.ErrHMACMismatch.Error()) {
+		t.Errorf("Expected exit code 1 with an HMAC error for a wrong password, got %d: %s", code, stderr)
+	}
+}
+
+// TestUsageErrors checks if missing or unknown commands and bad input fail with a non-zero exit code.
+func TestUsageErrors(t *testing.T) {
+	t.Setenv(passwordEnv, "password123")
+
+	tests := []struct {
+		name  string
+		stdin string
+		args  []string
+		want  int
+	}{
+		{"no command", "", nil, 2},
+		{"unknown command", "", []string{"frobnicate"}, 2},
+		{"unknown flag", "", []string{"encrypt", "-password", "secret"}, 2},
+		{"unknown cipher", "hello", []string{"encrypt", "-cipher", "rot13"}, 1},
+		{"inspect garbage", "not a container", []string{"inspect"}, 1},
+		{"decrypt garbage", "not a container", []string{"decrypt"}, 1},
+		{"extra arguments", "", []string{"inspect", "container.json"}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, _, _ := gocc(t, tt.stdin, tt.args...); code != tt.want {
+				t.Errorf("Expected exit code %d, got %d", tt.want, code)
+			}
+		})
+	}
+}
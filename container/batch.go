@@ -0,0 +1,111 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Item is one plaintext to encrypt with EncryptBatch.
+type Item struct {
+	Plaintext []byte
+}
+
+// Result is the outcome of encrypting one Item with EncryptBatch: the
+// container JSON, or an ErrEntry if that item failed.
+type Result struct {
+	Container string
+	Err       error
+}
+
+// EncryptBatch encrypts every item under password with up to concurrency
+// workers and returns the results in the order of items. opts are applied as
+// for CreateContainer, except that WithEnvelope and WithExternalCiphertext
+// are not allowed.
+//
+// The KDF runs once for the whole batch over one random salt, recorded in
+// each container's DeriveInfo.Salt. Every container then gets its own random
+// DeriveInfo.ItemSalt, from which its keys are expanded with HKDF, and a
+// fresh IV, so no two containers share a key. They can still be recognised
+// as one batch by their common Salt, and recovering the password opens them
+// all with the work of one.
+//
+// It returns an error without results if the options or password are
+// invalid or key derivation fails. Otherwise every item is attempted, and
+// the ErrEntry errors of those that failed are returned joined.
+func EncryptBatch(items []Item, password string, concurrency int, opts ...Option) ([]Result, error) {
+	if concurrency < 1 {
+		return nil, fmt.Errorf("concurrency %d must be at least 1", concurrency)
+	}
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	if o.envelope {
+		return nil, errors.New("EncryptBatch cannot create envelope containers")
+	}
+	if o.external != nil {
+		return nil, errors.New("EncryptBatch cannot return external ciphertext")
+	}
+	if err := o.checkPassword(password); err != nil {
+		return nil, err
+	}
+	if o.derive.isPBKDF2() && o.derive.Iters == 0 {
+		o.derive.Iters = defaultIterations()
+	}
+	// The workers share the reader, which need not be safe for concurrent use.
+	o.rand = &lockedReader{r: o.rand}
+
+	d := &Deriver{password: password, o: o, itemSalt: true}
+	if d.salt, err = readRandomBytes(o.rand, o.saltLen); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	if _, err := d.cachedMasterKey(); err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	results := make([]Result, len(items))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(items)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				_, containerJSON, err := d.seal(items[i].Plaintext)
+				if err != nil {
+					results[i].Err = ErrEntry{Index: i, Err: err}
+					continue
+				}
+				results[i].Container = string(containerJSON)
+			}
+		}()
+	}
+	for i := range items {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+	return results, errors.Join(errs...)
+}
+
+// lockedReader serialises reads from r.
+type lockedReader struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+func (l *lockedReader) Read(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.r.Read(p)
+}
@@ -0,0 +1,91 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestEncryptBatch checks if 1000 items encrypted with 8 workers come back in order, share one KDF salt but have their own item salts and IVs, and all decrypt.
+func TestEncryptBatch(t *testing.T) {
+	password := "password123"
+	items := make([]Item, 1000)
+	for i := range items {
+		items[i].Plaintext = []byte(fmt.Sprintf("blob %d", i))
+	}
+
+	results, err := EncryptBatch(items, password, 8, WithCipher(CipherAES256GCM), WithIterations(1000))
+	if err != nil {
+		t.Fatalf("Error encrypting batch: %v", err)
+	}
+	if len(results) != len(items) {
+		t.Fatalf("Expected %d results, got %d", len(items), len(results))
+	}
+
+	salts := make(map[string]bool)
+	itemSalts := make(map[string]bool)
+	ivs := make(map[string]bool)
+	for i, r := range results {
+		if r.Err != nil {
+			t.Fatalf("Item %d: unexpected error: %v", i, r.Err)
+		}
+		header, err := ParseHeader(r.Container)
+		if err != nil {
+			t.Fatalf("Item %d: error parsing header: %v", i, err)
+		}
+		salts[header.DeriveInfo.Salt] = true
+		itemSalts[header.DeriveInfo.ItemSalt] = true
+		ivs[header.EncryptionInfo.IV] = true
+
+		decryptedText, err := Decrypt(r.Container, password)
+		if err != nil {
+			t.Fatalf("Item %d: error decrypting: %v", i, err)
+		}
+		if want := string(items[i].Plaintext); decryptedText != want {
+			t.Errorf("Item %d: expected %q, got %q", i, want, decryptedText)
+		}
+	}
+	if len(salts) != 1 {
+		t.Errorf("Expected one KDF salt for the batch, got %d", len(salts))
+	}
+	if len(itemSalts) != len(items) || itemSalts[""] {
+		t.Errorf("Expected %d distinct item salts, got %d", len(items), len(itemSalts))
+	}
+	if len(ivs) != len(items) {
+		t.Errorf("Expected %d distinct IVs, got %d", len(items), len(ivs))
+	}
+
+	// The item salt selects the keys, so editing it fails authentication.
+	var container Container
+	if err := json.Unmarshal([]byte(results[0].Container), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.DeriveInfo.ItemSalt = flipLastHexDigit(container.DeriveInfo.ItemSalt)
+	if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for an edited item salt, got: %v", err)
+	}
+
+	// The item salt survives the binary form.
+	if err := json.Unmarshal([]byte(results[1].Container), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	data, err := container.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error marshaling container: %v", err)
+	}
+	if decrypted, err := DecryptContainerBinary(data, password); err != nil || string(decrypted) != string(items[1].Plaintext) {
+		t.Errorf("Expected the binary form to decrypt, got %q, %v", decrypted, err)
+	}
+}
+
+// TestEncryptBatchRejects checks if a non-positive concurrency and envelope encryption are rejected.
+func TestEncryptBatchRejects(t *testing.T) {
+	items := []Item{{Plaintext: []byte("x")}}
+	if _, err := EncryptBatch(items, "password123", 0, WithIterations(minIterations)); err == nil {
+		t.Errorf("Expected an error for concurrency 0")
+	}
+	if _, err := EncryptBatch(items, "password123", 2, WithEnvelope(), WithIterations(minIterations)); err == nil {
+		t.Errorf("Expected an error for envelope encryption")
+	}
+}
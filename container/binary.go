@@ -0,0 +1,319 @@
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// binaryMagic starts every binary container, followed by the format version.
+const (
+	binaryMagic   = "GOCC"
+	binaryVersion = 1
+)
+
+// Field tags of the binary container format. Every field is written as its
+// tag, a big-endian uint32 length and the value; empty fields are omitted.
+// Numbers are big-endian uint64, the salts, IV, ciphertext, MAC and
+// signature are raw bytes, annotations and wrapped keys are JSON and everything else is
+// a UTF-8 string.
+const (
+	tagVersion byte = iota + 1
+	tagCompression
+	tagEncoding
+	tagCreatedAt
+	tagExpiresAt
+	tagAAD
+	tagAnnotations
+	tagSalt
+	tagIters
+	tagKDF
+	tagMemory
+	tagTime
+	tagThreads
+	tagN
+	tagR
+	tagP
+	tagIV
+	tagCipher
+	tagKeySize
+	tagWrappedKeys
+	tagCiphertext
+	tagMAC
+	tagSignature
+	tagContentType
+	tagKeyID
+	tagDedupTag
+	tagPlaintextLen
+	tagKEK
+	tagPadding
+	tagMACHash
+	tagExternal
+	tagItemSalt
+)
+
+// MarshalBinary encodes c in the compact binary container format, which
+// stores the salt, IV, ciphertext and MAC as raw bytes rather than as text
+// and so is about half the size of the JSON form.
+func (c *Container) MarshalBinary() ([]byte, error) {
+	f, err := c.decodeFields()
+	if err != nil {
+		return nil, err
+	}
+	var sig []byte
+	if c.Signature != "" {
+		if sig, err = decodeHex(c.Signature); err != nil {
+			return nil, fmt.Errorf("decoding Signature: %w", err)
+		}
+	}
+	var annotations, wrappedKeys []byte
+	if len(c.ContainerMeta.Annotations) > 0 {
+		if annotations, err = json.Marshal(c.ContainerMeta.Annotations); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.WrappedKeys) > 0 {
+		if wrappedKeys, err = json.Marshal(c.WrappedKeys); err != nil {
+			return nil, err
+		}
+	}
+	var aad []byte
+	if c.ContainerMeta.AAD {
+		aad = []byte{1}
+	}
+
+	buf := append([]byte(binaryMagic), binaryVersion)
+	for _, field := range []struct {
+		tag   byte
+		value []byte
+	}{
+		{tagVersion, []byte(c.ContainerMeta.Version)},
+		{tagCompression, []byte(c.ContainerMeta.Compression)},
+		{tagEncoding, []byte(c.ContainerMeta.Encoding)},
+		{tagCreatedAt, []byte(c.ContainerMeta.CreatedAt)},
+		{tagExpiresAt, []byte(c.ContainerMeta.ExpiresAt)},
+		{tagAAD, aad},
+		{tagAnnotations, annotations},
+		{tagContentType, []byte(c.ContainerMeta.ContentType)},
+		{tagKeyID, []byte(c.ContainerMeta.KeyID)},
+		{tagDedupTag, []byte(c.ContainerMeta.DedupTag)},
+		{tagPlaintextLen, uintField(uint64(c.ContainerMeta.PlaintextLen))},
+		{tagKEK, boolField(c.ContainerMeta.KEK)},
+		{tagPadding, uintField(uint64(c.ContainerMeta.Padding))},
+		{tagMACHash, []byte(c.ContainerMeta.MACHash)},
+		{tagExternal, boolField(c.ContainerMeta.External)},
+		{tagSalt, f.salt},
+		{tagIters, uintField(uint64(c.DeriveInfo.Iters))},
+		{tagKDF, []byte(c.DeriveInfo.KDF)},
+		{tagMemory, uintField(uint64(c.DeriveInfo.Memory))},
+		{tagTime, uintField(uint64(c.DeriveInfo.Time))},
+		{tagThreads, uintField(uint64(c.DeriveInfo.Threads))},
+		{tagN, uintField(uint64(c.DeriveInfo.N))},
+		{tagR, uintField(uint64(c.DeriveInfo.R))},
+		{tagP, uintField(uint64(c.DeriveInfo.P))},
+		{tagItemSalt, f.itemSalt},
+		{tagIV, f.iv},
+		{tagCipher, []byte(c.EncryptionInfo.Cipher)},
+		{tagKeySize, uintField(uint64(c.EncryptionInfo.KeySize))},
+		{tagWrappedKeys, wrappedKeys},
+		{tagCiphertext, f.ciphertext},
+		{tagMAC, f.mac},
+		{tagSignature, sig},
+	} {
+		if len(field.value) == 0 {
+			continue
+		}
+		buf = append(buf, field.tag)
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(field.value)))
+		buf = append(buf, field.value...)
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a container written by MarshalBinary, replacing
+// the contents of c. Text fields are re-encoded with the encoding recorded in
+// the container. It returns ErrMalformedContainer for any structural problem.
+func (c *Container) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(binaryMagic)) {
+		return fmt.Errorf("%w: bad magic", ErrMalformedContainer)
+	}
+	data = data[len(binaryMagic):]
+	if len(data) == 0 || data[0] != binaryVersion {
+		return fmt.Errorf("%w: unsupported binary format version", ErrMalformedContainer)
+	}
+	data = data[1:]
+
+	var out Container
+	var salt, itemSalt, iv, ciphertext, mac, sig []byte
+	seen := make(map[byte]bool)
+	for len(data) > 0 {
+		if len(data) < 5 {
+			return fmt.Errorf("%w: truncated field header", ErrMalformedContainer)
+		}
+		tag, n := data[0], binary.BigEndian.Uint32(data[1:5])
+		data = data[5:]
+		if uint64(n) > uint64(len(data)) {
+			return fmt.Errorf("%w: field %d is truncated", ErrMalformedContainer, tag)
+		}
+		value := data[:n]
+		data = data[n:]
+		if seen[tag] {
+			return fmt.Errorf("%w: duplicate field %d", ErrMalformedContainer, tag)
+		}
+		seen[tag] = true
+
+		var err error
+		switch tag {
+		case tagVersion:
+			out.ContainerMeta.Version = string(value)
+		case tagCompression:
+			out.ContainerMeta.Compression = string(value)
+		case tagEncoding:
+			out.ContainerMeta.Encoding = Encoding(value)
+		case tagCreatedAt:
+			out.ContainerMeta.CreatedAt = string(value)
+		case tagExpiresAt:
+			out.ContainerMeta.ExpiresAt = string(value)
+		case tagAAD:
+			out.ContainerMeta.AAD = len(value) == 1 && value[0] == 1
+			if !out.ContainerMeta.AAD {
+				err = errors.New("invalid AAD flag")
+			}
+		case tagAnnotations:
+			err = json.Unmarshal(value, &out.ContainerMeta.Annotations)
+		case tagContentType:
+			out.ContainerMeta.ContentType = string(value)
+		case tagKeyID:
+			out.ContainerMeta.KeyID = string(value)
+		case tagDedupTag:
+			out.ContainerMeta.DedupTag = string(value)
+		case tagPlaintextLen:
+			err = readUintField(value, &out.ContainerMeta.PlaintextLen)
+		case tagPadding:
+			err = readUintField(value, &out.ContainerMeta.Padding)
+		case tagMACHash:
+			out.ContainerMeta.MACHash = string(value)
+		case tagExternal:
+			out.ContainerMeta.External = len(value) == 1 && value[0] == 1
+			if !out.ContainerMeta.External {
+				err = errors.New("invalid External flag")
+			}
+		case tagKEK:
+			out.ContainerMeta.KEK = len(value) == 1 && value[0] == 1
+			if !out.ContainerMeta.KEK {
+				err = errors.New("invalid KEK flag")
+			}
+		case tagSalt:
+			salt = value
+		case tagItemSalt:
+			itemSalt = value
+		case tagIters:
+			err = readUintField(value, &out.DeriveInfo.Iters)
+		case tagKDF:
+			out.DeriveInfo.KDF = string(value)
+		case tagMemory:
+			err = readUint32Field(value, &out.DeriveInfo.Memory)
+		case tagTime:
+			err = readUint32Field(value, &out.DeriveInfo.Time)
+		case tagThreads:
+			err = readUint32Field(value, &out.DeriveInfo.Threads)
+		case tagN:
+			err = readUintField(value, &out.DeriveInfo.N)
+		case tagR:
+			err = readUintField(value, &out.DeriveInfo.R)
+		case tagP:
+			err = readUintField(value, &out.DeriveInfo.P)
+		case tagIV:
+			iv = value
+		case tagCipher:
+			out.EncryptionInfo.Cipher = string(value)
+		case tagKeySize:
+			err = readUintField(value, &out.EncryptionInfo.KeySize)
+		case tagWrappedKeys:
+			err = json.Unmarshal(value, &out.WrappedKeys)
+		case tagCiphertext:
+			ciphertext = value
+		case tagMAC:
+			mac = value
+		case tagSignature:
+			sig = value
+		default:
+			err = errors.New("unknown field")
+		}
+		if err != nil {
+			return fmt.Errorf("%w: field %d: %v", ErrMalformedContainer, tag, err)
+		}
+	}
+
+	enc := out.ContainerMeta.Encoding
+	if !enc.valid() {
+		return fmt.Errorf("%w: unknown encoding %q", ErrMalformedContainer, enc)
+	}
+	// Envelope containers have no top-level salt, so it stays empty.
+	if len(salt) > 0 {
+		out.DeriveInfo.Salt = encodeField(salt, enc)
+	}
+	if len(itemSalt) > 0 {
+		out.DeriveInfo.ItemSalt = encodeField(itemSalt, enc)
+	}
+	out.EncryptionInfo.IV = encodeField(iv, enc)
+	out.ContainedData.EncryptedData = encodeField(ciphertext, enc)
+	if len(mac) > 0 {
+		out.ContainedData.HMAC = encodeField(mac, enc)
+	}
+	if len(sig) > 0 {
+		out.Signature = encodeField(sig, EncodingHex)
+	}
+	*c = out
+	return nil
+}
+
+func readUintField(value []byte, dst *int) error {
+	if len(value) != 8 {
+		return fmt.Errorf("expected 8 bytes, got %d", len(value))
+	}
+	v := binary.BigEndian.Uint64(value)
+	if v > uint64(int(^uint(0)>>1)) {
+		return fmt.Errorf("value %d out of range", v)
+	}
+	*dst = int(v)
+	return nil
+}
+
+func readUint32Field(value []byte, dst *uint32) error {
+	var v int
+	if err := readUintField(value, &v); err != nil {
+		return err
+	}
+	if uint64(v) > uint64(^uint32(0)) {
+		return fmt.Errorf("value %d out of range", v)
+	}
+	*dst = uint32(v)
+	return nil
+}
+
+// CreateContainerBinary is CreateContainerBytes returning the container in
+// the compact binary format instead of JSON.
+func CreateContainerBinary(plaintext []byte, password string, opts ...Option) ([]byte, error) {
+	containerJSON, err := CreateContainerBytes(plaintext, password, opts...)
+	if err != nil {
+		return nil, err
+	}
+	var container Container
+	if err := json.Unmarshal(containerJSON, &container); err != nil {
+		return nil, err
+	}
+	return container.MarshalBinary()
+}
+
+// DecryptContainerBinary decrypts a container created with
+// CreateContainerBinary, whatever its cipher.
+func DecryptContainerBinary(data []byte, password string) ([]byte, error) {
+	var container Container
+	if err := container.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return openContainer(&container, password, decryptParams{})
+}
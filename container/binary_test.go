@@ -0,0 +1,75 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestBinaryRoundTrip checks if binary containers decrypt, are about half the JSON size and survive a JSON round trip.
+func TestBinaryRoundTrip(t *testing.T) {
+	plaintext := bytes.Repeat([]byte{0x00, 0xff, 0x42, 0x13}, 1024)
+	password := "password123"
+
+	for _, opts := range [][]Option{
+		{WithIterations(minIterations)},
+		{WithCipher(CipherAES256GCM), WithIterations(minIterations), WithAnnotations(map[string]string{"owner": "alice"})},
+		{WithEnvelope(), WithEncoding(EncodingBase64), WithIterations(minIterations)},
+	} {
+		data, err := CreateContainerBinary(plaintext, password, opts...)
+		if err != nil {
+			t.Fatalf("Error creating container: %v", err)
+		}
+		decrypted, err := DecryptContainerBinary(data, password)
+		if err != nil {
+			t.Fatalf("Error decrypting container: %v", err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Errorf("Decrypted data does not match the plaintext")
+		}
+
+		var container Container
+		if err := container.UnmarshalBinary(data); err != nil {
+			t.Fatalf("Error unmarshaling container: %v", err)
+		}
+		again, err := container.MarshalBinary()
+		if err != nil {
+			t.Fatalf("Error marshaling container: %v", err)
+		}
+		if !bytes.Equal(again, data) {
+			t.Errorf("Expected MarshalBinary to reproduce the original bytes")
+		}
+	}
+
+	containerJSON, err := CreateContainerBytes(plaintext, password, WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	data, err := CreateContainerBinary(plaintext, password, WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	if len(data)*10 > len(containerJSON)*6 {
+		t.Errorf("Expected the binary form to be about half the JSON size, got %d vs %d bytes", len(data), len(containerJSON))
+	}
+}
+
+// TestBinaryMalformed checks if a corrupted magic header or truncated data returns ErrMalformedContainer.
+func TestBinaryMalformed(t *testing.T) {
+	data, err := CreateContainerBinary([]byte("hello world"), "password123", WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	badMagic := append([]byte(nil), data...)
+	badMagic[0] ^= 0xff
+	for name, input := range map[string][]byte{
+		"bad magic": badMagic,
+		"truncated": data[:len(data)-1],
+		"empty":     nil,
+	} {
+		if _, err := DecryptContainerBinary(input, "password123"); !errors.Is(err, ErrMalformedContainer) {
+			t.Errorf("%s: expected ErrMalformedContainer, got: %v", name, err)
+		}
+	}
+}
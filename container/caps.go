@@ -0,0 +1,60 @@
+package container
+
+import "sort"
+
+// Caps describes the algorithms and formats this version of the package
+// supports, so a sender can pick options a receiver can open.
+type Caps struct {
+	// Ciphers are the EncryptionInfo.Cipher names that can be decrypted.
+	Ciphers []string
+	// KDFs are the password KDFs that can be used and decrypted.
+	KDFs []string
+	// MACHashes are the Meta.MACHash hashes that can be used and decrypted.
+	MACHashes []string
+	// Encodings are the field encodings that can be written and read.
+	Encodings []Encoding
+	// Compressions are the Meta.Compression algorithms supported.
+	Compressions []string
+	// KeyWraps are the WrappedKey.Algorithm names of envelope recipients
+	// that can be unwrapped.
+	KeyWraps []string
+	// Versions are the readable Meta.Version values, oldest first. The
+	// legacy v1.0 is only read with WithLegacyV10 or by Reencrypt.
+	Versions []string
+	// MaxVersion is the newest format version that can be read.
+	MaxVersion string
+}
+
+// Capabilities returns what this version of the package supports. Every
+// call returns fresh slices, which the caller may modify.
+func Capabilities() Caps {
+	seen := make(map[string]bool)
+	var ciphers []string
+	for _, d := range decoders {
+		for _, name := range d.ciphers {
+			if !seen[name] {
+				seen[name] = true
+				ciphers = append(ciphers, name)
+			}
+		}
+	}
+	sort.Strings(ciphers)
+
+	kdfs := []string{KDFArgon2id, KDFScrypt}
+	for name := range pbkdf2Hashes {
+		kdfs = append(kdfs, name)
+	}
+	sort.Strings(kdfs)
+
+	versions := supportedVersions()
+	return Caps{
+		Ciphers:      ciphers,
+		KDFs:         kdfs,
+		MACHashes:    []string{MACHashSHA256, MACHashSHA384, MACHashSHA512},
+		Encodings:    []Encoding{EncodingHex, EncodingBase64},
+		Compressions: []string{CompressionGzip},
+		KeyWraps:     []string{KeyWrapPasswordKW, KeyWrapRSAOAEP, KeyWrapX25519},
+		Versions:     versions,
+		MaxVersion:   versions[len(versions)-1],
+	}
+}
@@ -0,0 +1,41 @@
+package container
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestCapabilities checks if the reported capabilities include the default cipher, KDF and newest format version.
+func TestCapabilities(t *testing.T) {
+	caps := Capabilities()
+	for _, name := range []string{CipherAES256CTR, CipherAES256GCM, CipherXChaCha20Poly1305, CipherAES256CBC} {
+		if !slices.Contains(caps.Ciphers, name) {
+			t.Errorf("Expected cipher %q in %v", name, caps.Ciphers)
+		}
+	}
+	for _, name := range []string{"pbkdf2-sha256", KDFArgon2id, KDFScrypt} {
+		if !slices.Contains(caps.KDFs, name) {
+			t.Errorf("Expected KDF %q in %v", name, caps.KDFs)
+		}
+	}
+	if !slices.Contains(caps.Encodings, EncodingBase64) {
+		t.Errorf("Expected encoding %q in %v", EncodingBase64, caps.Encodings)
+	}
+	if caps.MaxVersion != versionEnvelope {
+		t.Errorf("Expected MaxVersion %q, got %q", versionEnvelope, caps.MaxVersion)
+	}
+	if !slices.Equal(caps.KeyWraps, []string{KeyWrapPasswordKW, KeyWrapRSAOAEP, KeyWrapX25519}) {
+		t.Errorf("Expected only the current key wraps, got %v", caps.KeyWraps)
+	}
+	// Every listed version must be readable, v1.0 with WithLegacyV10.
+	if caps.Versions[0] != versionCTRPrefixed {
+		t.Errorf("Expected %q as the oldest version, got %v", versionCTRPrefixed, caps.Versions)
+	} else if _, err := Decrypt(legacyV10Container, "password123", WithLegacyV10()); err != nil {
+		t.Errorf("Expected the oldest listed version to decrypt, got: %v", err)
+	}
+
+	caps.Ciphers[0] = "changed"
+	if Capabilities().Ciphers[0] == "changed" {
+		t.Errorf("Expected Capabilities to return fresh slices")
+	}
+}
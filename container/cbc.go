@@ -0,0 +1,87 @@
+package container
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"fmt"
+)
+
+// CipherAES256CBC is AES-CBC with PKCS#7 padding and HMAC-SHA256, for
+// systems that cannot read anything else. Prefer the other ciphers where
+// possible.
+const CipherAES256CBC = "aes-256-cbc"
+
+// DecryptContainerCBC decrypts a container created with
+// WithCipher(CipherAES256CBC).
+func DecryptContainerCBC(containerJSON, password string, opts ...DecryptOption) (string, error) {
+	return decryptString(containerJSON, password, newDecryptParams(CipherAES256CBC, opts))
+}
+
+func sealCBC(container *Container, plaintext, salt, iv, aad, encKey, macKey []byte) error {
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return err
+	}
+
+	padded := pkcs7Pad(plaintext, aes.BlockSize)
+	defer zeroize(padded)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	return setMACData(container, ciphertext, salt, iv, aad, macKey)
+}
+
+// openCBC verifies the MAC before decrypting and unpadding, so invalid
+// padding can only be reported for ciphertext this key produced and reveals
+// nothing to an attacker.
+func openCBC(container *Container, f *rawFields, encKey, macKey []byte) ([]byte, error) {
+	if err := verifyMAC(container, f, macKey); err != nil {
+		return nil, err
+	}
+	if len(f.ciphertext) == 0 || len(f.ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: CBC ciphertext is %d bytes, not a positive multiple of the block size", ErrMalformedContainer, len(f.ciphertext))
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+
+	padded := make([]byte, len(f.ciphertext))
+	cipher.NewCBCDecrypter(block, f.iv).CryptBlocks(padded, f.ciphertext)
+	plaintext, err := pkcs7Unpad(padded, aes.BlockSize)
+	if err != nil {
+		zeroize(padded)
+		return nil, err
+	}
+	return plaintext, nil
+}
+
+// pkcs7Pad returns a copy of data padded to a multiple of blockSize. A full
+// block of padding is added when data is already a multiple.
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	padded := make([]byte, len(data)+n)
+	copy(padded, data)
+	for i := len(data); i < len(padded); i++ {
+		padded[i] = byte(n)
+	}
+	return padded
+}
+
+// pkcs7Unpad strips the padding added by pkcs7Pad, returning
+// ErrMalformedContainer if it is invalid.
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, fmt.Errorf("%w: invalid padding", ErrMalformedContainer)
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize {
+		return nil, fmt.Errorf("%w: invalid padding", ErrMalformedContainer)
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, fmt.Errorf("%w: invalid padding", ErrMalformedContainer)
+		}
+	}
+	return data[:len(data)-n], nil
+}
@@ -0,0 +1,137 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestCBCRoundTrip checks if plaintexts with and without a block-multiple length round-trip and are padded to the next block.
+func TestCBCRoundTrip(t *testing.T) {
+	password := "password123"
+	for _, size := range []int{0, 1, 15, 16, 17, 32, 100} {
+		plaintext := string(bytes.Repeat([]byte{'a'}, size))
+		containerJSON := mustCreate(t, plaintext, password, WithCipher(CipherAES256CBC), WithIterations(minIterations))
+
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		if container.EncryptionInfo.Cipher != CipherAES256CBC || container.ContainerMeta.Version != versionCTR {
+			t.Errorf("Expected cipher %s in a %s container, got %s in %s", CipherAES256CBC, versionCTR, container.EncryptionInfo.Cipher, container.ContainerMeta.Version)
+		}
+		if got, want := len(container.ContainedData.EncryptedData)/2, (size/aes.BlockSize+1)*aes.BlockSize; got != want {
+			t.Errorf("Expected %d bytes of ciphertext for %d bytes of plaintext, got %d", want, size, got)
+		}
+
+		for name, decrypt := range map[string]func(string, string, ...DecryptOption) (string, error){
+			"DecryptContainerCBC": DecryptContainerCBC,
+			"Decrypt":             Decrypt,
+		} {
+			decrypted, err := decrypt(containerJSON, password)
+			if err != nil {
+				t.Fatalf("%s: error decrypting %d bytes: %v", name, size, err)
+			}
+			if decrypted != plaintext {
+				t.Errorf("%s: expected %q, got %q", name, plaintext, decrypted)
+			}
+		}
+	}
+	envelopeJSON := mustCreate(t, "hello envelope", password, WithCipher(CipherAES256CBC), WithEnvelope(), WithIterations(minIterations))
+	if decrypted, err := DecryptContainerCBC(envelopeJSON, password); err != nil || decrypted != "hello envelope" {
+		t.Errorf("Expected the envelope container to decrypt, got %q, %v", decrypted, err)
+	}
+}
+
+// TestCBCRejections checks if tampered, wrong-password and wrong-entry-point CBC containers are rejected.
+func TestCBCRejections(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello cbc", password, WithCipher(CipherAES256CBC), WithIterations(minIterations))
+
+	if _, err := DecryptContainerCBC(containerJSON, "wrongpassword"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a wrong password, got %v", err)
+	}
+	if _, err := DecryptContainer(containerJSON, password); err == nil {
+		t.Errorf("Expected DecryptContainer to reject a CBC container")
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainedData.EncryptedData = flipLastHexDigit(container.ContainedData.EncryptedData)
+	tampered, _ := json.Marshal(container)
+	if _, err := DecryptContainerCBC(string(tampered), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for tampered ciphertext, got %v", err)
+	}
+
+	// A ciphertext that is not a whole number of blocks is malformed.
+	container.ContainedData.EncryptedData = container.ContainedData.EncryptedData[:len(container.ContainedData.EncryptedData)-2]
+	truncated, _ := json.Marshal(container)
+	if _, err := DecryptContainerCBC(string(truncated), password); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for a partial block, got %v", err)
+	}
+}
+
+// TestCBCInvalidPadding checks if bad padding under a valid MAC is reported as ErrMalformedContainer.
+func TestCBCInvalidPadding(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello cbc", password, WithCipher(CipherAES256CBC), WithIterations(minIterations))
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	salt, _ := hex.DecodeString(container.DeriveInfo.Salt)
+	iv, _ := hex.DecodeString(container.EncryptionInfo.IV)
+	encKey, macKey, err := deriveKeys(context.Background(), password, salt, &container.DeriveInfo, keyLen)
+	if err != nil {
+		t.Fatalf("Error deriving keys: %v", err)
+	}
+
+	// Encrypt a block ending in an impossible padding byte and authenticate it properly.
+	block, _ := aes.NewCipher(encKey)
+	padded := append(bytes.Repeat([]byte{'a'}, aes.BlockSize-1), 0)
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+	if err := setMACData(&container, ciphertext, salt, iv, nil, macKey); err != nil {
+		t.Fatalf("Error computing MAC: %v", err)
+	}
+	forged, _ := json.Marshal(container)
+
+	if _, err := DecryptContainerCBC(string(forged), password); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for invalid padding, got %v", err)
+	}
+}
+
+// TestPKCS7 checks if padding round-trips and malformed padding is rejected.
+func TestPKCS7(t *testing.T) {
+	for size := 0; size <= 2*aes.BlockSize; size++ {
+		data := bytes.Repeat([]byte{0x42}, size)
+		padded := pkcs7Pad(data, aes.BlockSize)
+		if len(padded)%aes.BlockSize != 0 || len(padded) <= size {
+			t.Fatalf("Expected %d bytes to pad to a longer block multiple, got %d", size, len(padded))
+		}
+		unpadded, err := pkcs7Unpad(padded, aes.BlockSize)
+		if err != nil || !bytes.Equal(unpadded, data) {
+			t.Errorf("Expected %d bytes to round-trip, got %x, %v", size, unpadded, err)
+		}
+	}
+
+	for name, data := range map[string][]byte{
+		"empty":            nil,
+		"partial block":    bytes.Repeat([]byte{1}, aes.BlockSize-1),
+		"zero pad":         append(bytes.Repeat([]byte{'a'}, aes.BlockSize-1), 0),
+		"pad too long":     append(bytes.Repeat([]byte{'a'}, aes.BlockSize-1), aes.BlockSize+1),
+		"inconsistent pad": append(bytes.Repeat([]byte{'a'}, aes.BlockSize-2), 1, 2),
+	} {
+		if _, err := pkcs7Unpad(data, aes.BlockSize); !errors.Is(err, ErrMalformedContainer) {
+			t.Errorf("%s: expected ErrMalformedContainer, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package container
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+)
+
+// checksum returns the hex SHA-256 of the JSON encoding of c with
+// Meta.Checksum left empty.
+func (c *Container) checksum() (string, error) {
+	blanked := *c
+	blanked.ContainerMeta.Checksum = ""
+	// Hash the encoding as it is produced instead of holding a copy of it;
+	// the Encoder's trailing newline is not part of json.Marshal's output.
+	h := &trimNewlineWriter{w: sha256.New()}
+	if err := json.NewEncoder(h).Encode(&blanked); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.w.Sum(nil)), nil
+}
+
+// trimNewlineWriter writes everything to w except a final newline.
+type trimNewlineWriter struct {
+	w       hash.Hash
+	pending bool
+}
+
+func (t *trimNewlineWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if t.pending {
+		t.w.Write([]byte{'\n'})
+	}
+	t.pending = p[len(p)-1] == '\n'
+	if t.pending {
+		t.w.Write(p[:len(p)-1])
+	} else {
+		t.w.Write(p)
+	}
+	return len(p), nil
+}
+
+// marshal sets Meta.Checksum of c and returns its JSON encoding.
+func (c *Container) marshal() ([]byte, error) {
+	return c.marshalIndent("", "")
+}
+
+// marshalIndent is marshal formatted like json.MarshalIndent, or compact when
+// prefix and indent are both empty. The checksum always covers the compact
+// encoding, so it does not depend on formatting.
+func (c *Container) marshalIndent(prefix, indent string) ([]byte, error) {
+	sum, err := c.checksum()
+	if err != nil {
+		return nil, err
+	}
+	c.ContainerMeta.Checksum = sum
+	if prefix == "" && indent == "" {
+		return json.Marshal(c)
+	}
+	return json.MarshalIndent(c, prefix, indent)
+}
+
+// VerifyChecksum checks the Meta.Checksum of containerJSON, a SHA-256 over
+// every field this package knows, to detect accidental corruption in storage
+// cheaply and without the password. It returns ErrChecksumMismatch if the
+// checksum is missing or does not match, or ErrMalformedContainer if the JSON
+// no longer parses. The checksum is not secret and is no substitute for the
+// MAC: anyone can recompute it after a deliberate change.
+func VerifyChecksum(containerJSON string) error {
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if container.ContainerMeta.Checksum == "" {
+		return fmt.Errorf("%w: container has no checksum", ErrChecksumMismatch)
+	}
+	sum, err := container.checksum()
+	if err != nil {
+		return err
+	}
+	if !constantTimeHexEqual(sum, container.ContainerMeta.Checksum) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package container
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestVerifyChecksum checks if created, signed and rekeyed containers carry a valid checksum.
+func TestVerifyChecksum(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello checksum", password, WithIterations(minIterations))
+	if err := VerifyChecksum(containerJSON); err != nil {
+		t.Errorf("Expected a valid checksum, got: %v", err)
+	}
+
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	signed, err := SignContainer(containerJSON, priv)
+	if err != nil {
+		t.Fatalf("Error signing container: %v", err)
+	}
+	if err := VerifyChecksum(signed); err != nil {
+		t.Errorf("Expected a valid checksum after signing, got: %v", err)
+	}
+
+	rekeyed, err := RekeyContainer(mustCreate(t, "hello checksum", password, WithEnvelope(), WithIterations(minIterations)), password, "new password")
+	if err != nil {
+		t.Fatalf("Error rekeying container: %v", err)
+	}
+	if err := VerifyChecksum(rekeyed); err != nil {
+		t.Errorf("Expected a valid checksum after rekeying, got: %v", err)
+	}
+}
+
+// TestVerifyChecksumDetectsCorruption checks if a flipped byte, a missing checksum or broken JSON are reported.
+func TestVerifyChecksumDetectsCorruption(t *testing.T) {
+	containerJSON := mustCreate(t, "hello checksum", "password123", WithIterations(minIterations))
+
+	i := strings.Index(containerJSON, `"EncryptedData":"`) + len(`"EncryptedData":"`)
+	flipped := []byte(containerJSON)
+	flipped[i] ^= 1
+	if err := VerifyChecksum(string(flipped)); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch for a flipped byte, got: %v", err)
+	}
+
+	if err := VerifyChecksum(legacyV10Container); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch for a container without a checksum, got: %v", err)
+	}
+
+	if err := VerifyChecksum(containerJSON[:len(containerJSON)-1]); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for truncated JSON, got: %v", err)
+	}
+}
+
+// TestChecksumNotAuthenticated checks if a container still decrypts after its checksum is removed, since the MAC does not cover it.
+func TestChecksumNotAuthenticated(t *testing.T) {
+	password := "password123"
+	var container Container
+	if err := json.Unmarshal([]byte(mustCreate(t, "hello checksum", password, WithIterations(minIterations))), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.Checksum = ""
+
+	decrypted, err := DecryptContainer(mustMarshal(t, &container), password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decrypted != "hello checksum" {
+		t.Errorf("Expected 'hello checksum', got '%s'", decrypted)
+	}
+}
+
+// TestChecksumMatchesMarshal checks if the streamed checksum equals the SHA-256 of json.Marshal with the checksum blanked.
+func TestChecksumMatchesMarshal(t *testing.T) {
+	containerJSON := mustCreate(t, "hello <checksum> & more", "password123", WithIterations(minIterations), WithAnnotations(map[string]string{"k": "v\n"}))
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Error parsing container: %v", err)
+	}
+	got, err := container.checksum()
+	if err != nil {
+		t.Fatalf("Error computing checksum: %v", err)
+	}
+	container.ContainerMeta.Checksum = ""
+	b, err := json.Marshal(&container)
+	if err != nil {
+		t.Fatalf("Error marshaling container: %v", err)
+	}
+	sum := sha256.Sum256(b)
+	if want := hex.EncodeToString(sum[:]); got != want {
+		t.Errorf("Expected checksum %s, got %s", want, got)
+	}
+}
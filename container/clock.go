@@ -0,0 +1,23 @@
+package container
+
+import "time"
+
+// Clock tells the time. It is consulted for the CreatedAt timestamp of new
+// containers and for expiry checks, so tests can make both deterministic
+// with WithClock and WithDecryptClock. Durations, such as those reported to
+// an Observer and used for iteration calibration, always use the real clock.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real clock, used unless another Clock is given.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// fixedClock always reports the same time.
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
@@ -0,0 +1,42 @@
+package container
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestClock checks if an injected clock fixes CreatedAt and decides expiry deterministically.
+func TestClock(t *testing.T) {
+	password := "password123"
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expires := created.Add(time.Hour)
+
+	containerJSON := mustCreate(t, "hello clock", password, WithClock(fixedClock(created)), WithExpiry(expires), WithIterations(minIterations))
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if header.ContainerMeta.CreatedAt != "2024-05-01T12:00:00Z" {
+		t.Errorf("Expected CreatedAt 2024-05-01T12:00:00Z, got %q", header.ContainerMeta.CreatedAt)
+	}
+
+	tests := []struct {
+		now     time.Time
+		expired bool
+	}{
+		{created, false},
+		{expires.Add(-time.Second), false},
+		{expires, true},
+		{expires.Add(24 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		_, err := Decrypt(containerJSON, password, WithDecryptClock(fixedClock(tt.now)))
+		if tt.expired && !errors.Is(err, ErrExpired) {
+			t.Errorf("At %s: expected ErrExpired, got: %v", tt.now, err)
+		}
+		if !tt.expired && err != nil {
+			t.Errorf("At %s: error decrypting container: %v", tt.now, err)
+		}
+	}
+}
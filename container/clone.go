@@ -0,0 +1,67 @@
+package container
+
+import (
+	"crypto/subtle"
+	"reflect"
+)
+
+// Clone returns a deep copy of c that shares no maps, slices or pointers
+// with it, so either can be modified without affecting the other.
+func (c *Container) Clone() *Container {
+	if c == nil {
+		return nil
+	}
+	clone := *c
+	if c.ContainerMeta.Annotations != nil {
+		clone.ContainerMeta.Annotations = make(map[string]string, len(c.ContainerMeta.Annotations))
+		for k, v := range c.ContainerMeta.Annotations {
+			clone.ContainerMeta.Annotations[k] = v
+		}
+	}
+	if c.WrappedKeys != nil {
+		clone.WrappedKeys = make([]WrappedKey, len(c.WrappedKeys))
+		for i, w := range c.WrappedKeys {
+			if w.DeriveInfo != nil {
+				d := *w.DeriveInfo
+				w.DeriveInfo = &d
+			}
+			clone.WrappedKeys[i] = w
+		}
+	}
+	return &clone
+}
+
+// Equal reports whether c and other have identical fields. The salt, IV,
+// ciphertext, MAC and wrapped keys are compared in constant time, so the
+// comparison does not reveal where they first differ.
+func (c *Container) Equal(other *Container) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	a, b := c.Clone(), other.Clone()
+	if len(a.WrappedKeys) != len(b.WrappedKeys) {
+		return false
+	}
+
+	// Compare the secret fields, then blank them so the rest can be
+	// compared as a whole.
+	equal := 1
+	secret := func(x, y *string) {
+		equal &= subtle.ConstantTimeCompare([]byte(*x), []byte(*y))
+		*x, *y = "", ""
+	}
+	secret(&a.DeriveInfo.Salt, &b.DeriveInfo.Salt)
+	secret(&a.EncryptionInfo.IV, &b.EncryptionInfo.IV)
+	secret(&a.ContainedData.EncryptedData, &b.ContainedData.EncryptedData)
+	secret(&a.ContainedData.HMAC, &b.ContainedData.HMAC)
+	for i := range a.WrappedKeys {
+		wa, wb := &a.WrappedKeys[i], &b.WrappedKeys[i]
+		secret(&wa.EphemeralKey, &wb.EphemeralKey)
+		secret(&wa.Nonce, &wb.Nonce)
+		secret(&wa.Key, &wb.Key)
+		if wa.DeriveInfo != nil && wb.DeriveInfo != nil {
+			secret(&wa.DeriveInfo.Salt, &wb.DeriveInfo.Salt)
+		}
+	}
+	return reflect.DeepEqual(a, b) && equal == 1
+}
@@ -0,0 +1,79 @@
+package container
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// TestCloneIsIndependent checks if modifying a clone's annotations and wrapped keys leaves the original unchanged.
+func TestCloneIsIndependent(t *testing.T) {
+	containerJSON := mustCreate(t, "hello clone", "password123", WithEnvelope(),
+		WithAnnotations(map[string]string{"owner": "alice"}), WithIterations(minIterations))
+	var original Container
+	if err := json.Unmarshal([]byte(containerJSON), &original); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+
+	clone := original.Clone()
+	if !clone.Equal(&original) {
+		t.Fatalf("Expected a clone to equal the original")
+	}
+
+	clone.ContainerMeta.Annotations["owner"] = "mallory"
+	clone.WrappedKeys[0].DeriveInfo.Iters++
+	clone.WrappedKeys[0].Key = flipLastHexDigit(clone.WrappedKeys[0].Key)
+	if original.ContainerMeta.Annotations["owner"] != "alice" {
+		t.Errorf("Expected the original annotation to be unchanged, got %q", original.ContainerMeta.Annotations["owner"])
+	}
+	if decrypted, err := Decrypt(mustMarshal(t, &original), "password123"); err != nil || decrypted != "hello clone" {
+		t.Errorf("Expected the original to still decrypt, got %q, %v", decrypted, err)
+	}
+	if clone.Equal(&original) {
+		t.Errorf("Expected a modified clone to differ from the original")
+	}
+}
+
+// TestContainerEqual checks if Equal detects a one-byte ciphertext change and a header change.
+func TestContainerEqual(t *testing.T) {
+	containerJSON := mustCreate(t, "hello equal", "password123", WithIterations(minIterations))
+	var a Container
+	if err := json.Unmarshal([]byte(containerJSON), &a); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(c *Container)
+	}{
+		{"ciphertext", func(c *Container) { c.ContainedData.EncryptedData = flipLastHexDigit(c.ContainedData.EncryptedData) }},
+		{"mac", func(c *Container) { c.ContainedData.HMAC = flipLastHexDigit(c.ContainedData.HMAC) }},
+		{"iters", func(c *Container) { c.DeriveInfo.Iters++ }},
+		{"annotations", func(c *Container) { c.ContainerMeta.Annotations = map[string]string{"k": "v"} }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := a.Clone()
+			tt.modify(b)
+			if a.Equal(b) || b.Equal(&a) {
+				t.Errorf("Expected containers differing in %s to be unequal", tt.name)
+			}
+		})
+	}
+
+	if !a.Equal(a.Clone()) {
+		t.Errorf("Expected a container to equal its clone")
+	}
+	var nilContainer *Container
+	if a.Equal(nil) || !nilContainer.Equal(nil) {
+		t.Errorf("Expected nil to equal only nil")
+	}
+}
+
+func mustMarshal(t *testing.T, c *Container) string {
+	t.Helper()
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Failed to marshal container: %v", err)
+	}
+	return string(b)
+}
@@ -0,0 +1,59 @@
+package container
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Compression algorithms recorded in Meta.Compression.
+const (
+	CompressionGzip = "gzip"
+)
+
+func compress(algorithm string, data []byte) ([]byte, error) {
+	switch algorithm {
+	case CompressionGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, errors.New("unsupported compression: " + algorithm)
+}
+
+// MaxDecompressedSize is the largest plaintext a compressed container may
+// expand to when it does not record Meta.PlaintextLen. It stops a small
+// container from decompressing to gigabytes, and may be raised by programs
+// that need more.
+var MaxDecompressedSize = 1 << 30
+
+// decompress reverses compress, failing with ErrMalformedContainer if the
+// output would exceed limit bytes. It must only be given authenticated data.
+func decompress(algorithm string, data []byte, limit int) ([]byte, error) {
+	switch algorithm {
+	case CompressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+		}
+		out, err := io.ReadAll(io.LimitReader(r, int64(limit)+1))
+		if err != nil {
+			zeroize(out)
+			return nil, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+		}
+		if len(out) > limit {
+			zeroize(out)
+			return nil, fmt.Errorf("%w: decompressed plaintext exceeds %d bytes", ErrMalformedContainer, limit)
+		}
+		return out, nil
+	}
+	return nil, errors.New("unsupported compression: " + algorithm)
+}
@@ -0,0 +1,82 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestWithCompression checks if a compressible payload produces a smaller container that round-trips.
+func TestWithCompression(t *testing.T) {
+	plaintext := strings.Repeat("all work and no play makes jack a dull boy\n", 200)
+	password := "password123"
+
+	plain := mustCreate(t, plaintext, password, WithIterations(minIterations))
+	compressed := mustCreate(t, plaintext, password, WithIterations(minIterations), WithCompression(CompressionGzip))
+
+	var plainContainer, compressedContainer Container
+	if err := json.Unmarshal([]byte(plain), &plainContainer); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if err := json.Unmarshal([]byte(compressed), &compressedContainer); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if compressedContainer.ContainerMeta.Compression != CompressionGzip {
+		t.Errorf("Expected Compression %q, got %q", CompressionGzip, compressedContainer.ContainerMeta.Compression)
+	}
+	if len(compressedContainer.ContainedData.EncryptedData) >= len(plainContainer.ContainedData.EncryptedData) {
+		t.Errorf("Expected compressed EncryptedData to be smaller: %d >= %d",
+			len(compressedContainer.ContainedData.EncryptedData), len(plainContainer.ContainedData.EncryptedData))
+	}
+
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		containerJSON := mustCreate(t, plaintext, password, WithCipher(cipherName), WithIterations(minIterations), WithCompression(CompressionGzip))
+		decryptedText, err := Decrypt(containerJSON, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting container: %v", cipherName, err)
+		}
+		if decryptedText != plaintext {
+			t.Errorf("%s: decrypted text does not match the plaintext", cipherName)
+		}
+	}
+
+	// The compression flag is authenticated.
+	compressedContainer.ContainerMeta.Compression = ""
+	tampered, err := json.Marshal(compressedContainer)
+	if err != nil {
+		t.Fatalf("Failed to marshal tampered container: %v", err)
+	}
+	if _, err := DecryptContainer(string(tampered), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch, got: %v", err)
+	}
+}
+
+// TestWithCompressionUnsupported checks if an unknown compression algorithm is rejected.
+func TestWithCompressionUnsupported(t *testing.T) {
+	if _, err := CreateContainer("x", "password123", WithCompression("lzma")); err == nil {
+		t.Errorf("Expected an error for an unsupported compression algorithm")
+	}
+}
+
+// TestDecompressLimit checks if decompressing beyond PlaintextLen or MaxDecompressedSize fails with ErrMalformedContainer.
+func TestDecompressLimit(t *testing.T) {
+	plaintext := strings.Repeat("a", 4096)
+	compressed, err := compress(CompressionGzip, []byte(plaintext))
+	if err != nil {
+		t.Fatalf("Error compressing: %v", err)
+	}
+	if out, err := decompress(CompressionGzip, compressed, len(plaintext)); err != nil || string(out) != plaintext {
+		t.Errorf("Expected decompression up to the limit to succeed, got: %v", err)
+	}
+	if _, err := decompress(CompressionGzip, compressed, len(plaintext)-1); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer, got: %v", err)
+	}
+
+	containerJSON := mustCreate(t, plaintext, "password123", WithIterations(minIterations), WithCompression(CompressionGzip))
+	defer func(n int) { MaxDecompressedSize = n }(MaxDecompressedSize)
+	MaxDecompressedSize = 1024
+	if _, err := DecryptContainer(containerJSON, "password123"); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer above MaxDecompressedSize, got: %v", err)
+	}
+}
@@ -1,41 +1,155 @@
 package container
 
 import (
+	"bytes"
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
-	"errors"
-	"golang.org/x/crypto/pbkdf2"
-	mathrnd "math/rand"
+	"fmt"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+	"hash"
+	"io"
+	"log/slog"
+	"sort"
+	"strings"
 	"time"
+	"unicode/utf8"
 )
 
+// Cipher names recorded in EncryptionInfo.Cipher. Containers without a
+// cipher name predate the field and use AES-256-CTR. The AES names give the
+// mode and the default key size; EncryptionInfo.KeySize, when set, decides
+// the actual key length, so an "aes-256-gcm" container with a KeySize of 16
+// uses AES-128-GCM.
 const (
-	saltLen = 12
-	ivLen   = 16
+	CipherAES256CTR = "aes-256-ctr"
+	CipherAES256GCM = "aes-256-gcm"
+	// CipherXChaCha20Poly1305 avoids AES entirely, which is faster and
+	// constant-time on platforms without AES hardware support.
+	CipherXChaCha20Poly1305 = "xchacha20-poly1305"
+)
+
+// Format versions recorded in Meta.Version.
+const (
+	// versionCTRPrefixed is the legacy format of the first release: the raw
+	// PBKDF2 key, AES-256-CTR ciphertext preceded by an unused 16-byte zero
+	// block and an unkeyed SHA-256 of the plaintext in place of a MAC. It is
+	// only read to upgrade it, never created.
+	versionCTRPrefixed = "v1.0"
+	versionCTR         = "v1.1"
+	versionAEAD        = "v2"
+	// versionEnvelope containers encrypt the data under a random content
+	// key that is stored wrapped in Container.WrappedKeys.
+	versionEnvelope = "v3"
+)
+
+const (
+	saltLen     = 16
+	minSaltLen  = 8
+	maxSaltLen  = 64
+	gcmNonceLen = 12
+	keyLen      = 32
+	macKeyLen   = 32
+	// aeadTagLen is the tag size of both AEAD ciphers.
+	aeadTagLen = 16
 )
 
 type Container struct {
-	ContainerMeta  Meta       `json:"ContainerMeta"`
-	DeriveInfo     Derive     `json:"DeriveInfo"`
-	EncryptionInfo Encryption `json:"EncryptionInfo"`
-	ContainedData  Data       `json:"ContainedData"`
+	ContainerMeta  Meta         `json:"ContainerMeta"`
+	DeriveInfo     Derive       `json:"DeriveInfo"`
+	EncryptionInfo Encryption   `json:"EncryptionInfo"`
+	WrappedKeys    []WrappedKey `json:"WrappedKeys,omitempty"`
+	ContainedData  Data         `json:"ContainedData"`
+	// Signature is an optional Ed25519 signature added by SignContainer.
+	Signature string `json:"Signature,omitempty"`
 }
 
+// Meta describes how a container was made. Every field except Checksum is
+// covered by the MAC, from v1.1 on, but stored in plaintext, so it can be read
+// without the password and not edited without it.
 type Meta struct {
-	Version string `json:"Version"`
+	Version     string `json:"Version"`
+	Compression string `json:"Compression,omitempty"`
+	// Encoding is the encoding of the binary fields, empty for hex.
+	Encoding Encoding `json:"Encoding,omitempty"`
+	// CreatedAt is the RFC 3339 UTC time the container was created.
+	// Containers created before the field was added leave it empty.
+	CreatedAt string `json:"CreatedAt,omitempty"`
+	// ExpiresAt is the RFC 3339 time set by WithExpiry after which the
+	// container no longer decrypts.
+	ExpiresAt string `json:"ExpiresAt,omitempty"`
+	// AAD records that the container is bound to associated data given with
+	// WithAAD, which must be supplied again to decrypt it.
+	AAD bool `json:"AAD,omitempty"`
+	// KEK records that the password was combined with a key-encryption key
+	// by CreateContainerWrappedPassword, which must be supplied again to
+	// decrypt it.
+	KEK bool `json:"KEK,omitempty"`
+	// Annotations are user labels set with WithAnnotations.
+	Annotations map[string]string `json:"Annotations,omitempty"`
+	// ContentType is the media type of the plaintext, such as
+	// ContentTypeJSON for containers made by CreateContainerJSON, or empty
+	// when unknown.
+	ContentType string `json:"ContentType,omitempty"`
+	// KeyID names the password or key the container was made for, as set
+	// with WithKeyID.
+	KeyID string `json:"KeyID,omitempty"`
+	// DedupTag is the hex keyed fingerprint of the plaintext set with
+	// WithDedupTag, equal for equal plaintexts only under the same tag key.
+	DedupTag string `json:"DedupTag,omitempty"`
+	// PlaintextLen is the length of the plaintext before compression, set
+	// with WithPlaintextLen so decryptors can size buffers; decryption fails
+	// if it does not match. It is zero when the option was not given or the
+	// plaintext is empty.
+	PlaintextLen int `json:"PlaintextLen,omitempty"`
+	// External records that the ciphertext was returned separately by
+	// WithExternalCiphertext and EncryptedData left empty.
+	External bool `json:"External,omitempty"`
+	// MACHash is the hash of the HMAC set with WithMACHash, or empty for
+	// SHA-256.
+	MACHash string `json:"MACHash,omitempty"`
+	// Padding is the block size set with WithPadding. The plaintext was
+	// padded to a multiple of it, with its real length in the ciphertext.
+	Padding int `json:"Padding,omitempty"`
+	// Checksum is the hex SHA-256 of the container with this field empty,
+	// checked by VerifyChecksum. It guards against corruption, not
+	// tampering, and is not covered by the MAC.
+	Checksum string `json:"Checksum,omitempty"`
 }
 
 type Derive struct {
-	Salt  string `json:"Salt"`
-	Iters int    `json:"Iters"`
+	Salt    string `json:"Salt"`
+	Iters   int    `json:"Iters"`
+	KDF     string `json:"KDF,omitempty"`
+	Memory  uint32 `json:"Memory,omitempty"`
+	Time    uint32 `json:"Time,omitempty"`
+	Threads uint32 `json:"Threads,omitempty"`
+	N       int    `json:"N,omitempty"`
+	R       int    `json:"R,omitempty"`
+	P       int    `json:"P,omitempty"`
+	// ItemSalt is the per-container salt of a container made by
+	// EncryptBatch. The KDF runs once over Salt for the whole batch, and
+	// each container's keys are expanded from its result and ItemSalt with
+	// HKDF. Since the keys depend on it, editing it fails authentication.
+	ItemSalt string `json:"ItemSalt,omitempty"`
 }
 
 type Encryption struct {
 	IV string `json:"IV"`
+	// Cipher names the cipher and, for AES, its mode. It does not give the
+	// AES key length, which KeySize does.
+	Cipher string `json:"Cipher,omitempty"`
+	// KeySize is the key length in bytes set with WithKeySize, so 16 means
+	// AES-128 whatever Cipher says. Zero means the default of 32.
+	KeySize int `json:"KeySize,omitempty"`
 }
 
 type Data struct {
@@ -59,116 +173,1128 @@ func (c *Container) SetContainedData(encryptedData, hmac string) {
 	c.ContainedData = Data{EncryptedData: encryptedData, HMAC: hmac}
 }
 
+// redacted replaces secret-bearing fields in String.
+const redacted = "[redacted]"
+
+// String describes the container for logs. It prints the version, cipher,
+// KDF and iteration count but redacts the salt, IV, ciphertext and MAC, so
+// logging a Container with %v does not leak them. Use Dump for every field.
+func (c Container) String() string {
+	return fmt.Sprintf("Container{Version: %s, Cipher: %s, KDF: %s, Iters: %d, Salt: %s, IV: %s, EncryptedData: %s, HMAC: %s}",
+		c.ContainerMeta.Version, c.cipherName(), c.DeriveInfo.kdfName(), c.DeriveInfo.Iters,
+		redacted, redacted, redacted, redacted)
+}
+
+// GoString redacts %#v output the same way as String.
+func (c Container) GoString() string {
+	return c.String()
+}
+
+// Dump prints every field of the container, including the salt, IV,
+// ciphertext, MAC and wrapped keys that String redacts. It is meant for
+// debugging and its output should not be logged.
+func (c Container) Dump() string {
+	// plainContainer has Container's fields but not its methods, so
+	// formatting it does not call String.
+	type plainContainer Container
+	return fmt.Sprintf("%+v", plainContainer(c))
+}
+
+func (e *Encryption) keySize() int {
+	if e.KeySize == 0 {
+		return keyLen
+	}
+	return e.KeySize
+}
+
+func validKeySize(n int) bool {
+	return n == 16 || n == 24 || n == 32
+}
+
+// expiry parses ExpiresAt, returning the zero time when it is not set.
+func (m *Meta) expiry() (time.Time, error) {
+	if m.ExpiresAt == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, m.ExpiresAt)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: invalid ExpiresAt: %v", ErrMalformedContainer, err)
+	}
+	return t, nil
+}
+
+// cipherName returns the cipher c was encrypted with, treating an empty name
+// as the v1.0 default of AES-256-CTR.
+func (c *Container) cipherName() string {
+	if c.EncryptionInfo.Cipher == "" {
+		return CipherAES256CTR
+	}
+	return c.EncryptionInfo.Cipher
+}
+
+// zeroize overwrites b with zeros so secrets do not linger in memory.
+func zeroize(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 func generateRandomBytes(length int) ([]byte, error) {
+	return readRandomBytes(rand.Reader, length)
+}
+
+func readRandomBytes(r io.Reader, length int) ([]byte, error) {
 	buf := make([]byte, length)
-	_, err := rand.Read(buf)
+	_, err := io.ReadFull(r, buf)
 	if err != nil {
 		return nil, err
 	}
 	return buf, nil
 }
 
-func generateRandomNumber() int {
-	iterations := 130000
-	elapsed := workload(iterations)
-	source := mathrnd.NewSource(time.Now().UnixNano() + elapsed)
-	random := mathrnd.New(source)
-	randomNumber := random.Intn(int(elapsed) + 1)
-	if randomNumber < 4096 {
-		return 4096
+// deriveKeys derives a keySize-byte encryption key and a MAC key for password
+// as described by d, wiping the intermediate master key. Callers should
+// zeroize both keys once done with them.
+func deriveKeys(ctx context.Context, password string, salt []byte, d *Derive, keySize int) (encKey, macKey []byte, err error) {
+	pw := []byte(password)
+	defer zeroize(pw)
+	dk, err := deriveKey(ctx, pw, salt, d, keySize)
+	if err != nil {
+		return nil, nil, err
 	}
-	return randomNumber
+	defer zeroize(dk)
+	return splitKey(dk)
 }
 
-func workload(iterations int) int64 {
-	start := time.Now()
-	var sum int64 = 1
-	for i := 0; i < iterations; i++ {
-		sum *= 2
+// deriveItemKeys is deriveKeys for a container made by EncryptBatch, whose
+// keys are expanded from the master key with its item salt.
+func deriveItemKeys(ctx context.Context, password string, salt, itemSalt []byte, d *Derive, keySize int) (encKey, macKey []byte, err error) {
+	pw := []byte(password)
+	defer zeroize(pw)
+	dk, err := deriveKey(ctx, pw, salt, d, keySize)
+	if err != nil {
+		return nil, nil, err
 	}
-	elapsed := time.Since(start).Nanoseconds()
-	return elapsed
+	defer zeroize(dk)
+	return itemKeys(dk, itemSalt)
 }
 
-func CreateContainer(plaintext, password string) (string, error) {
-	hmac := sha256.Sum256([]byte(plaintext))
-	salt, err := generateRandomBytes(saltLen)
+// itemKeys derives the encryption and MAC keys of one EncryptBatch item
+// from the batch master key dk and the item's salt.
+func itemKeys(dk, itemSalt []byte) (encKey, macKey []byte, err error) {
+	ik := make([]byte, len(dk))
+	defer zeroize(ik)
+	if _, err = io.ReadFull(hkdf.New(sha256.New, dk, itemSalt, []byte("item")), ik); err != nil {
+		return nil, nil, err
+	}
+	return splitKey(ik)
+}
+
+// deriveLegacyKey derives the key of a legacy v1.0 container, which used
+// the PBKDF2 output directly as its AES-256 key.
+func deriveLegacyKey(ctx context.Context, password string, salt []byte, d *Derive) ([]byte, error) {
+	pw := []byte(password)
+	defer zeroize(pw)
+	return deriveKey(ctx, pw, salt, d, keyLen)
+}
+
+// splitKey derives independent encryption and MAC keys from the
+// password-derived master key, so the same bytes are never used for both.
+// The encryption key has the same length as dk.
+func splitKey(dk []byte) (encKey, macKey []byte, err error) {
+	encKey = make([]byte, len(dk))
+	if _, err = io.ReadFull(hkdf.Expand(sha256.New, dk, []byte("enc")), encKey); err != nil {
+		return nil, nil, err
+	}
+	macKey = make([]byte, macKeyLen)
+	if _, err = io.ReadFull(hkdf.Expand(sha256.New, dk, []byte("mac")), macKey); err != nil {
+		return nil, nil, err
+	}
+	return encKey, macKey, nil
+}
+
+// macInput returns the exact bytes covered by the container MAC:
+//
+//	salt ‖ iv ‖ canonicalBytes(c) ‖ ["AAD" ‖ aad] ‖ ciphertext
+//
+// Every field is prefixed with its length as a big-endian uint32 and iters is
+// encoded as a big-endian uint64, so no two distinct headers share an encoding.
+// Header fields added after v1.0 are bound as name/value pairs only when set,
+// which keeps the encoding of older containers unchanged. The version, cipher
+// and KDF of every container created since v1.1 are therefore always bound,
+// so editing them to reach a weaker decryption path fails authentication.
+// When Meta.AAD is set, aad is bound as the value of a final "AAD" pair. AEAD
+// ciphers use the same bytes with an empty ciphertext as their additional
+// data.
+func macInput(c *Container, salt, iv, aad, ciphertext []byte) []byte {
+	return appendField(macHeader(c, salt, iv, aad), ciphertext)
+}
+
+// macHeader returns macInput without the trailing ciphertext field, so the
+// MAC can be computed without copying the ciphertext.
+func macHeader(c *Container, salt, iv, aad []byte) []byte {
+	buf := appendField(nil, salt)
+	buf = appendField(buf, iv)
+	buf = append(buf, canonicalBytes(c)...)
+	if c.ContainerMeta.AAD {
+		buf = appendField(buf, []byte("AAD"))
+		buf = appendField(buf, aad)
+	}
+	return buf
+}
+
+// canonicalBytes returns the authenticated header fields of c in the fixed
+// encoding the MAC covers, independent of how the container was marshaled:
+//
+//	iters ‖ version ‖ [name ‖ value]...
+//
+// Each field is a big-endian uint32 length followed by its bytes. The pairs
+// follow in the order below, and a pair is left out when its value is empty.
+// Numbers are big-endian uint64, flags a single 1 byte and annotations the
+// length-prefixed key/value pairs sorted by key. New header fields must be
+// appended to the end of the list so existing containers keep their MAC.
+func canonicalBytes(c *Container) []byte {
+	var iters [8]byte
+	binary.BigEndian.PutUint64(iters[:], uint64(c.DeriveInfo.Iters))
+
+	buf := appendField(nil, iters[:])
+	buf = appendField(buf, []byte(c.ContainerMeta.Version))
+	for _, f := range []struct {
+		name  string
+		value []byte
+	}{
+		{"Compression", []byte(c.ContainerMeta.Compression)},
+		{"Encoding", []byte(c.ContainerMeta.Encoding)},
+		{"CreatedAt", []byte(c.ContainerMeta.CreatedAt)},
+		{"ExpiresAt", []byte(c.ContainerMeta.ExpiresAt)},
+		{"KDF", []byte(c.DeriveInfo.KDF)},
+		{"Memory", uintField(uint64(c.DeriveInfo.Memory))},
+		{"Time", uintField(uint64(c.DeriveInfo.Time))},
+		{"Threads", uintField(uint64(c.DeriveInfo.Threads))},
+		{"N", uintField(uint64(c.DeriveInfo.N))},
+		{"R", uintField(uint64(c.DeriveInfo.R))},
+		{"P", uintField(uint64(c.DeriveInfo.P))},
+		{"KeySize", uintField(uint64(c.EncryptionInfo.KeySize))},
+		{"EncryptionCipher", []byte(c.EncryptionInfo.Cipher)},
+		{"Annotations", annotationsField(c.ContainerMeta.Annotations)},
+		{"ContentType", []byte(c.ContainerMeta.ContentType)},
+		{"KeyID", []byte(c.ContainerMeta.KeyID)},
+		// DedupTag is hex, so it is authenticated in lowercase whatever
+		// case a tool stores it in.
+		{"DedupTag", []byte(strings.ToLower(c.ContainerMeta.DedupTag))},
+		{"PlaintextLen", uintField(uint64(c.ContainerMeta.PlaintextLen))},
+		{"KEK", boolField(c.ContainerMeta.KEK)},
+		{"Padding", uintField(uint64(c.ContainerMeta.Padding))},
+		{"MACHash", []byte(c.ContainerMeta.MACHash)},
+		{"External", boolField(c.ContainerMeta.External)},
+	} {
+		if len(f.value) > 0 {
+			buf = appendField(buf, []byte(f.name))
+			buf = appendField(buf, f.value)
+		}
+	}
+	return buf
+}
+
+// appendField appends b to buf prefixed with its length as a big-endian
+// uint32.
+func appendField(buf, b []byte) []byte {
+	buf = binary.BigEndian.AppendUint32(buf, uint32(len(b)))
+	return append(buf, b...)
+}
+
+// uintField encodes v as a big-endian uint64, or as nothing when v is zero.
+func uintField(v uint64) []byte {
+	if v == 0 {
+		return nil
+	}
+	return binary.BigEndian.AppendUint64(nil, v)
+}
+
+// boolField encodes true as a single 1 byte, and false as nothing.
+func boolField(v bool) []byte {
+	if !v {
+		return nil
+	}
+	return []byte{1}
+}
+
+// annotationsField encodes annotations as length-prefixed key/value pairs
+// sorted by key, or as nothing when there are none.
+func annotationsField(annotations map[string]string) []byte {
+	keys := make([]string, 0, len(annotations))
+	for k := range annotations {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf []byte
+	for _, k := range keys {
+		for _, s := range []string{k, annotations[k]} {
+			buf = binary.BigEndian.AppendUint32(buf, uint32(len(s)))
+			buf = append(buf, s...)
+		}
+	}
+	return buf
+}
+
+// computeMAC returns the HMAC-SHA256 under macKey of the concatenation of
+// data.
+func computeMAC(macKey []byte, data ...[]byte) []byte {
+	return computeHMAC(sha256.New, macKey, data...)
+}
+
+// computeHMAC is computeMAC with the hash h.
+func computeHMAC(h func() hash.Hash, macKey []byte, data ...[]byte) []byte {
+	mac := hmac.New(h, macKey)
+	for _, d := range data {
+		mac.Write(d)
+	}
+	return mac.Sum(nil)
+}
+
+// containerMAC returns the MAC of macInput(c, salt, iv, aad, ciphertext),
+// using the hash named by Meta.MACHash, without building it in memory.
+func containerMAC(macKey []byte, c *Container, salt, iv, aad, ciphertext []byte) ([]byte, error) {
+	h, err := macHash(c.ContainerMeta.MACHash)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	iterCount := generateRandomNumber()
-	iv, err := generateRandomBytes(ivLen)
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(ciphertext)))
+	return computeHMAC(h, macKey, macHeader(c, salt, iv, aad), n[:], ciphertext), nil
+}
+
+// CreateContainer encrypts plaintext under password. Without options it uses
+// AES-256-CTR with HMAC-SHA256 and a calibrated PBKDF2-SHA256 key; see
+// Option for the available choices. An empty plaintext is valid: its
+// container is still authenticated and decrypts to "".
+func CreateContainer(plaintext, password string, opts ...Option) (string, error) {
+	pt := []byte(plaintext)
+	defer zeroize(pt)
+	b, err := CreateContainerBytes(pt, password, opts...)
 	if err != nil {
 		return "", err
 	}
+	return string(b), nil
+}
 
-	dk := pbkdf2.Key([]byte(password), salt, iterCount, 32, sha256.New)
+// CreateContainerContext is CreateContainer with a context that cancels the
+// key derivation. Once ctx is done it returns ctx.Err() promptly and wipes
+// any partially derived key.
+func CreateContainerContext(ctx context.Context, plaintext, password string, opts ...Option) (string, error) {
+	return CreateContainer(plaintext, password, append(opts, func(o *options) { o.ctx = ctx })...)
+}
+
+// CreateInfo reports the parameters CreateContainerWithInfo actually used.
+type CreateInfo struct {
+	Cipher string
+	KDF    string
+	// Iterations is the PBKDF2 iteration count, which is calibrated unless
+	// set with WithIterations, or 0 for other KDFs.
+	Iterations int
+	SaltLen    int
+	KeySize    int
+}
 
-	block, err := aes.NewCipher(dk)
+// CreateContainerWithInfo is CreateContainer that also reports the effective
+// parameters, such as the calibrated iteration count, so callers can log them
+// without parsing the container. For envelope containers they describe the
+// password wrapping.
+func CreateContainerWithInfo(plaintext, password string, opts ...Option) (containerJSON string, info CreateInfo, err error) {
+	containerJSON, err = CreateContainer(plaintext, password, append(opts, func(o *options) { o.info = &info })...)
 	if err != nil {
-		return "", err
+		return "", CreateInfo{}, err
+	}
+	return containerJSON, info, nil
+}
+
+// CreateContainerBytes is CreateContainer for binary plaintext, returning the
+// container JSON as bytes.
+func CreateContainerBytes(plaintext []byte, password string, opts ...Option) ([]byte, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	return createContainer(plaintext, password, o)
+}
+
+// CreateContainerWithIterations is CreateContainer with an explicit PBKDF2
+// iteration count instead of a calibrated one.
+func CreateContainerWithIterations(plaintext, password string, iters int) (string, error) {
+	return CreateContainer(plaintext, password, WithIterations(iters))
+}
+
+// CreateContainerGCM encrypts plaintext with AES-256-GCM. The nonce is stored
+// in EncryptionInfo.IV and the header is bound as additional data, so the
+// HMAC field is left empty.
+func CreateContainerGCM(plaintext, password string) (string, error) {
+	return CreateContainer(plaintext, password, WithCipher(CipherAES256GCM))
+}
+
+// CreateContainerChaCha encrypts plaintext with XChaCha20-Poly1305 using a
+// 24-byte random nonce stored in EncryptionInfo.IV.
+func CreateContainerChaCha(plaintext, password string) (string, error) {
+	return CreateContainer(plaintext, password, WithCipher(CipherXChaCha20Poly1305))
+}
+
+// CreateContainerArgon2 encrypts plaintext like CreateContainer but derives
+// the key with the memory-hard Argon2id instead of PBKDF2.
+func CreateContainerArgon2(plaintext, password string, params Argon2Params) (string, error) {
+	return CreateContainer(plaintext, password, WithArgon2Params(params))
+}
+
+// CreateContainerScrypt encrypts plaintext like CreateContainer but derives
+// the key with scrypt. N must be a power of two greater than 1.
+func CreateContainerScrypt(plaintext, password string, N, r, p int) (string, error) {
+	return CreateContainer(plaintext, password, WithScryptParams(N, r, p))
+}
+
+func ivSize(cipherName string) (int, error) {
+	switch cipherName {
+	case CipherAES256CTR, CipherAES256CBC:
+		return aes.BlockSize, nil
+	case CipherAES256GCM:
+		return gcmNonceLen, nil
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NonceSizeX, nil
+	}
+	return 0, fmt.Errorf("%w: cipher %q", ErrUnsupportedAlgorithm, cipherName)
+}
+
+// createContainer encrypts plaintext as configured by o. The salt, IV and,
+// for PBKDF2 without an explicit count, the iteration count are generated here.
+func createContainer(plaintext []byte, password string, o *options) (b []byte, err error) {
+	if o.observer != nil {
+		start := time.Now()
+		defer func() { o.observer.ObserveEncrypt(time.Since(start), err) }()
+	}
+	if err := o.checkPassword(password); err != nil {
+		return nil, err
+	}
+	if o.envelope {
+		return createEnvelope(plaintext, o, func(cek []byte) ([]WrappedKey, error) {
+			w, err := wrapWithPassword(cek, password, o)
+			if err != nil {
+				return nil, err
+			}
+			return []WrappedKey{*w}, nil
+		})
+	}
+	ivLen, err := ivSize(o.cipher)
+	if err != nil {
+		return nil, err
+	}
+	salt, err := readRandomBytes(o.rand, o.saltLen)
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	iv, err := readRandomBytes(o.rand, ivLen)
+	if err != nil {
+		return nil, fmt.Errorf("generating IV: %w", err)
+	}
+
+	derive := o.derive
+	derive.Salt = encodeField(salt, o.encoding)
+	if derive.isPBKDF2() && derive.Iters == 0 {
+		derive.Iters = defaultIterations()
+	}
+
+	encKey, macKey, err := deriveKeys(o.ctx, password, salt, &derive, o.keySize())
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(encKey)
+	defer zeroize(macKey)
+
+	container := &Container{DeriveInfo: derive}
+	return sealContainer(container, plaintext, salt, iv, encKey, macKey, o)
+}
+
+// meta returns the metadata of a new container made as configured by o for
+// a plaintext of plaintextLen bytes with the given dedup tag.
+func (o *options) meta(plaintextLen int, dedupTag string) Meta {
+	m := Meta{
+		Compression: o.compression,
+		Encoding:    o.encoding,
+		CreatedAt:   o.clock.Now().UTC().Format(time.RFC3339),
+		ExpiresAt:   o.expiresAt,
+		AAD:         len(o.aad) > 0,
+		KEK:         o.kek,
+		Annotations: o.annotations,
+		ContentType: o.contentType,
+		KeyID:       o.keyID,
+		DedupTag:    dedupTag,
+		Padding:     o.padding,
+		MACHash:     o.macHash,
+		External:    o.external != nil,
+	}
+	if o.plaintextLen {
+		m.PlaintextLen = plaintextLen
+	}
+	switch {
+	case o.envelope:
+		m.Version = versionEnvelope
+	case usesHMAC(o.cipher):
+		m.Version = versionCTR
+	default:
+		m.Version = versionAEAD
+	}
+	return m
+}
+
+// sealContainer fills in the metadata of container from o, encrypts
+// plaintext into it and returns its JSON encoding.
+func sealContainer(container *Container, plaintext, salt, iv, encKey, macKey []byte, o *options) ([]byte, error) {
+	if o.rejectNested && looksLikeContainer(plaintext) {
+		return nil, ErrNestedContainer
+	}
+	container.ContainerMeta = o.meta(len(plaintext), o.dedupTagFor(plaintext))
+	container.EncryptionInfo = Encryption{Cipher: o.cipher, KeySize: o.keySizeOpt}
+	if o.info != nil {
+		derive := container.DeriveInfo
+		if len(container.WrappedKeys) > 0 && container.WrappedKeys[0].DeriveInfo != nil {
+			derive = *container.WrappedKeys[0].DeriveInfo
+		}
+		*o.info = CreateInfo{Cipher: o.cipher, KDF: derive.kdfName(), KeySize: o.keySize()}
+		if derive.isPBKDF2() {
+			o.info.Iterations = derive.Iters
+		}
+		if derive.KDF != KDFNone {
+			o.info.SaltLen = o.saltLen
+		}
+	}
+	var err error
+	if o.compression != "" {
+		plaintext, err = compress(o.compression, plaintext)
+		if err != nil {
+			return nil, err
+		}
+		defer zeroize(plaintext)
+	}
+	if o.padding != 0 {
+		plaintext, err = pad(plaintext, o.padding, o.rand)
+		if err != nil {
+			return nil, err
+		}
+		defer zeroize(plaintext)
+	}
+	switch o.cipher {
+	case CipherAES256CTR:
+		err = sealCTR(container, plaintext, salt, iv, o.aad, encKey, macKey)
+	case CipherAES256CBC:
+		err = sealCBC(container, plaintext, salt, iv, o.aad, encKey, macKey)
+	default:
+		err = sealAEAD(container, o.cipher, plaintext, salt, iv, o.aad, encKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if o.external != nil {
+		if err := takeCiphertext(container, o.external); err != nil {
+			return nil, err
+		}
+	}
+
+	logContainer(o.ctx, o.logger, "container created", container, nil)
+	return container.marshalIndent(o.indentPrefix, o.indent)
+}
+
+func sealCTR(container *Container, plaintext, salt, iv, aad, encKey, macKey []byte) error {
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return err
 	}
 
-	ciphertext := make([]byte, aes.BlockSize+len(plaintext))
+	ciphertext := make([]byte, len(plaintext))
 	stream := cipher.NewCTR(block, iv)
-	stream.XORKeyStream(ciphertext[aes.BlockSize:], []byte(plaintext))
+	stream.XORKeyStream(ciphertext, plaintext)
 
-	container := &Container{}
-	container.SetContainerMeta("v1.0")
-	container.SetDeriveInfo(hex.EncodeToString(salt), iterCount)
-	container.SetEncryptionInfo(hex.EncodeToString(iv))
-	container.SetContainedData(hex.EncodeToString(ciphertext), hex.EncodeToString(hmac[:]))
+	return setMACData(container, ciphertext, salt, iv, aad, macKey)
+}
 
-	b, err := json.Marshal(container)
+// setMACData stores iv, ciphertext and their MAC in container.
+func setMACData(container *Container, ciphertext, salt, iv, aad, macKey []byte) error {
+	enc := container.ContainerMeta.Encoding
+	container.EncryptionInfo.IV = encodeField(iv, enc)
+	mac, err := containerMAC(macKey, container, salt, iv, aad, ciphertext)
 	if err != nil {
-		return "", err
+		return err
 	}
-	return string(b), nil
+	container.SetContainedData(encodeField(ciphertext, enc), encodeField(mac, enc))
+	return nil
 }
 
-func DecryptContainer(containerJSON, password string) (string, error) {
-	var container Container
-	err := json.Unmarshal([]byte(containerJSON), &container)
+// usesHMAC reports whether cipherName is authenticated with a separate
+// HMAC, rather than being an AEAD.
+func usesHMAC(cipherName string) bool {
+	return cipherName == CipherAES256CTR || cipherName == CipherAES256CBC
+}
+
+func newAEAD(cipherName string, key []byte) (cipher.AEAD, error) {
+	switch cipherName {
+	case CipherAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case CipherXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	}
+	return nil, fmt.Errorf("%w: cipher %q", ErrUnsupportedAlgorithm, cipherName)
+}
+
+func sealAEAD(container *Container, cipherName string, plaintext, salt, nonce, aad, encKey []byte) error {
+	aead, err := newAEAD(cipherName, encKey)
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	salt, err := hex.DecodeString(container.DeriveInfo.Salt)
+	enc := container.ContainerMeta.Encoding
+	container.EncryptionInfo.IV = encodeField(nonce, enc)
+	ciphertext := aead.Seal(nil, nonce, plaintext, macInput(container, salt, nonce, aad, nil))
+	container.SetContainedData(encodeField(ciphertext, enc), "")
+	return nil
+}
+
+// DecryptContainer decrypts a container produced by CreateContainer. The
+// plaintext is returned as a string holding its bytes unchanged, which need
+// not be valid UTF-8 if binary data was encrypted; WithValidateUTF8 makes
+// such plaintext an error. Go strings cannot be wiped, so callers that need
+// to clear the plaintext from memory, or that expect binary data, should use
+// DecryptContainerBytes instead. Legacy v1.0 containers are read when
+// WithLegacyV10 is given.
+func DecryptContainer(containerJSON, password string, opts ...DecryptOption) (string, error) {
+	return decryptString(containerJSON, password, newDecryptParams(CipherAES256CTR, opts))
+}
+
+// DecryptContainerContext is DecryptContainer with a context that cancels
+// the key derivation, returning ctx.Err() once ctx is done.
+func DecryptContainerContext(ctx context.Context, containerJSON, password string, opts ...DecryptOption) (string, error) {
+	return DecryptContainer(containerJSON, password, append(opts, func(p *decryptParams) { p.ctx = ctx })...)
+}
+
+// DecryptContainerBytes is DecryptContainer for binary plaintext. The caller
+// owns the returned slice and may wipe it once done with it.
+func DecryptContainerBytes(container []byte, password string, opts ...DecryptOption) ([]byte, error) {
+	return decryptContainer(container, password, newDecryptParams(CipherAES256CTR, opts))
+}
+
+// DecryptContainerGCM decrypts a container produced by CreateContainerGCM.
+func DecryptContainerGCM(containerJSON, password string) (string, error) {
+	return decryptString(containerJSON, password, decryptParams{cipher: CipherAES256GCM})
+}
+
+// DecryptContainerChaCha decrypts a container produced by CreateContainerChaCha.
+func DecryptContainerChaCha(containerJSON, password string) (string, error) {
+	return decryptString(containerJSON, password, decryptParams{cipher: CipherXChaCha20Poly1305})
+}
+
+// Decrypt decrypts a container produced by any of the password-based
+// CreateContainer functions. It reads the format version, cipher and KDF
+// from the container itself, so callers need not know how it was made, and
+// returns ErrUnsupportedVersion or ErrUnsupportedAlgorithm for formats and
+// algorithms this version does not implement. Legacy v1.0 containers are
+// read when WithLegacyV10 is given.
+func Decrypt(containerJSON, password string, opts ...DecryptOption) (string, error) {
+	return decryptString(containerJSON, password, newDecryptParams("", opts))
+}
+
+// DecryptContainerTo is Decrypt writing the plaintext to dst instead of
+// returning it. The whole container is authenticated before anything is
+// written, so on any error dst receives no plaintext. The plaintext is still
+// held in memory once; use EncryptStream and DecryptStream for data too large
+// for that.
+func DecryptContainerTo(dst io.Writer, containerJSON, password string, opts ...DecryptOption) error {
+	plaintext, err := decryptContainer([]byte(containerJSON), password, newDecryptParams("", opts))
 	if err != nil {
-		return "", err
+		return err
 	}
-	encrypted, err := hex.DecodeString(container.ContainedData.EncryptedData)
+	defer zeroize(plaintext)
+	_, err = dst.Write(plaintext)
+	return err
+}
+
+// DecryptContainerFromReader is Decrypt for a container read from r, which
+// may deliver it in pieces of any size. Errors from r are returned wrapped
+// but otherwise unchanged, so they can be told apart from ErrMalformedContainer
+// and authentication errors.
+func DecryptContainerFromReader(r io.Reader, password string, opts ...DecryptOption) (string, error) {
+	p := newDecryptParams("", opts)
+	container, err := readContainer(r, p.strict)
 	if err != nil {
 		return "", err
 	}
-	iv, err := hex.DecodeString(container.EncryptionInfo.IV)
+	plaintext, err := openContainer(container, password, p)
 	if err != nil {
 		return "", err
 	}
+	defer zeroize(plaintext)
+	return p.plaintextString(plaintext)
+}
 
-	dk := pbkdf2.Key([]byte(password), salt, container.DeriveInfo.Iters, 32, sha256.New)
+// DecryptContainerAt is DecryptContainer with expiry checked against now
+// instead of the current time.
+func DecryptContainerAt(containerJSON, password string, now time.Time) (string, error) {
+	return decryptString(containerJSON, password, decryptParams{cipher: CipherAES256CTR, clock: fixedClock(now)})
+}
 
-	block, err := aes.NewCipher(dk)
+// DecryptContainerWithAAD is Decrypt for a container created with WithAAD.
+// It returns ErrHMACMismatch unless aad equals the data given at creation.
+func DecryptContainerWithAAD(containerJSON, password string, aad []byte) (string, error) {
+	return decryptString(containerJSON, password, decryptParams{aad: aad})
+}
+
+// VerifyContainer checks that containerJSON is authentic and that password
+// opens it, returning nil on success or ErrHMACMismatch. The recovered
+// plaintext and derived keys are wiped before it returns.
+func VerifyContainer(containerJSON, password string) error {
+	plaintext, err := decryptContainer([]byte(containerJSON), password, decryptParams{})
+	zeroize(plaintext)
+	return err
+}
+
+func decryptString(containerJSON, password string, p decryptParams) (string, error) {
+	plaintext, err := decryptContainer([]byte(containerJSON), password, p)
 	if err != nil {
 		return "", err
 	}
+	defer zeroize(plaintext)
+	return p.plaintextString(plaintext)
+}
+
+// plaintextString returns plaintext as a string, checking that it is valid
+// UTF-8 if p asks for it.
+func (p *decryptParams) plaintextString(plaintext []byte) (string, error) {
+	if p.validateUTF8 && !utf8.Valid(plaintext) {
+		return "", ErrInvalidUTF8
+	}
+	return string(plaintext), nil
+}
 
-	plaintext := make([]byte, len(encrypted)-aes.BlockSize)
-	stream := cipher.NewCTR(block, iv)
-	stream.XORKeyStream(plaintext, encrypted[aes.BlockSize:])
+// decryptParams controls how decryptContainer opens a container.
+type decryptParams struct {
+	// cipher is the cipher the container must use, or empty for any.
+	cipher string
+	// aad must match the data the container was created with, if any.
+	aad []byte
+	// clock tells the time expiry is checked against, or is nil for
+	// SystemClock.
+	clock Clock
+	// unwrap recovers the content key of an envelope container from one of
+	// its wrapped keys. When nil, the password is used.
+	unwrap func(w *WrappedKey) ([]byte, error)
+	// strict rejects containers with unknown JSON fields.
+	strict bool
+	// ctx cancels key derivation, or is nil for context.Background.
+	ctx context.Context
+	// key is the raw key given to DecryptContainerWithKey, used instead of
+	// the password.
+	key []byte
+	// progress is called by DecryptStream after each chunk, if set.
+	progress func(bytesProcessed int64)
+	// collect makes DecryptMulti carry on past failing entries.
+	collect bool
+	// observer is told about each container opened, if set.
+	observer Observer
+	// logger records the parameters of each container opened, if set.
+	logger *slog.Logger
+	// kek is set when the password was combined with a KEK by
+	// DecryptContainerWrappedPassword.
+	kek bool
+	// deriveTime, if set, receives how long key derivation or unwrapping
+	// took.
+	deriveTime *time.Duration
+	// external is set by DecryptContainerExternal, which supplies the
+	// ciphertext of a container created with WithExternalCiphertext.
+	external bool
+	// legacy is set by WithLegacyV10 and Reencrypt to read v1.0 containers.
+	legacy bool
+	// validateUTF8 makes the functions returning a string reject plaintext
+	// that is not valid UTF-8.
+	validateUTF8 bool
+}
 
-	check := sha256.Sum256(plaintext)
-	if hex.EncodeToString(check[:]) != container.ContainedData.HMAC {
-		return "", errors.New("HMAC mismatch")
+func (p *decryptParams) context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
 	}
+	return p.ctx
+}
 
-	return string(plaintext), nil
+func newDecryptParams(cipherName string, opts []DecryptOption) decryptParams {
+	p := decryptParams{cipher: cipherName}
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return p
 }
 
-func decodeHex(hexStr string) ([]byte, error) {
-	bytes, err := hex.DecodeString(hexStr)
+// decryptContainer opens containerJSON as described by p.
+func decryptContainer(containerJSON []byte, password string, p decryptParams) ([]byte, error) {
+	container, err := unmarshalContainer(containerJSON, p.strict)
+	if err != nil {
+		return nil, err
+	}
+	return openContainer(container, password, p)
+}
+
+// unmarshalContainer parses containerJSON. In strict mode unknown fields and
+// trailing data are rejected instead of ignored.
+func unmarshalContainer(containerJSON []byte, strict bool) (*Container, error) {
+	var container Container
+	if !strict {
+		if err := json.Unmarshal(containerJSON, &container); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+		}
+		return &container, nil
+	}
+
+	return readContainer(bytes.NewReader(containerJSON), true)
+}
+
+// readContainer parses one container from r. Errors from r are returned as
+// they are, while invalid JSON wraps ErrMalformedContainer. With strict set
+// it also rejects unknown fields and trailing data, as unmarshalContainer.
+func readContainer(r io.Reader, strict bool) (*Container, error) {
+	er := &errReader{r: r}
+	dec := json.NewDecoder(er)
+	if strict {
+		dec.DisallowUnknownFields()
+	}
+	var container Container
+	if err := dec.Decode(&container); err != nil {
+		if er.err != nil {
+			return nil, fmt.Errorf("reading container: %w", er.err)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if strict {
+		if _, err := dec.Token(); err != io.EOF {
+			if er.err != nil {
+				return nil, fmt.Errorf("reading container: %w", er.err)
+			}
+			return nil, fmt.Errorf("%w: trailing data after container", ErrMalformedContainer)
+		}
+	}
+	return &container, nil
+}
+
+// errReader records the first error other than io.EOF returned by r.
+type errReader struct {
+	r   io.Reader
+	err error
+}
+
+func (er *errReader) Read(p []byte) (int, error) {
+	n, err := er.r.Read(p)
+	if err != nil && err != io.EOF && er.err == nil {
+		er.err = err
+	}
+	return n, err
+}
+
+// openContainer authenticates and decrypts a parsed container.
+func openContainer(container *Container, password string, p decryptParams) (plaintext []byte, err error) {
+	if p.observer != nil {
+		start := time.Now()
+		defer func() { p.observer.ObserveDecrypt(time.Since(start), err) }()
+	}
+	if p.logger != nil {
+		defer func() { logContainer(p.context(), p.logger, "container decrypted", container, err) }()
+	}
+	if container.ContainerMeta.External != p.external {
+		if p.external {
+			return nil, fmt.Errorf("%w: container holds its own ciphertext", ErrMalformedContainer)
+		}
+		return nil, fmt.Errorf("%w: ciphertext is stored externally; use DecryptContainerExternal", ErrMalformedContainer)
+	}
+	if err := container.Validate(); err != nil {
+		return nil, err
+	}
+	dec := decoders[container.ContainerMeta.Version]
+	cipherName := p.cipher
+	if cipherName == "" {
+		cipherName = container.cipherName()
+	}
+	if container.cipherName() != cipherName {
+		return nil, fmt.Errorf("%w: container uses %s, expected %s", ErrUnsupportedAlgorithm, container.cipherName(), cipherName)
+	}
+	if container.ContainerMeta.AAD != (len(p.aad) > 0) || container.ContainerMeta.KEK != p.kek {
+		return nil, ErrHMACMismatch
+	}
+
+	f, err := container.decodeFields()
+	if err != nil {
+		return nil, err
+	}
+	f.aad = p.aad
+	expiresAt, err := container.ContainerMeta.expiry()
+	if err != nil {
+		return nil, err
+	}
+	keySize := container.EncryptionInfo.keySize()
+	if dec.legacy && !p.legacy {
+		return nil, fmt.Errorf("%w: %q is a legacy format without a MAC; pass WithLegacyV10 to read it", ErrUnsupportedVersion, container.ContainerMeta.Version)
+	}
+	if p.unwrap != nil && !dec.envelope {
+		return nil, fmt.Errorf("%w: %q is not an envelope container", ErrUnsupportedVersion, container.ContainerMeta.Version)
+	}
+	var encKey, macKey []byte
+	deriveStart := time.Now()
+	switch {
+	case p.key != nil:
+		encKey, macKey, err = rawKeys(container, p.key, keySize)
+	case dec.legacy:
+		encKey, err = deriveLegacyKey(p.context(), password, f.salt, &container.DeriveInfo)
+	case dec.envelope:
+		unwrap := p.unwrap
+		if unwrap == nil {
+			unwrap = func(w *WrappedKey) ([]byte, error) { return unwrapWithPassword(p.context(), w, password) }
+		}
+		encKey, macKey, err = unwrapKeys(container, unwrap, keySize)
+	case len(f.itemSalt) > 0:
+		encKey, macKey, err = deriveItemKeys(p.context(), password, f.salt, f.itemSalt, &container.DeriveInfo, keySize)
+	default:
+		encKey, macKey, err = deriveKeys(p.context(), password, f.salt, &container.DeriveInfo, keySize)
+	}
+	if p.deriveTime != nil {
+		*p.deriveTime = time.Since(deriveStart)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(encKey)
+	defer zeroize(macKey)
+	plaintext, err = dec.open(container, f, encKey, macKey)
+	if err != nil {
+		return nil, err
+	}
+	// ExpiresAt is only trusted once the header has been authenticated.
+	if !expiresAt.IsZero() {
+		clock := p.clock
+		if clock == nil {
+			clock = SystemClock
+		}
+		if !clock.Now().Before(expiresAt) {
+			zeroize(plaintext)
+			return nil, fmt.Errorf("%w: expired at %s", ErrExpired, container.ContainerMeta.ExpiresAt)
+		}
+	}
+	if container.ContainerMeta.Padding != 0 {
+		padded := plaintext
+		defer zeroize(padded)
+		if plaintext, err = unpad(padded); err != nil {
+			return nil, err
+		}
+	}
+	if container.ContainerMeta.Compression != "" {
+		// Only authenticated plaintext ever reaches the decompressor.
+		compressed := plaintext
+		defer zeroize(compressed)
+		limit := MaxDecompressedSize
+		if n := container.ContainerMeta.PlaintextLen; n != 0 {
+			limit = n
+		}
+		if plaintext, err = decompress(container.ContainerMeta.Compression, compressed, limit); err != nil {
+			return nil, err
+		}
+	}
+	if n := container.ContainerMeta.PlaintextLen; n != 0 && len(plaintext) != n {
+		zeroize(plaintext)
+		return nil, fmt.Errorf("%w: plaintext is %d bytes, PlaintextLen is %d", ErrMalformedContainer, len(plaintext), n)
+	}
+	return plaintext, nil
+}
+
+// rawFields holds the decoded binary fields of a container, along with the
+// associated data supplied by the caller.
+type rawFields struct {
+	salt, iv, ciphertext, mac []byte
+	itemSalt                  []byte
+	aad                       []byte
+}
+
+// decodeFields decodes the binary fields of c. Errors name the field that
+// failed, such as "decoding DeriveInfo.Salt: invalid hex: ...".
+func (c *Container) decodeFields() (*rawFields, error) {
+	var f rawFields
+	enc := c.ContainerMeta.Encoding
+	for _, field := range []struct {
+		name  string
+		value string
+		dst   *[]byte
+	}{
+		{"DeriveInfo.Salt", c.DeriveInfo.Salt, &f.salt},
+		{"DeriveInfo.ItemSalt", c.DeriveInfo.ItemSalt, &f.itemSalt},
+		{"EncryptionInfo.IV", c.EncryptionInfo.IV, &f.iv},
+		{"ContainedData.EncryptedData", c.ContainedData.EncryptedData, &f.ciphertext},
+		{"ContainedData.HMAC", c.ContainedData.HMAC, &f.mac},
+	} {
+		b, err := decodeField(field.value, enc)
+		if err != nil {
+			return nil, fmt.Errorf("decoding %s: %w", field.name, err)
+		}
+		*field.dst = b
+	}
+	return &f, nil
+}
+
+// decoder describes how to open the containers of one format version.
+type decoder struct {
+	ciphers       []string
+	minCiphertext int
+	// envelope decoders take their keys from Container.WrappedKeys instead
+	// of deriving them from the password directly.
+	envelope bool
+	// legacy decoders use the derived key as is, with no MAC key, and are
+	// only used with WithLegacyV10 or by Reencrypt.
+	legacy bool
+	open   func(c *Container, f *rawFields, encKey, macKey []byte) ([]byte, error)
+}
+
+// decoders maps every readable Meta.Version to its decoder.
+var decoders = map[string]decoder{
+	versionCTRPrefixed: {
+		ciphers:       []string{CipherAES256CTR},
+		minCiphertext: aes.BlockSize,
+		legacy:        true,
+		open:          openLegacyV10,
+	},
+	versionCTR: {
+		ciphers: []string{CipherAES256CTR, CipherAES256CBC},
+		open:    openHMAC,
+	},
+	versionAEAD: {
+		ciphers:       []string{CipherAES256GCM, CipherXChaCha20Poly1305},
+		minCiphertext: aeadTagLen,
+		open:          openAEAD,
+	},
+	versionEnvelope: {
+		ciphers:  []string{CipherAES256CTR, CipherAES256CBC, CipherAES256GCM, CipherXChaCha20Poly1305},
+		envelope: true,
+		open:     openEnvelope,
+	},
+}
+
+// supportedVersions returns the readable format versions in order.
+func supportedVersions() []string {
+	versions := make([]string, 0, len(decoders))
+	for v := range decoders {
+		versions = append(versions, v)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+func (d decoder) supports(cipherName string) bool {
+	for _, name := range d.ciphers {
+		if name == cipherName {
+			return true
+		}
+	}
+	return false
+}
+
+// openLegacyV10 opens a legacy v1.0 container, whose ciphertext starts with
+// an unused zero block and whose HMAC field is the unkeyed SHA-256 of the
+// plaintext. encKey is the raw PBKDF2 key.
+//
+// The hash only tells a wrong password apart; it does not authenticate
+// anything, since anyone who knows or guesses the plaintext can alter the
+// ciphertext and recompute it. That is why v1.0 is only read to upgrade it.
+func openLegacyV10(container *Container, f *rawFields, encKey, _ []byte) ([]byte, error) {
+	plaintext, err := decryptCTR(f.ciphertext[aes.BlockSize:], f.iv, encKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(plaintext)
+	if !hmac.Equal(sum[:], f.mac) {
+		zeroize(plaintext)
+		return nil, ErrHMACMismatch
+	}
+	return plaintext, nil
+}
+
+func openCTR(container *Container, f *rawFields, encKey, macKey []byte) ([]byte, error) {
+	if err := verifyMAC(container, f, macKey); err != nil {
+		return nil, err
+	}
+	return decryptCTR(f.ciphertext, f.iv, encKey)
+}
+
+// openHMAC opens a v1.1 container, whose cipher is authenticated by an HMAC.
+func openHMAC(container *Container, f *rawFields, encKey, macKey []byte) ([]byte, error) {
+	if container.cipherName() == CipherAES256CBC {
+		return openCBC(container, f, encKey, macKey)
+	}
+	return openCTR(container, f, encKey, macKey)
+}
+
+// verifyMAC compares the stored MAC against the expected one in constant time.
+func verifyMAC(container *Container, f *rawFields, macKey []byte) error {
+	expected, err := containerMAC(macKey, container, f.salt, f.iv, f.aad, f.ciphertext)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(expected, f.mac) {
+		return ErrHMACMismatch
+	}
+	return nil
+}
+
+// constantTimeHexEqual reports whether the hex strings a and b decode to the
+// same bytes, taking time independent of their contents. It returns false if
+// either is not valid hex.
+func constantTimeHexEqual(a, b string) bool {
+	x, err := hex.DecodeString(a)
+	if err != nil {
+		return false
+	}
+	y, err := hex.DecodeString(b)
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(x, y) == 1
+}
+
+func decryptCTR(encrypted, iv, encKey []byte) ([]byte, error) {
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext := make([]byte, len(encrypted))
+	stream := cipher.NewCTR(block, iv)
+	stream.XORKeyStream(plaintext, encrypted)
+	return plaintext, nil
+}
+
+// openEnvelope opens the data of a v3 container with the unwrapped keys,
+// which may use any cipher.
+func openEnvelope(container *Container, f *rawFields, encKey, macKey []byte) ([]byte, error) {
+	if usesHMAC(container.cipherName()) {
+		return openHMAC(container, f, encKey, macKey)
+	}
+	return openAEAD(container, f, encKey, macKey)
+}
+
+func openAEAD(container *Container, f *rawFields, encKey, _ []byte) ([]byte, error) {
+	aead, err := newAEAD(container.cipherName(), encKey)
 	if err != nil {
 		return nil, err
 	}
-	return bytes, nil
+	if len(f.iv) != aead.NonceSize() {
+		return nil, ErrHMACMismatch
+	}
+
+	plaintext, err := aead.Open(nil, f.iv, f.ciphertext, macInput(container, f.salt, f.iv, f.aad, nil))
+	if err != nil {
+		return nil, ErrHMACMismatch
+	}
+	return plaintext, nil
+}
+
+func decodeHex(hexStr string) ([]byte, error) {
+	return decodeField(hexStr, EncodingHex)
 }
@@ -1,10 +1,33 @@
 package container
 
 import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 	"testing"
+	"testing/iotest"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/pbkdf2"
 )
 
+// TestMain fixes the default PBKDF2 iteration count so that tests creating
+// containers without WithIterations are fast and reproducible.
+func TestMain(m *testing.M) {
+	fixedIterations = minIterations
+	os.Exit(m.Run())
+}
+
 // TestGenerateRandomBytes checks if the function generates a byte slice of the correct length.
 func TestGenerateRandomBytes(t *testing.T) {
 	length := 16
@@ -19,15 +42,6 @@ func TestGenerateRandomBytes(t *testing.T) {
 	}
 }
 
-// TestGenerateRandomNumber checks if the function generates a number greater than or equal to 4096.
-func TestGenerateRandomNumber(t *testing.T) {
-	randomNumber := generateRandomNumber()
-
-	if randomNumber < 4096 {
-		t.Errorf("Expected random number to be greater than or equal to 4096, got %d", randomNumber)
-	}
-}
-
 // TestCreateContainer checks if the CreateContainer function returns a valid JSON string.
 func TestCreateContainer(t *testing.T) {
 	plaintext := "hello world"
@@ -84,7 +98,7 @@ func TestDecryptContainerWithWrongPassword(t *testing.T) {
 	_, err = DecryptContainer(containerJSON, wrongPassword)
 	if err == nil {
 		t.Errorf("DecryptContainer did not return an error with the wrong password")
-	} else if err.Error() != "HMAC mismatch" {
+	} else if !errors.Is(err, ErrHMACMismatch) {
 		t.Errorf("Expected HMAC mismatch error, got: %v", err)
 	}
 }
@@ -113,6 +127,68 @@ func TestDecodeHexWithInvalidHex(t *testing.T) {
 	_, err := decodeHex(invalidHexStr)
 	if err == nil {
 		t.Errorf("decodeHex did not return an error with invalid hex string")
+	} else if !errors.Is(err, ErrInvalidHex) {
+		t.Errorf("Expected ErrInvalidHex, got: %v", err)
+	}
+}
+
+// TestDecodeErrorsNameField checks if corrupt hex or base64 in each binary field is reported with the field's name.
+func TestDecodeErrorsNameField(t *testing.T) {
+	password := "password123"
+	tests := []struct {
+		field  string
+		modify func(c *Container, bad string)
+	}{
+		{"DeriveInfo.Salt", func(c *Container, bad string) { c.DeriveInfo.Salt = bad }},
+		{"EncryptionInfo.IV", func(c *Container, bad string) { c.EncryptionInfo.IV = bad }},
+		{"ContainedData.EncryptedData", func(c *Container, bad string) { c.ContainedData.EncryptedData = bad }},
+		{"ContainedData.HMAC", func(c *Container, bad string) { c.ContainedData.HMAC = bad }},
+	}
+
+	for _, enc := range []struct {
+		encoding Encoding
+		want     error
+	}{
+		{EncodingHex, ErrInvalidHex},
+		{EncodingBase64, ErrInvalidBase64},
+	} {
+		containerJSON := mustCreate(t, "hello world", password, WithEncoding(enc.encoding), WithIterations(minIterations))
+		for _, tt := range tests {
+			var container Container
+			if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			tt.modify(&container, "zz!")
+
+			_, err := DecryptContainer(mustMarshal(t, &container), password)
+			if !errors.Is(err, enc.want) {
+				t.Errorf("%s/%s: expected %v, got: %v", enc.encoding, tt.field, enc.want, err)
+			} else if !strings.Contains(err.Error(), "decoding "+tt.field+":") {
+				t.Errorf("%s/%s: expected the error to name the field, got: %v", enc.encoding, tt.field, err)
+			}
+		}
+	}
+}
+
+// TestConstantTimeHexEqual checks if hex strings are compared by their decoded bytes and malformed hex never matches.
+func TestConstantTimeHexEqual(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"00ff10", "00ff10", true},
+		{"00ff10", "00FF10", true},
+		{"", "", true},
+		{"00ff10", "00ff11", false},
+		{"00ff10", "00ff", false},
+		{"zz", "zz", false},
+		{"00f", "00f", false},
+		{"00ff10", "zz", false},
+	}
+	for _, tt := range tests {
+		if got := constantTimeHexEqual(tt.a, tt.b); got != tt.want {
+			t.Errorf("constantTimeHexEqual(%q, %q) = %v, expected %v", tt.a, tt.b, got, tt.want)
+		}
 	}
 }
 
@@ -171,6 +247,32 @@ func TestCreateContainerRandomness(t *testing.T) {
 	}
 }
 
+// TestFixedIterations checks if containers created with the fixed test iteration count share it and differ in salt and IV.
+func TestFixedIterations(t *testing.T) {
+	var headers []Header
+	for i := 0; i < 2; i++ {
+		containerJSON, err := CreateContainer("same text", "samepassword")
+		if err != nil {
+			t.Fatalf("Error creating container: %v", err)
+		}
+		header, err := ParseHeader(containerJSON)
+		if err != nil {
+			t.Fatalf("Error parsing header: %v", err)
+		}
+		headers = append(headers, header)
+	}
+
+	if headers[0].DeriveInfo.Iters != fixedIterations || headers[1].DeriveInfo.Iters != fixedIterations {
+		t.Errorf("Expected both containers to use %d iterations, got %d and %d", fixedIterations, headers[0].DeriveInfo.Iters, headers[1].DeriveInfo.Iters)
+	}
+	if headers[0].DeriveInfo.Salt == headers[1].DeriveInfo.Salt {
+		t.Errorf("Expected the containers to have different salts")
+	}
+	if headers[0].EncryptionInfo.IV == headers[1].EncryptionInfo.IV {
+		t.Errorf("Expected the containers to have different IVs")
+	}
+}
+
 // TestHMACVerification checks if HMAC verification correctly identifies tampered data.
 func TestHMACVerification(t *testing.T) {
 	plaintext := "sensitive information"
@@ -189,8 +291,7 @@ func TestHMACVerification(t *testing.T) {
 	}
 
 	// Tamper with the encrypted data
-	tamperedData := container.ContainedData.EncryptedData[:len(container.ContainedData.EncryptedData)-1] + "0"
-	container.ContainedData.EncryptedData = tamperedData
+	container.ContainedData.EncryptedData = flipLastHexDigit(container.ContainedData.EncryptedData)
 
 	// Marshal the tampered container back to JSON
 	tamperedContainerJSON, err := json.Marshal(container)
@@ -202,7 +303,1116 @@ func TestHMACVerification(t *testing.T) {
 	_, err = DecryptContainer(string(tamperedContainerJSON), password)
 	if err == nil {
 		t.Errorf("DecryptContainer did not return an error with tampered data")
-	} else if err.Error() != "HMAC mismatch" {
+	} else if !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected HMAC mismatch error, got: %v", err)
+	}
+}
+
+// flipLastHexDigit returns s with its last hex digit changed, so the decoded bytes always differ.
+func flipLastHexDigit(s string) string {
+	last := "0"
+	if s[len(s)-1] == '0' {
+		last = "1"
+	}
+	return s[:len(s)-1] + last
+}
+
+// TestHMACIsKeyed checks if the stored HMAC is not a plain hash of the plaintext and differs between passwords.
+func TestHMACIsKeyed(t *testing.T) {
+	plaintext := "hello world"
+
+	containerJSON, err := CreateContainer(plaintext, "password123")
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+
+	plainHash := sha256.Sum256([]byte(plaintext))
+	if container.ContainedData.HMAC == hex.EncodeToString(plainHash[:]) {
+		t.Errorf("HMAC field must not be an unkeyed hash of the plaintext")
+	}
+
+	// Recompute the MAC with a key derived from another password; it must not match.
+	salt, _ := hex.DecodeString(container.DeriveInfo.Salt)
+	encrypted, _ := hex.DecodeString(container.ContainedData.EncryptedData)
+	dk := pbkdf2.Key([]byte("otherpassword"), salt, container.DeriveInfo.Iters, keyLen, sha256.New)
+	_, macKey, err := splitKey(dk)
+	if err != nil {
+		t.Fatalf("Error splitting key: %v", err)
+	}
+	iv, _ := hex.DecodeString(container.EncryptionInfo.IV)
+	if hex.EncodeToString(computeMAC(macKey, macInput(&container, salt, iv, nil, encrypted))) == container.ContainedData.HMAC {
+		t.Errorf("HMAC computed with a different password should not match")
+	}
+}
+
+// TestHeaderTamperingDetected checks if modifying any authenticated header field causes an HMAC mismatch.
+func TestHeaderTamperingDetected(t *testing.T) {
+	password := "password123"
+	containerJSON, err := CreateContainer("hello world", password)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(c *Container)
+	}{
+		{"salt", func(c *Container) { c.DeriveInfo.Salt = flipLastHexDigit(c.DeriveInfo.Salt) }},
+		{"iv", func(c *Container) { c.EncryptionInfo.IV = flipLastHexDigit(c.EncryptionInfo.IV) }},
+		{"iters", func(c *Container) { c.DeriveInfo.Iters++ }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var container Container
+			if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			tt.tamper(&container)
+
+			tampered, err := json.Marshal(container)
+			if err != nil {
+				t.Fatalf("Failed to marshal tampered container: %v", err)
+			}
+
+			_, err = DecryptContainer(string(tampered), password)
+			if !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("Expected HMAC mismatch error, got: %v", err)
+			}
+		})
+	}
+}
+
+// TestMACInputLayout checks if macInput encodes the header fields in the documented, length-prefixed order.
+func TestMACInputLayout(t *testing.T) {
+	container := &Container{}
+	container.SetContainerMeta("v1.0")
+	container.SetDeriveInfo("", 4096)
+
+	got := macInput(container, []byte{0xaa}, []byte{0xbb, 0xcc}, nil, []byte{0xdd})
+	want := []byte{
+		0, 0, 0, 1, 0xaa,
+		0, 0, 0, 2, 0xbb, 0xcc,
+		0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0x10, 0x00,
+		0, 0, 0, 4, 'v', '1', '.', '0',
+		0, 0, 0, 1, 0xdd,
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("Unexpected macInput layout:\n got %x\nwant %x", got, want)
+	}
+}
+
+// TestCanonicalBytesIndependentOfJSON checks if differently ordered, indented and escaped JSON for the same header gives the same canonical bytes, and pins their layout.
+func TestCanonicalBytesIndependentOfJSON(t *testing.T) {
+	containerJSON := mustCreate(t, "hello canonical", "password123", WithIterations(minIterations), WithAnnotations(map[string]string{"owner": "<ops>", "team": "crypto"}))
+	var original Container
+	if err := json.Unmarshal([]byte(containerJSON), &original); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+
+	// Round-tripping through a map sorts the keys, so the fields come out in
+	// a different order than the struct declares them.
+	var generic map[string]any
+	if err := json.Unmarshal([]byte(containerJSON), &generic); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	reordered, err := json.MarshalIndent(generic, "", "\t")
+	if err != nil {
+		t.Fatalf("Failed to marshal container: %v", err)
+	}
+	reordered = bytes.ReplaceAll(reordered, []byte("crypto"), []byte(`\u0063rypto`))
+	var variant Container
+	if err := json.Unmarshal(reordered, &variant); err != nil {
+		t.Fatalf("Failed to unmarshal reordered container: %v", err)
+	}
+	if !bytes.Equal(canonicalBytes(&original), canonicalBytes(&variant)) {
+		t.Errorf("Expected the same canonical bytes for reordered JSON")
+	}
+	if _, err := Decrypt(string(reordered), "password123"); err != nil {
+		t.Errorf("Error decrypting reordered container: %v", err)
+	}
+
+	c := &Container{
+		ContainerMeta:  Meta{Version: "v2", Annotations: map[string]string{"b": "2", "a": "1"}},
+		DeriveInfo:     Derive{Iters: 1, KDF: KDFPBKDF2},
+		EncryptionInfo: Encryption{Cipher: CipherAES256GCM},
+	}
+	var want []byte
+	for _, field := range []string{
+		"\x00\x00\x00\x00\x00\x00\x00\x01", "v2",
+		"KDF", KDFPBKDF2,
+		"EncryptionCipher", CipherAES256GCM,
+		"Annotations", "\x00\x00\x00\x01a\x00\x00\x00\x011\x00\x00\x00\x01b\x00\x00\x00\x012",
+	} {
+		want = binary.BigEndian.AppendUint32(want, uint32(len(field)))
+		want = append(want, field...)
+	}
+	if got := canonicalBytes(c); !bytes.Equal(got, want) {
+		t.Errorf("Unexpected canonical layout:\n got %x\nwant %x", got, want)
+	}
+}
+
+// TestGCMRoundTrip checks if a GCM container decrypts back to the original plaintext and records its cipher.
+func TestGCMRoundTrip(t *testing.T) {
+	plaintext := "hello gcm"
+	password := "password123"
+
+	containerJSON, err := CreateContainerGCM(plaintext, password)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.EncryptionInfo.Cipher != CipherAES256GCM {
+		t.Errorf("Expected cipher %q, got %q", CipherAES256GCM, container.EncryptionInfo.Cipher)
+	}
+	if container.ContainedData.HMAC != "" {
+		t.Errorf("Expected empty HMAC field for GCM, got %q", container.ContainedData.HMAC)
+	}
+	if len(container.EncryptionInfo.IV) != 24 {
+		t.Errorf("Expected a 12-byte nonce, got %q", container.EncryptionInfo.IV)
+	}
+
+	decryptedText, err := DecryptContainerGCM(containerJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected decrypted text to be '%s', got '%s'", plaintext, decryptedText)
+	}
+
+	if _, err := DecryptContainer(containerJSON, password); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected DecryptContainer to reject a GCM container with ErrUnsupportedAlgorithm, got: %v", err)
+	}
+}
+
+// TestGCMTampering checks if tampering with the GCM ciphertext or nonce is reported as an HMAC mismatch.
+func TestGCMTampering(t *testing.T) {
+	password := "password123"
+	containerJSON, err := CreateContainerGCM("hello gcm", password)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(c *Container)
+	}{
+		{"ciphertext", func(c *Container) { c.ContainedData.EncryptedData = flipLastHexDigit(c.ContainedData.EncryptedData) }},
+		{"nonce", func(c *Container) { c.EncryptionInfo.IV = flipLastHexDigit(c.EncryptionInfo.IV) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var container Container
+			if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			tt.tamper(&container)
+
+			tampered, err := json.Marshal(container)
+			if err != nil {
+				t.Fatalf("Failed to marshal tampered container: %v", err)
+			}
+
+			_, err = DecryptContainerGCM(string(tampered), password)
+			if !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("Expected HMAC mismatch error, got: %v", err)
+			}
+		})
+	}
+}
+
+// TestChaChaRoundTrip checks if an XChaCha20-Poly1305 container round-trips and uses a 24-byte nonce.
+func TestChaChaRoundTrip(t *testing.T) {
+	plaintext := "hello chacha"
+	password := "password123"
+
+	containerJSON, err := CreateContainerChaCha(plaintext, password)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.EncryptionInfo.Cipher != CipherXChaCha20Poly1305 {
+		t.Errorf("Expected cipher %q, got %q", CipherXChaCha20Poly1305, container.EncryptionInfo.Cipher)
+	}
+	if len(container.EncryptionInfo.IV) != 48 {
+		t.Errorf("Expected a 24-byte nonce, got %q", container.EncryptionInfo.IV)
+	}
+
+	decryptedText, err := DecryptContainerChaCha(containerJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected decrypted text to be '%s', got '%s'", plaintext, decryptedText)
+	}
+
+	_, err = DecryptContainerChaCha(containerJSON, "wrongpassword")
+	if !errors.Is(err, ErrHMACMismatch) {
 		t.Errorf("Expected HMAC mismatch error, got: %v", err)
 	}
 }
+
+// TestDecryptDispatch checks if Decrypt opens every supported combination of format, cipher and KDF, and rejects unknown ones.
+func TestDecryptDispatch(t *testing.T) {
+	password := "password123"
+	kdfs := map[string][]Option{
+		KDFPBKDF2:       {WithIterations(minIterations)},
+		KDFPBKDF2SHA512: {WithPRF(PRFSHA512), WithIterations(minIterations)},
+		KDFArgon2id:     {WithArgon2Params(testArgon2Params)},
+		KDFScrypt:       {WithScryptParams(minScryptN, 8, 1)},
+	}
+
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256CBC, CipherAES256GCM, CipherXChaCha20Poly1305} {
+		for kdf, kdfOpts := range kdfs {
+			for _, envelope := range []bool{false, true} {
+				name := cipherName + "/" + kdf
+				opts := append([]Option{WithCipher(cipherName)}, kdfOpts...)
+				if envelope {
+					name += "/envelope"
+					opts = append(opts, WithEnvelope())
+				}
+				t.Run(name, func(t *testing.T) {
+					plaintext := "hello " + name
+					decrypted, err := Decrypt(mustCreate(t, plaintext, password, opts...), password)
+					if err != nil {
+						t.Fatalf("Error decrypting container: %v", err)
+					}
+					if decrypted != plaintext {
+						t.Errorf("Expected %q, got %q", plaintext, decrypted)
+					}
+				})
+			}
+		}
+	}
+
+	t.Run("v1.0", func(t *testing.T) {
+		if decrypted, err := Decrypt(legacyV10Container, password, WithLegacyV10()); err != nil || decrypted != "legacy v1.0 container" {
+			t.Errorf("Expected the legacy container to decrypt, got %q, %v", decrypted, err)
+		}
+		if _, err := Decrypt(legacyV10Container, password); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("Expected ErrUnsupportedVersion without WithLegacyV10, got: %v", err)
+		}
+	})
+
+	containerJSON := mustCreate(t, "hello", password, WithIterations(minIterations))
+	unknown := []struct {
+		name   string
+		modify func(c *Container)
+		want   error
+	}{
+		{"version", func(c *Container) { c.ContainerMeta.Version = "v99" }, ErrUnsupportedVersion},
+		{"cipher", func(c *Container) { c.EncryptionInfo.Cipher = "rot13" }, ErrUnsupportedAlgorithm},
+		{"kdf", func(c *Container) { c.DeriveInfo.KDF = "md5" }, ErrUnsupportedAlgorithm},
+	}
+	for _, tt := range unknown {
+		t.Run("unknown "+tt.name, func(t *testing.T) {
+			var container Container
+			if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			tt.modify(&container)
+			if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func benchmarkSeal(b *testing.B, cipherName string, size int) {
+	plaintext := make([]byte, size)
+	salt := make([]byte, saltLen)
+	encKey := make([]byte, keyLen)
+	macKey := make([]byte, macKeyLen)
+	ivLen, err := ivSize(cipherName)
+	if err != nil {
+		b.Fatalf("Error getting IV size: %v", err)
+	}
+	iv := make([]byte, ivLen)
+
+	b.SetBytes(int64(len(plaintext)))
+	for i := 0; i < b.N; i++ {
+		var err error
+		container := &Container{}
+		if cipherName == CipherAES256CTR {
+			err = sealCTR(container, plaintext, salt, iv, nil, encKey, macKey)
+		} else {
+			err = sealAEAD(container, cipherName, plaintext, salt, iv, nil, encKey)
+		}
+		if err != nil {
+			b.Fatalf("Error sealing: %v", err)
+		}
+	}
+}
+
+// BenchmarkSealAESCTR measures AES-256-CTR with HMAC-SHA256 on a 64KiB payload.
+func BenchmarkSealAESCTR(b *testing.B) { benchmarkSeal(b, CipherAES256CTR, 64*1024) }
+
+// BenchmarkSealXChaCha20Poly1305 measures XChaCha20-Poly1305 on a 64KiB payload.
+func BenchmarkSealXChaCha20Poly1305(b *testing.B) { benchmarkSeal(b, CipherXChaCha20Poly1305, 64*1024) }
+
+// TestContainerBytesRoundTrip checks if arbitrary binary data, including NUL bytes, round-trips exactly.
+func TestContainerBytesRoundTrip(t *testing.T) {
+	plaintext := []byte{0x00, 0xff, 0x00, 0x80, 'a', 0x00, 0xc3, 0x28, 0x00}
+	password := "password123"
+
+	containerJSON, err := CreateContainerBytes(plaintext, password)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	decrypted, err := DecryptContainerBytes(containerJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Errorf("Expected decrypted bytes %x, got %x", plaintext, decrypted)
+	}
+
+	// The string API reads the same containers.
+	decryptedText, err := DecryptContainer(string(containerJSON), password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != string(plaintext) {
+		t.Errorf("Expected decrypted text %q, got %q", plaintext, decryptedText)
+	}
+}
+
+// TestDecryptContainerShortCiphertext checks if a ciphertext too short for its format returns ErrMalformedContainer instead of panicking.
+func TestDecryptContainerShortCiphertext(t *testing.T) {
+	password := "password123"
+
+	tests := []struct {
+		name          string
+		containerJSON string
+		decrypt       func(string, string, ...DecryptOption) (string, error)
+	}{
+		{"v1.0", legacyV10Container, func(containerJSON, password string, _ ...DecryptOption) (string, error) {
+			return Reencrypt(containerJSON, password)
+		}},
+		{"gcm", mustCreate(t, "hello world", password, WithCipher(CipherAES256GCM)), Decrypt},
+	}
+
+	for _, tt := range tests {
+		for _, encryptedData := range []string{"deadbeef", ""} {
+			var container Container
+			if err := json.Unmarshal([]byte(tt.containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			container.ContainedData.EncryptedData = encryptedData
+
+			tampered, err := json.Marshal(container)
+			if err != nil {
+				t.Fatalf("Failed to marshal tampered container: %v", err)
+			}
+
+			_, err = tt.decrypt(string(tampered), password)
+			if !errors.Is(err, ErrMalformedContainer) {
+				t.Errorf("%s, EncryptedData %q: expected ErrMalformedContainer, got: %v", tt.name, encryptedData, err)
+			}
+		}
+	}
+}
+
+func mustCreate(t testing.TB, plaintext, password string, opts ...Option) string {
+	t.Helper()
+	containerJSON, err := CreateContainer(plaintext, password, opts...)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	return containerJSON
+}
+
+// legacyV10Container holds "legacy v1.0 container" encrypted under "password123" by the first release,
+// whose ciphertext starts with an unused 16-byte zero block and whose HMAC is the SHA-256 of the plaintext.
+const legacyV10Container = `{"ContainerMeta":{"Version":"v1.0"},"DeriveInfo":{"Salt":"f59e81ff7c90329a9eaa9b86","Iters":4096},"EncryptionInfo":{"IV":"ae8224df61c9a60bb5af00b1b274f468"},"ContainedData":{"EncryptedData":"00000000000000000000000000000000d4549ffc6ac3508f502506b599b2d9dce25af88510","HMAC":"f9172035fda31906f36c820fa593a9d32e27902445a553f28b889df83b6895c4"}}`
+
+// TestDecryptLegacyV10Container checks if v1.0 containers decrypt with WithLegacyV10 and are rejected without it.
+func TestDecryptLegacyV10Container(t *testing.T) {
+	password := "password123"
+	decryptedText, err := DecryptContainer(legacyV10Container, password, WithLegacyV10())
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != "legacy v1.0 container" {
+		t.Errorf("Expected decrypted text to be 'legacy v1.0 container', got '%s'", decryptedText)
+	}
+	if _, err := DecryptContainer(legacyV10Container, "wrongpassword", WithLegacyV10()); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong password, got: %v", err)
+	}
+
+	if _, err := DecryptContainer(legacyV10Container, password); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion from DecryptContainer, got: %v", err)
+	}
+	if err := VerifyContainer(legacyV10Container, password); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion from VerifyContainer, got: %v", err)
+	}
+
+	// A v1.1 container relabelled v1.0 does not pass the legacy check either.
+	var container Container
+	if err := json.Unmarshal([]byte(mustCreate(t, "hello world 1234", password, WithIterations(minIterations))), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.Version = versionCTRPrefixed
+	container.ContainedData.EncryptedData = strings.Repeat("00", 16) + container.ContainedData.EncryptedData
+	if _, err := UpgradeContainer(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch, got: %v", err)
+	}
+}
+
+// TestCiphertextHasNoPrefix checks if new CTR containers are v1.1 and their ciphertext is exactly as long as the plaintext.
+func TestCiphertextHasNoPrefix(t *testing.T) {
+	plaintext := "hello world"
+	containerJSON := mustCreate(t, plaintext, "password123")
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.ContainerMeta.Version != "v1.1" {
+		t.Errorf("Expected version v1.1, got %q", container.ContainerMeta.Version)
+	}
+	if len(container.ContainedData.EncryptedData) != 2*len(plaintext) {
+		t.Errorf("Expected %d hex characters of ciphertext, got %d", 2*len(plaintext), len(container.ContainedData.EncryptedData))
+	}
+}
+
+// TestTypedErrors checks if decryption failures can be told apart with errors.Is and keep readable messages.
+func TestTypedErrors(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello world", password)
+
+	_, err := DecryptContainer(containerJSON, "wrongpassword")
+	if !errors.Is(err, ErrHMACMismatch) || !strings.Contains(err.Error(), "HMAC mismatch") {
+		t.Errorf("Expected ErrHMACMismatch, got: %v", err)
+	}
+
+	_, err = DecryptContainer("{not json", password)
+	if !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for invalid JSON, got: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.DeriveInfo.Salt = "zz"
+	tampered, err := json.Marshal(container)
+	if err != nil {
+		t.Fatalf("Failed to marshal tampered container: %v", err)
+	}
+	_, err = DecryptContainer(string(tampered), password)
+	if !errors.Is(err, ErrInvalidHex) {
+		t.Errorf("Expected ErrInvalidHex for a bad salt, got: %v", err)
+	}
+}
+
+// TestUnsupportedVersion checks if unknown versions, and ciphers a version does not support, return ErrUnsupportedVersion.
+func TestUnsupportedVersion(t *testing.T) {
+	password := "password123"
+
+	tests := []struct {
+		name          string
+		containerJSON string
+		version       string
+	}{
+		{"unknown version", mustCreate(t, "hello world", password), "v99"},
+		{"gcm downgraded to v1.0", mustCreate(t, "hello world", password, WithCipher(CipherAES256GCM)), "v1.0"},
+		{"gcm downgraded to v1.1", mustCreate(t, "hello world", password, WithCipher(CipherAES256GCM)), versionCTR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var container Container
+			if err := json.Unmarshal([]byte(tt.containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			container.ContainerMeta.Version = tt.version
+
+			tampered, err := json.Marshal(container)
+			if err != nil {
+				t.Fatalf("Failed to marshal tampered container: %v", err)
+			}
+
+			_, err = Decrypt(string(tampered), password)
+			if !errors.Is(err, ErrUnsupportedVersion) {
+				t.Errorf("Expected ErrUnsupportedVersion, got: %v", err)
+			}
+		})
+	}
+}
+
+// TestUnsupportedVersionListsVersions checks if DecryptContainer names the supported versions when given an unknown one.
+func TestUnsupportedVersionListsVersions(t *testing.T) {
+	var container Container
+	if err := json.Unmarshal([]byte(mustCreate(t, "hello world", "password123", WithIterations(minIterations))), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.Version = "v99"
+
+	_, err := DecryptContainer(mustMarshal(t, &container), "password123")
+	if !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("Expected ErrUnsupportedVersion, got: %v", err)
+	}
+	if want := `"v99", supported versions are v1.0, v1.1, v2, v3`; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected the error to contain %q, got: %v", want, err)
+	}
+}
+
+// TestDowngrade checks if editing the version, cipher or KDF of a container fails authentication.
+func TestDowngrade(t *testing.T) {
+	password := "password123"
+	gcmJSON := mustCreate(t, "hello world", password, WithCipher(CipherAES256GCM), WithIterations(minIterations))
+	// A whole block, so the CBC length check does not catch the edit first.
+	ctrJSON := mustCreate(t, "hello world 1234", password, WithIterations(minIterations))
+
+	tests := []struct {
+		name          string
+		containerJSON string
+		modify        func(c *Container)
+	}{
+		{"gcm to xchacha", gcmJSON, func(c *Container) {
+			c.EncryptionInfo.Cipher = CipherXChaCha20Poly1305
+			c.EncryptionInfo.IV += strings.Repeat("00", 12)
+		}},
+		{"ctr to cbc", ctrJSON, func(c *Container) { c.EncryptionInfo.Cipher = CipherAES256CBC }},
+		{"pbkdf2 prf", ctrJSON, func(c *Container) { c.DeriveInfo.KDF = KDFPBKDF2SHA512 }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var container Container
+			if err := json.Unmarshal([]byte(tt.containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			tt.modify(&container)
+			if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("Expected ErrHMACMismatch, got: %v", err)
+			}
+		})
+	}
+}
+
+// TestVerifyContainer checks if VerifyContainer accepts the right password and reports ErrHMACMismatch otherwise.
+func TestVerifyContainer(t *testing.T) {
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		containerJSON := mustCreate(t, "hello world", "password123", WithCipher(cipherName))
+
+		if err := VerifyContainer(containerJSON, "password123"); err != nil {
+			t.Errorf("%s: expected the container to verify, got: %v", cipherName, err)
+		}
+		if err := VerifyContainer(containerJSON, "wrongpassword"); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("%s: expected ErrHMACMismatch, got: %v", cipherName, err)
+		}
+	}
+}
+
+// TestZeroize checks if zeroize sets every byte to zero.
+func TestZeroize(t *testing.T) {
+	buf := []byte("super secret key material")
+	zeroize(buf)
+	for i, b := range buf {
+		if b != 0 {
+			t.Fatalf("Expected byte %d to be zero, got %#x", i, b)
+		}
+	}
+	zeroize(nil)
+}
+
+// TestTruncatedMAC checks if a MAC that is not valid hex returns ErrInvalidHex while a shortened valid one is a mismatch.
+func TestTruncatedMAC(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello world", password)
+
+	tests := []struct {
+		name    string
+		trimmed int
+		want    error
+	}{
+		{"odd length", 1, ErrInvalidHex},
+		{"one byte short", 2, ErrHMACMismatch},
+		{"empty", -1, ErrHMACMismatch},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var container Container
+			if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			mac := container.ContainedData.HMAC
+			if tt.trimmed < 0 {
+				container.ContainedData.HMAC = ""
+			} else {
+				container.ContainedData.HMAC = mac[:len(mac)-tt.trimmed]
+			}
+
+			tampered, err := json.Marshal(container)
+			if err != nil {
+				t.Fatalf("Failed to marshal tampered container: %v", err)
+			}
+
+			_, err = DecryptContainer(string(tampered), password)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got: %v", tt.want, err)
+			}
+		})
+	}
+}
+
+// TestWithAAD checks if a container bound to associated data only opens with the same data.
+func TestWithAAD(t *testing.T) {
+	plaintext := "tenant secret"
+	password := "password123"
+	aad := []byte("tenant-42/report.json")
+
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM, CipherXChaCha20Poly1305} {
+		containerJSON := mustCreate(t, plaintext, password, WithCipher(cipherName), WithIterations(minIterations), WithAAD(aad))
+
+		decryptedText, err := DecryptContainerWithAAD(containerJSON, password, aad)
+		if err != nil {
+			t.Fatalf("%s: error decrypting container: %v", cipherName, err)
+		}
+		if decryptedText != plaintext {
+			t.Errorf("%s: expected %q, got %q", cipherName, plaintext, decryptedText)
+		}
+
+		for _, wrong := range [][]byte{nil, []byte("tenant-43/report.json")} {
+			if _, err := DecryptContainerWithAAD(containerJSON, password, wrong); !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("%s: expected ErrHMACMismatch for AAD %q, got: %v", cipherName, wrong, err)
+			}
+		}
+		if _, err := Decrypt(containerJSON, password); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("%s: expected ErrHMACMismatch without AAD, got: %v", cipherName, err)
+		}
+	}
+
+	// A container without AAD must not open when AAD is supplied.
+	containerJSON := mustCreate(t, plaintext, password, WithIterations(minIterations))
+	if _, err := DecryptContainerWithAAD(containerJSON, password, aad); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for unexpected AAD, got: %v", err)
+	}
+}
+
+// TestWithExpiry checks if expiry is enforced on decryption against the supplied time.
+func TestWithExpiry(t *testing.T) {
+	plaintext := "short-lived secret"
+	password := "password123"
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	containerJSON := mustCreate(t, plaintext, password, WithIterations(minIterations), WithExpiry(expiresAt))
+
+	decryptedText, err := DecryptContainerAt(containerJSON, password, expiresAt.Add(-time.Second))
+	if err != nil {
+		t.Fatalf("Error decrypting container before expiry: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected %q, got %q", plaintext, decryptedText)
+	}
+
+	for _, now := range []time.Time{expiresAt, expiresAt.Add(time.Hour)} {
+		decryptedText, err := DecryptContainerAt(containerJSON, password, now)
+		if !errors.Is(err, ErrExpired) {
+			t.Errorf("Expected ErrExpired at %v, got: %v", now, err)
+		}
+		if decryptedText != "" {
+			t.Errorf("Expected no plaintext for an expired container, got %q", decryptedText)
+		}
+	}
+
+	// Extending the expiry invalidates the MAC.
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.ExpiresAt = expiresAt.AddDate(10, 0, 0).Format(time.RFC3339)
+	tampered, err := json.Marshal(container)
+	if err != nil {
+		t.Fatalf("Failed to marshal tampered container: %v", err)
+	}
+	if _, err := DecryptContainerAt(string(tampered), password, expiresAt.Add(time.Hour)); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch, got: %v", err)
+	}
+
+	// Without an expiry the container opens at any time.
+	containerJSON = mustCreate(t, plaintext, password, WithIterations(minIterations))
+	if _, err := DecryptContainerAt(containerJSON, password, time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Expected no error without expiry, got: %v", err)
+	}
+}
+
+// TestUnsupportedAlgorithm checks if unknown cipher and KDF names return ErrUnsupportedAlgorithm.
+func TestUnsupportedAlgorithm(t *testing.T) {
+	containerJSON := mustCreate(t, "hello world", "password123", WithIterations(minIterations))
+
+	for name, tamper := range map[string]func(c *Container){
+		"cipher": func(c *Container) { c.EncryptionInfo.Cipher = "rot13" },
+		"kdf":    func(c *Container) { c.DeriveInfo.KDF = "md5" },
+	} {
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		tamper(&container)
+		tampered, err := json.Marshal(container)
+		if err != nil {
+			t.Fatalf("Failed to marshal container: %v", err)
+		}
+		if _, err := Decrypt(string(tampered), "password123"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Errorf("%s: expected ErrUnsupportedAlgorithm, got: %v", name, err)
+		}
+	}
+
+	if _, err := CreateContainer("hello world", "password123", WithCipher("rot13")); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm from CreateContainer, got: %v", err)
+	}
+}
+
+// FuzzDecryptContainer checks if decrypting arbitrary input returns an error instead of panicking.
+func FuzzDecryptContainer(f *testing.F) {
+	const password = "password123"
+	valid := mustCreate(f, "hello fuzz", password, WithIterations(minIterations))
+	aead := mustCreate(f, "hello fuzz", password, WithIterations(minIterations), WithCipher(CipherAES256GCM))
+	for _, seed := range []string{valid, aead, legacyV10Container} {
+		f.Add([]byte(seed))
+		for _, n := range []int{0, 1, len(seed) / 4, len(seed) / 2, len(seed) - 1} {
+			f.Add([]byte(seed[:n]))
+		}
+	}
+	f.Add([]byte(`{}`))
+	f.Add([]byte(`null`))
+	f.Add([]byte(`{"ContainerMeta":{"Version":"v1.1"},"DeriveInfo":{"Salt":"","Iters":100000},"EncryptionInfo":{"IV":""},"ContainedData":{"EncryptedData":"","HMAC":""}}`))
+
+	// Only inputs carrying a genuine MAC may decrypt, so any plaintext must be
+	// one of the seeds'.
+	genuine := map[string]bool{"hello fuzz": true}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if plaintext, err := DecryptContainer(string(data), password); err == nil && !genuine[plaintext] {
+			t.Errorf("Decrypted forged input %q to %q", data, plaintext)
+		}
+		if plaintext, err := Decrypt(string(data), password); err == nil && !genuine[plaintext] {
+			t.Errorf("Decrypted forged input %q to %q", data, plaintext)
+		}
+	})
+}
+
+// TestCreateContainerWithInfo checks if the reported parameters match those embedded in the container.
+func TestCreateContainerWithInfo(t *testing.T) {
+	password := "password123"
+
+	// No WithIterations, so the count is the default one.
+	containerJSON, info, err := CreateContainerWithInfo("hello info", password)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if info.Iterations == 0 || info.Iterations != header.DeriveInfo.Iters {
+		t.Errorf("Expected %d iterations, got %d", header.DeriveInfo.Iters, info.Iterations)
+	}
+	want := CreateInfo{Cipher: CipherAES256CTR, KDF: KDFPBKDF2, Iterations: info.Iterations, SaltLen: saltLen, KeySize: keyLen}
+	if info != want {
+		t.Errorf("Expected %+v, got %+v", want, info)
+	}
+
+	tests := []struct {
+		name string
+		opts []Option
+		want CreateInfo
+	}{
+		{"argon2 gcm", []Option{WithCipher(CipherAES256GCM), WithArgon2Params(testArgon2Params), WithSaltLen(32), WithKeySize(16)},
+			CreateInfo{Cipher: CipherAES256GCM, KDF: KDFArgon2id, SaltLen: 32, KeySize: 16}},
+		{"envelope sha512", []Option{WithEnvelope(), WithPRF(PRFSHA512), WithIterations(minIterations)},
+			CreateInfo{Cipher: CipherAES256CTR, KDF: KDFPBKDF2SHA512, Iterations: minIterations, SaltLen: saltLen, KeySize: keyLen}},
+	}
+	for _, tt := range tests {
+		_, info, err := CreateContainerWithInfo("hello info", password, tt.opts...)
+		if err != nil {
+			t.Fatalf("%s: error creating container: %v", tt.name, err)
+		}
+		if info != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, info)
+		}
+	}
+
+	if _, info, err := CreateContainerWithInfo("hello info", password, WithCipher("rot13")); err == nil || info != (CreateInfo{}) {
+		t.Errorf("Expected an error and no info for an unknown cipher, got %v and %+v", err, info)
+	}
+}
+
+// TestEmptyPlaintext checks if an empty plaintext round-trips with every cipher and its MAC or tag still authenticates the container.
+func TestEmptyPlaintext(t *testing.T) {
+	password := "password123"
+
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"ctr", nil},
+		{"gcm", []Option{WithCipher(CipherAES256GCM)}},
+		{"chacha", []Option{WithCipher(CipherXChaCha20Poly1305)}},
+		{"envelope", []Option{WithEnvelope()}},
+		{"gzip", []Option{WithCompression(CompressionGzip)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithIterations(minIterations)}, tt.opts...)
+			containerJSON := mustCreate(t, "", password, opts...)
+
+			decrypted, err := Decrypt(containerJSON, password)
+			if err != nil {
+				t.Fatalf("Error decrypting empty container: %v", err)
+			}
+			if decrypted != "" {
+				t.Errorf("Expected empty plaintext, got %q", decrypted)
+			}
+
+			var container Container
+			if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			if container.ContainedData.HMAC != "" {
+				container.ContainedData.HMAC = flipLastHexDigit(container.ContainedData.HMAC)
+			} else {
+				container.ContainedData.EncryptedData = flipLastHexDigit(container.ContainedData.EncryptedData)
+			}
+			tampered, err := json.Marshal(container)
+			if err != nil {
+				t.Fatalf("Failed to marshal tampered container: %v", err)
+			}
+			if _, err := Decrypt(string(tampered), password); !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("Expected ErrHMACMismatch for tampered empty container, got %v", err)
+			}
+			if _, err := Decrypt(containerJSON, "wrong password"); !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("Expected ErrHMACMismatch for wrong password, got %v", err)
+			}
+		})
+	}
+
+	// DecryptContainer, the CTR-only entry point, returns "" without error.
+	containerJSON := mustCreate(t, "", password, WithIterations(minIterations))
+	if decrypted, err := DecryptContainer(containerJSON, password); err != nil || decrypted != "" {
+		t.Errorf("Expected empty plaintext and no error, got %q, %v", decrypted, err)
+	}
+}
+
+// TestSplitKey checks if the encryption and MAC keys are distinct HKDF subkeys of the master key and are the ones the container uses.
+func TestSplitKey(t *testing.T) {
+	plaintext := "hello subkeys"
+	password := "password123"
+	containerJSON := mustCreate(t, plaintext, password, WithIterations(minIterations))
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	salt, _ := hex.DecodeString(container.DeriveInfo.Salt)
+	iv, _ := hex.DecodeString(container.EncryptionInfo.IV)
+	encrypted, _ := hex.DecodeString(container.ContainedData.EncryptedData)
+
+	dk := pbkdf2.Key([]byte(password), salt, container.DeriveInfo.Iters, keyLen, sha256.New)
+	encKey, macKey, err := splitKey(dk)
+	if err != nil {
+		t.Fatalf("Error splitting key: %v", err)
+	}
+	if bytes.Equal(encKey, macKey) || bytes.Equal(encKey, dk) || bytes.Equal(macKey, dk) {
+		t.Fatalf("Expected encryption, MAC and master keys to differ")
+	}
+	for label, key := range map[string][]byte{"enc": encKey, "mac": macKey} {
+		want := make([]byte, len(key))
+		if _, err := io.ReadFull(hkdf.Expand(sha256.New, dk, []byte(label)), want); err != nil {
+			t.Fatalf("Error expanding %q: %v", label, err)
+		}
+		if !bytes.Equal(key, want) {
+			t.Errorf("Expected the %q key to be HKDF-Expand of the master key", label)
+		}
+	}
+
+	if got := hex.EncodeToString(computeMAC(macKey, macInput(&container, salt, iv, nil, encrypted))); got != container.ContainedData.HMAC {
+		t.Errorf("Expected the MAC to be keyed with the MAC subkey")
+	}
+	block, err := aes.NewCipher(encKey)
+	if err != nil {
+		t.Fatalf("Error creating cipher: %v", err)
+	}
+	decrypted := make([]byte, len(encrypted))
+	cipher.NewCTR(block, iv).XORKeyStream(decrypted, encrypted)
+	if string(decrypted) != plaintext {
+		t.Errorf("Expected the data to be encrypted with the encryption subkey")
+	}
+
+	if got, err := DecryptContainer(containerJSON, password); err != nil || got != plaintext {
+		t.Errorf("Expected %q, got %q, %v", plaintext, got, err)
+	}
+}
+
+// benchmarkEncrypt runs benchmarkSeal over payloads from 1KiB to 1MiB.
+func benchmarkEncrypt(b *testing.B, cipherName string) {
+	for _, size := range []int{1024, 64 * 1024, 1024 * 1024} {
+		b.Run(fmt.Sprintf("%dB", size), func(b *testing.B) { benchmarkSeal(b, cipherName, size) })
+	}
+}
+
+// BenchmarkEncryptCTR measures AES-256-CTR with HMAC-SHA256 throughput across payload sizes.
+func BenchmarkEncryptCTR(b *testing.B) { benchmarkEncrypt(b, CipherAES256CTR) }
+
+// BenchmarkEncryptGCM measures AES-256-GCM throughput across payload sizes.
+func BenchmarkEncryptGCM(b *testing.B) { benchmarkEncrypt(b, CipherAES256GCM) }
+
+// BenchmarkSealContainerAllocs measures the memory sealContainer allocates to encrypt and encode a 1MiB payload, excluding key derivation.
+func BenchmarkSealContainerAllocs(b *testing.B) {
+	for _, enc := range []Encoding{EncodingHex, EncodingBase64} {
+		b.Run(string(enc), func(b *testing.B) {
+			o, err := newOptions([]Option{WithIterations(minIterations), WithEncoding(enc)})
+			if err != nil {
+				b.Fatalf("Error resolving options: %v", err)
+			}
+			plaintext := make([]byte, 1024*1024)
+			salt := make([]byte, saltLen)
+			iv := make([]byte, aes.BlockSize)
+			encKey := make([]byte, keyLen)
+			macKey := make([]byte, macKeyLen)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(len(plaintext)))
+			for i := 0; i < b.N; i++ {
+				container := &Container{DeriveInfo: o.derive}
+				if _, err := sealContainer(container, plaintext, salt, iv, encKey, macKey, o); err != nil {
+					b.Fatalf("Error sealing: %v", err)
+				}
+			}
+		})
+	}
+}
+
+// TestIVLengthMismatch checks if an IV of the wrong length is reported as ErrMalformedContainer instead of panicking.
+func TestIVLengthMismatch(t *testing.T) {
+	password := "password123"
+	tests := []struct {
+		cipher  string
+		decrypt func(string, string, ...DecryptOption) (string, error)
+	}{
+		{CipherAES256CTR, DecryptContainer},
+		{CipherAES256CBC, DecryptContainerCBC},
+		{CipherAES256GCM, Decrypt},
+		{CipherXChaCha20Poly1305, Decrypt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cipher, func(t *testing.T) {
+			containerJSON := mustCreate(t, "hello iv", password, WithCipher(tt.cipher), WithIterations(minIterations))
+			for _, ivLen := range []int{0, 8, 32} {
+				var container Container
+				if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+					t.Fatalf("Failed to unmarshal container: %v", err)
+				}
+				container.EncryptionInfo.IV = hex.EncodeToString(make([]byte, ivLen))
+				tampered, _ := json.Marshal(container)
+
+				if _, err := tt.decrypt(string(tampered), password); !errors.Is(err, ErrMalformedContainer) {
+					t.Errorf("%d-byte IV: expected ErrMalformedContainer, got %v", ivLen, err)
+				}
+			}
+		})
+	}
+}
+
+// TestDecryptContainerTo checks if the plaintext is written to the writer and nothing is written for a tampered container.
+func TestDecryptContainerTo(t *testing.T) {
+	plaintext := "hello writer"
+	password := "password123"
+	containerJSON := mustCreate(t, plaintext, password, WithCipher(CipherAES256GCM), WithIterations(minIterations))
+
+	var out bytes.Buffer
+	if err := DecryptContainerTo(&out, containerJSON, password); err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if out.String() != plaintext {
+		t.Errorf("Expected %q, got %q", plaintext, out.String())
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainedData.EncryptedData = flipLastHexDigit(container.ContainedData.EncryptedData)
+	tampered, _ := json.Marshal(container)
+
+	out.Reset()
+	if err := DecryptContainerTo(&out, string(tampered), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch, got %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected nothing written for a tampered container, got %q", out.String())
+	}
+}
+
+// TestDecryptContainerFromReader checks if a container read one byte at a time decrypts, and reader errors are distinct from malformed input.
+func TestDecryptContainerFromReader(t *testing.T) {
+	plaintext := "hello reader"
+	password := "password123"
+	containerJSON := mustCreate(t, plaintext, password, WithIterations(minIterations))
+
+	decrypted, err := DecryptContainerFromReader(iotest.OneByteReader(strings.NewReader(containerJSON)), password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decrypted != plaintext {
+		t.Errorf("Expected %q, got %q", plaintext, decrypted)
+	}
+
+	errBoom := errors.New("boom")
+	r := io.MultiReader(strings.NewReader(containerJSON[:len(containerJSON)/2]), iotest.ErrReader(errBoom))
+	if _, err := DecryptContainerFromReader(r, password); !errors.Is(err, errBoom) || errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected the reader error, got %v", err)
+	}
+
+	if _, err := DecryptContainerFromReader(strings.NewReader(containerJSON[:len(containerJSON)/2]), password); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for a truncated container, got %v", err)
+	}
+	if _, err := DecryptContainerFromReader(strings.NewReader(containerJSON), "wrongpassword"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a wrong password, got %v", err)
+	}
+}
+
+// TestWithValidateUTF8 checks if binary plaintext is returned unchanged by default and rejected with ErrInvalidUTF8 only under WithValidateUTF8.
+func TestWithValidateUTF8(t *testing.T) {
+	password := "password123"
+	binary := string([]byte{0xff, 0xfe, 0x00, 0x80})
+	containerJSON := mustCreate(t, binary, password, WithIterations(minIterations))
+
+	decryptedText, err := DecryptContainer(containerJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != binary {
+		t.Errorf("Expected the binary plaintext unchanged, got %q", decryptedText)
+	}
+	if _, err := DecryptContainer(containerJSON, password, WithValidateUTF8()); !errors.Is(err, ErrInvalidUTF8) {
+		t.Errorf("Expected ErrInvalidUTF8, got: %v", err)
+	}
+	if _, err := DecryptContainerBytes([]byte(containerJSON), password, WithValidateUTF8()); err != nil {
+		t.Errorf("Expected DecryptContainerBytes to ignore WithValidateUTF8, got: %v", err)
+	}
+
+	textJSON := mustCreate(t, "héllo wörld", password, WithIterations(minIterations))
+	if decryptedText, err := Decrypt(textJSON, password, WithValidateUTF8()); err != nil || decryptedText != "héllo wörld" {
+		t.Errorf("Expected valid UTF-8 to decrypt, got %q, %v", decryptedText, err)
+	}
+}
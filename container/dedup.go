@@ -0,0 +1,25 @@
+package container
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// dedupContext separates dedup tags from any other HMAC under the same key.
+const dedupContext = "go-crypto-container dedup v1\x00"
+
+// minDedupKeyLen is the shortest key WithDedupTag accepts.
+const minDedupKeyLen = 16
+
+// dedupTagFor returns the dedup tag of plaintext under the WithDedupTag key,
+// the tag kept by RefreshContainer, or "" when there is neither.
+func (o *options) dedupTagFor(plaintext []byte) string {
+	if o.dedupKey == nil {
+		return o.dedupTag
+	}
+	mac := hmac.New(sha256.New, o.dedupKey)
+	mac.Write([]byte(dedupContext))
+	mac.Write(plaintext)
+	return hex.EncodeToString(mac.Sum(nil))
+}
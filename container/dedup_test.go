@@ -0,0 +1,55 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// TestWithDedupTag checks if containers with the same plaintext share a dedup tag only under the same tag key.
+func TestWithDedupTag(t *testing.T) {
+	password := "password123"
+	keyA := bytes.Repeat([]byte{0xa}, 32)
+	keyB := bytes.Repeat([]byte{0xb}, 32)
+
+	tag := func(plaintext string, opts ...Option) string {
+		t.Helper()
+		header, err := ParseHeader(mustCreate(t, plaintext, password, append(opts, WithIterations(minIterations))...))
+		if err != nil {
+			t.Fatalf("Error parsing header: %v", err)
+		}
+		return header.ContainerMeta.DedupTag
+	}
+
+	first, second := tag("same plaintext", WithDedupTag(keyA)), tag("same plaintext", WithDedupTag(keyA), WithCipher(CipherAES256GCM))
+	if first == "" || first != second {
+		t.Errorf("Expected equal dedup tags under the same key, got %q and %q", first, second)
+	}
+	if other := tag("same plaintext", WithDedupTag(keyB)); other == first {
+		t.Errorf("Expected different dedup tags under different keys")
+	}
+	if other := tag("other plaintext", WithDedupTag(keyA)); other == first {
+		t.Errorf("Expected different dedup tags for different plaintexts")
+	}
+	if none := tag("same plaintext"); none != "" {
+		t.Errorf("Expected no dedup tag by default, got %q", none)
+	}
+
+	if _, err := CreateContainer("x", password, WithDedupTag([]byte("short")), WithIterations(minIterations)); err == nil {
+		t.Errorf("Expected an error for a short dedup tag key")
+	}
+}
+
+// TestDedupTagAuthenticated checks if editing the dedup tag makes decryption fail.
+func TestDedupTagAuthenticated(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello dedup", password, WithDedupTag(bytes.Repeat([]byte{1}, 16)), WithIterations(minIterations))
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.DedupTag = flipLastHexDigit(container.ContainerMeta.DedupTag)
+	if _, err := Decrypt(mustMarshal(t, &container), password); err == nil {
+		t.Errorf("Expected an edited dedup tag to fail authentication")
+	}
+}
@@ -0,0 +1,194 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Deriver encrypts many items under one password and set of KDF parameters.
+// It is safe for concurrent use.
+//
+// A derived key depends on the salt, so the Deriver can only reuse one when
+// the salt repeats. By default every item gets a fresh random salt, as with
+// CreateContainer, and the KDF runs for each item. Only when params.Salt is
+// set is that salt used for every item and its keys derived once and cached.
+//
+// Reusing a salt is less secure: every container made with it shares one
+// key, so they can be linked to each other, and an attacker who recovers the
+// password by brute force opens all of them with the work of one. Fix the
+// salt only when that trade-off is acceptable, such as for many short-lived
+// items encrypted together.
+type Deriver struct {
+	password string
+	o        *options
+	// salt is the fixed salt from params.Salt, or nil for a fresh salt per
+	// item.
+	salt []byte
+	// itemSalt is set by EncryptBatch. The master key for salt is derived
+	// once and each item's keys are expanded from it with a fresh
+	// Derive.ItemSalt.
+	itemSalt bool
+
+	mu             sync.Mutex
+	encKey, macKey []byte
+	masterKey      []byte
+}
+
+// NewDeriver returns a Deriver for password using the KDF and parameters in
+// params, as stored in Container.DeriveInfo. A PBKDF2 iteration count of 0 is
+// calibrated once here. opts are applied as for CreateContainer, except that
+// options selecting the KDF, WithEnvelope and WithExternalCiphertext are not
+// allowed.
+func NewDeriver(password string, params Derive, opts ...Option) (*Deriver, error) {
+	kdfOpts, err := deriveOptions(params)
+	if err != nil {
+		return nil, err
+	}
+	o, err := newOptions(append(opts, kdfOpts...))
+	if err != nil {
+		return nil, err
+	}
+	if o.envelope {
+		return nil, errors.New("a Deriver cannot create envelope containers")
+	}
+	if o.external != nil {
+		return nil, errors.New("a Deriver cannot return external ciphertext")
+	}
+	if err := o.checkPassword(password); err != nil {
+		return nil, err
+	}
+	if o.derive.isPBKDF2() && o.derive.Iters == 0 {
+		o.derive.Iters = defaultIterations()
+	}
+
+	d := &Deriver{password: password, o: o}
+	if params.Salt != "" {
+		if d.salt, err = decodeHex(params.Salt); err != nil {
+			return nil, fmt.Errorf("decoding Salt: %w", err)
+		}
+		if len(d.salt) < minSaltLen || len(d.salt) > maxSaltLen {
+			return nil, fmt.Errorf("salt length %d is outside %d to %d bytes", len(d.salt), minSaltLen, maxSaltLen)
+		}
+	}
+	return d, nil
+}
+
+// deriveOptions returns the options selecting the KDF described by params.
+func deriveOptions(params Derive) ([]Option, error) {
+	switch name := params.kdfName(); name {
+	case KDFPBKDF2, KDFPBKDF2SHA512:
+		opts := []Option{WithKDF(name)}
+		if params.Iters != 0 {
+			opts = append(opts, WithIterations(params.Iters))
+		}
+		return opts, nil
+	case KDFArgon2id:
+		return []Option{WithArgon2Params(Argon2Params{Memory: params.Memory, Time: params.Time, Threads: params.Threads})}, nil
+	case KDFScrypt:
+		return []Option{WithScryptParams(params.N, params.R, params.P)}, nil
+	}
+	return nil, fmt.Errorf("%w: KDF %q", ErrUnsupportedAlgorithm, params.KDF)
+}
+
+// Encrypt encrypts plaintext into a new container with a fresh IV and,
+// unless the salt is fixed, a fresh salt.
+func (d *Deriver) Encrypt(plaintext []byte) (container *Container, err error) {
+	container, _, err = d.seal(plaintext)
+	return container, err
+}
+
+// seal is Encrypt that also returns the container's JSON encoding.
+func (d *Deriver) seal(plaintext []byte) (container *Container, containerJSON []byte, err error) {
+	if d.o.observer != nil {
+		start := time.Now()
+		defer func() { d.o.observer.ObserveEncrypt(time.Since(start), err) }()
+	}
+	salt := d.salt
+	var encKey, macKey, itemSalt []byte
+	switch {
+	case d.itemSalt:
+		if itemSalt, err = readRandomBytes(d.o.rand, d.o.saltLen); err != nil {
+			return nil, nil, fmt.Errorf("generating item salt: %w", err)
+		}
+		var dk []byte
+		if dk, err = d.cachedMasterKey(); err == nil {
+			encKey, macKey, err = itemKeys(dk, itemSalt)
+		}
+		defer zeroize(encKey)
+		defer zeroize(macKey)
+	case salt != nil:
+		encKey, macKey, err = d.cachedKeys()
+	default:
+		if salt, err = readRandomBytes(d.o.rand, d.o.saltLen); err != nil {
+			return nil, nil, fmt.Errorf("generating salt: %w", err)
+		}
+		encKey, macKey, err = deriveKeys(d.o.ctx, d.password, salt, &d.o.derive, d.o.keySize())
+		defer zeroize(encKey)
+		defer zeroize(macKey)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ivLen, err := ivSize(d.o.cipher)
+	if err != nil {
+		return nil, nil, err
+	}
+	iv, err := readRandomBytes(d.o.rand, ivLen)
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating IV: %w", err)
+	}
+	derive := d.o.derive
+	derive.Salt = encodeField(salt, d.o.encoding)
+	if itemSalt != nil {
+		derive.ItemSalt = encodeField(itemSalt, d.o.encoding)
+	}
+	container = &Container{DeriveInfo: derive}
+	if containerJSON, err = sealContainer(container, plaintext, salt, iv, encKey, macKey, d.o); err != nil {
+		return nil, nil, err
+	}
+	return container, containerJSON, nil
+}
+
+// cachedKeys returns the keys for the fixed salt, deriving them on first use.
+func (d *Deriver) cachedKeys() (encKey, macKey []byte, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.encKey == nil {
+		d.encKey, d.macKey, err = deriveKeys(d.o.ctx, d.password, d.salt, &d.o.derive, d.o.keySize())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return d.encKey, d.macKey, nil
+}
+
+// cachedMasterKey returns the master key for the fixed salt, deriving it on
+// first use.
+func (d *Deriver) cachedMasterKey() ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.masterKey == nil {
+		pw := []byte(d.password)
+		defer zeroize(pw)
+		dk, err := deriveKey(d.o.ctx, pw, d.salt, &d.o.derive, d.o.keySize())
+		if err != nil {
+			return nil, err
+		}
+		d.masterKey = dk
+	}
+	return d.masterKey, nil
+}
+
+// Close wipes the cached keys. It must not be called concurrently with
+// Encrypt; the Deriver derives the keys again if used after Close.
+func (d *Deriver) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	zeroize(d.encKey)
+	zeroize(d.macKey)
+	zeroize(d.masterKey)
+	d.encKey, d.macKey, d.masterKey = nil, nil, nil
+}
@@ -0,0 +1,81 @@
+package container
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestDeriver checks if a Deriver with a fixed salt reuses it and one without gives every item a fresh salt, and that both decrypt.
+func TestDeriver(t *testing.T) {
+	password := "password123"
+	fixedSalt := strings.Repeat("ab", saltLen)
+
+	for name, params := range map[string]Derive{
+		"fixed salt": {KDF: KDFPBKDF2, Iters: minIterations, Salt: fixedSalt},
+		"fresh salt": {KDF: KDFPBKDF2, Iters: minIterations},
+	} {
+		d, err := NewDeriver(password, params, WithCipher(CipherAES256GCM))
+		if err != nil {
+			t.Fatalf("%s: error creating deriver: %v", name, err)
+		}
+		salts := make(map[string]bool)
+		for _, plaintext := range []string{"first", "second", "third"} {
+			container, err := d.Encrypt([]byte(plaintext))
+			if err != nil {
+				t.Fatalf("%s: error encrypting: %v", name, err)
+			}
+			salts[container.DeriveInfo.Salt] = true
+			decrypted, err := Decrypt(mustMarshal(t, container), password)
+			if err != nil {
+				t.Fatalf("%s: error decrypting: %v", name, err)
+			}
+			if decrypted != plaintext {
+				t.Errorf("%s: expected %q, got %q", name, plaintext, decrypted)
+			}
+		}
+		d.Close()
+
+		if params.Salt != "" && (len(salts) != 1 || !salts[fixedSalt]) {
+			t.Errorf("%s: expected every container to use the fixed salt, got %v", name, salts)
+		}
+		if params.Salt == "" && len(salts) != 3 {
+			t.Errorf("%s: expected 3 distinct salts, got %d", name, len(salts))
+		}
+	}
+}
+
+// TestNewDeriverRejects checks if unsupported KDFs, bad salts and envelope encryption are rejected.
+func TestNewDeriverRejects(t *testing.T) {
+	if _, err := NewDeriver("password123", Derive{KDF: KDFNone}); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm for KDF none, got: %v", err)
+	}
+	if _, err := NewDeriver("password123", Derive{KDF: KDFPBKDF2, Iters: minIterations, Salt: "zz"}); !errors.Is(err, ErrInvalidHex) {
+		t.Errorf("Expected ErrInvalidHex for a bad salt, got: %v", err)
+	}
+	if _, err := NewDeriver("password123", Derive{KDF: KDFPBKDF2, Iters: minIterations, Salt: "abcd"}); err == nil {
+		t.Errorf("Expected an error for a 2-byte salt")
+	}
+	if _, err := NewDeriver("password123", Derive{KDF: KDFPBKDF2, Iters: minIterations}, WithEnvelope()); err == nil {
+		t.Errorf("Expected an error for envelope encryption")
+	}
+}
+
+// BenchmarkDeriver compares encrypting small items with a fixed salt, where the key is cached, against a fresh salt per item.
+func BenchmarkDeriver(b *testing.B) {
+	plaintext := []byte("a small item")
+	for name, salt := range map[string]string{"FixedSalt": strings.Repeat("ab", saltLen), "FreshSalt": ""} {
+		b.Run(name, func(b *testing.B) {
+			d, err := NewDeriver("password123", Derive{KDF: KDFPBKDF2, Iters: minIterations, Salt: salt})
+			if err != nil {
+				b.Fatalf("Error creating deriver: %v", err)
+			}
+			defer d.Close()
+			for i := 0; i < b.N; i++ {
+				if _, err := d.Encrypt(plaintext); err != nil {
+					b.Fatalf("Error encrypting: %v", err)
+				}
+			}
+		})
+	}
+}
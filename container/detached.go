@@ -0,0 +1,60 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// CreateContainerDetached is CreateContainer returning the MAC separately
+// from the rest of the container, for workflows that store them apart, such
+// as the data in object storage and the MAC in a database. header is the
+// container JSON with an empty HMAC field and mac is that field, in the
+// container's encoding. Only ciphers authenticated with HMAC have a MAC to
+// detach, so other ciphers are rejected.
+func CreateContainerDetached(plaintext, password string, opts ...Option) (header string, mac string, err error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return "", "", err
+	}
+	if !usesHMAC(o.cipher) {
+		return "", "", fmt.Errorf("%w: cipher %s has no detachable MAC", ErrUnsupportedAlgorithm, o.cipher)
+	}
+	pt := []byte(plaintext)
+	defer zeroize(pt)
+	b, err := createContainer(pt, password, o)
+	if err != nil {
+		return "", "", err
+	}
+
+	var container Container
+	if err := json.Unmarshal(b, &container); err != nil {
+		return "", "", err
+	}
+	mac = container.ContainedData.HMAC
+	container.ContainedData.HMAC = ""
+	if b, err = container.marshal(); err != nil {
+		return "", "", err
+	}
+	return string(b), mac, nil
+}
+
+// DecryptContainerDetached decrypts a container split by
+// CreateContainerDetached, given its header and MAC. It returns
+// ErrHMACMismatch if mac does not belong to header or the password is wrong.
+func DecryptContainerDetached(header, mac, password string, opts ...DecryptOption) (string, error) {
+	var container Container
+	if err := json.Unmarshal([]byte(header), &container); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if container.ContainedData.HMAC != "" {
+		return "", fmt.Errorf("%w: header already holds a MAC", ErrMalformedContainer)
+	}
+	container.ContainedData.HMAC = mac
+	p := newDecryptParams("", opts)
+	plaintext, err := openContainer(&container, password, p)
+	if err != nil {
+		return "", err
+	}
+	defer zeroize(plaintext)
+	return p.plaintextString(plaintext)
+}
@@ -0,0 +1,44 @@
+package container
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestDetachedMAC checks if a detached container decrypts with its own MAC and fails with ErrHMACMismatch given another one.
+func TestDetachedMAC(t *testing.T) {
+	password := "password123"
+	for _, enc := range []Encoding{EncodingHex, EncodingBase64} {
+		header, mac, err := CreateContainerDetached("hello detached", password, WithEncoding(enc), WithIterations(minIterations))
+		if err != nil {
+			t.Fatalf("%s: error creating detached container: %v", enc, err)
+		}
+		if mac == "" || strings.Contains(header, mac) {
+			t.Errorf("%s: expected the MAC to be kept out of the header", enc)
+		}
+		if err := VerifyChecksum(header); err != nil {
+			t.Errorf("%s: expected the header to carry a valid checksum, got: %v", enc, err)
+		}
+
+		decryptedText, err := DecryptContainerDetached(header, mac, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting detached container: %v", enc, err)
+		}
+		if decryptedText != "hello detached" {
+			t.Errorf("%s: expected %q, got %q", enc, "hello detached", decryptedText)
+		}
+
+		_, otherMAC, err := CreateContainerDetached("hello detached", password, WithEncoding(enc), WithIterations(minIterations))
+		if err != nil {
+			t.Fatalf("%s: error creating detached container: %v", enc, err)
+		}
+		if _, err := DecryptContainerDetached(header, otherMAC, password); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("%s: expected ErrHMACMismatch for a mismatched MAC, got: %v", enc, err)
+		}
+	}
+
+	if _, _, err := CreateContainerDetached("x", password, WithCipher(CipherAES256GCM), WithIterations(minIterations)); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm for an AEAD cipher, got: %v", err)
+	}
+}
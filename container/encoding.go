@@ -0,0 +1,76 @@
+package container
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Encoding is the text encoding of the binary fields of a container: the
+// salt, IV, ciphertext and MAC. It is recorded in Meta.Encoding; containers
+// without one use hex. Wrapped keys and signatures are always hex.
+type Encoding string
+
+// Supported encodings.
+const (
+	EncodingHex Encoding = "hex"
+	// EncodingBase64 uses unpadded standard base64, which adds about a third
+	// to the size of a field where hex doubles it.
+	EncodingBase64 Encoding = "base64"
+)
+
+func (e Encoding) valid() bool {
+	return e == "" || e == EncodingHex || e == EncodingBase64
+}
+
+// encodeChunk is how many bytes encodeField encodes at a time.
+const encodeChunk = 768
+
+// encodeField encodes b with enc straight into a string builder sized for
+// the result, so large fields are allocated once rather than encoded into a
+// byte slice and then copied into a string.
+func encodeField(b []byte, enc Encoding) string {
+	b64 := enc == EncodingBase64
+	var sb strings.Builder
+	if b64 {
+		sb.Grow(base64.RawStdEncoding.EncodedLen(len(b)))
+	} else {
+		sb.Grow(hex.EncodedLen(len(b)))
+	}
+	// encodeChunk is a multiple of 3, so base64 chunks need no padding and
+	// concatenate to the encoding of b.
+	var buf [2 * encodeChunk]byte
+	for len(b) > 0 {
+		n := min(len(b), encodeChunk)
+		if b64 {
+			m := base64.RawStdEncoding.EncodedLen(n)
+			base64.RawStdEncoding.Encode(buf[:m], b[:n])
+			sb.Write(buf[:m])
+		} else {
+			sb.Write(buf[:hex.Encode(buf[:], b[:n])])
+		}
+		b = b[n:]
+	}
+	return sb.String()
+}
+
+// decodeField decodes s from enc, wrapping any error in ErrInvalidHex or
+// ErrInvalidBase64.
+func decodeField(s string, enc Encoding) ([]byte, error) {
+	switch enc {
+	case "", EncodingHex:
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidHex, err)
+		}
+		return b, nil
+	case EncodingBase64:
+		b, err := base64.RawStdEncoding.DecodeString(s)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBase64, err)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("%w: unknown encoding %q", ErrMalformedContainer, enc)
+}
@@ -0,0 +1,83 @@
+package container
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestWithEncoding checks if hex and base64 containers round-trip and base64 is meaningfully shorter.
+func TestWithEncoding(t *testing.T) {
+	plaintext := strings.Repeat("0123456789abcdef", 64)
+	password := "password123"
+
+	sizes := make(map[Encoding]int)
+	for _, enc := range []Encoding{EncodingHex, EncodingBase64} {
+		for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+			containerJSON := mustCreate(t, plaintext, password, WithEncoding(enc), WithCipher(cipherName), WithIterations(minIterations))
+
+			header, err := ParseHeader(containerJSON)
+			if err != nil {
+				t.Fatalf("%s/%s: error parsing header: %v", enc, cipherName, err)
+			}
+			if header.ContainerMeta.Encoding != enc {
+				t.Errorf("%s/%s: expected encoding %q, got %q", enc, cipherName, enc, header.ContainerMeta.Encoding)
+			}
+			decryptedText, err := Decrypt(containerJSON, password)
+			if err != nil {
+				t.Fatalf("%s/%s: error decrypting container: %v", enc, cipherName, err)
+			}
+			if decryptedText != plaintext {
+				t.Errorf("%s/%s: decrypted text does not match the plaintext", enc, cipherName)
+			}
+			if cipherName == CipherAES256CTR {
+				sizes[enc] = len(containerJSON)
+			}
+		}
+	}
+
+	if sizes[EncodingBase64]*10 > sizes[EncodingHex]*8 {
+		t.Errorf("Expected base64 to be at least 20%% smaller than hex, got %d vs %d bytes", sizes[EncodingBase64], sizes[EncodingHex])
+	}
+}
+
+// TestEncodingErrors checks if unknown encodings are rejected and malformed base64 returns ErrInvalidBase64.
+func TestEncodingErrors(t *testing.T) {
+	password := "password123"
+	if _, err := CreateContainer("hello world", password, WithEncoding("base32")); err == nil {
+		t.Errorf("Expected an error for an unsupported encoding")
+	}
+
+	containerJSON := mustCreate(t, "hello world", password, WithEncoding(EncodingBase64), WithIterations(minIterations))
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainedData.HMAC = "!!" + container.ContainedData.HMAC
+	tampered, err := json.Marshal(container)
+	if err != nil {
+		t.Fatalf("Failed to marshal tampered container: %v", err)
+	}
+	if _, err := DecryptContainer(string(tampered), password); !errors.Is(err, ErrInvalidBase64) {
+		t.Errorf("Expected ErrInvalidBase64, got: %v", err)
+	}
+}
+
+// TestEncodeFieldMatchesEncodeToString checks if encodeField produces the standard encodings across chunk boundaries.
+func TestEncodeFieldMatchesEncodeToString(t *testing.T) {
+	for _, n := range []int{0, 1, 2, 3, encodeChunk - 1, encodeChunk, encodeChunk + 1, 3*encodeChunk + 2} {
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = byte(i * 7)
+		}
+		if got, want := encodeField(b, EncodingHex), hex.EncodeToString(b); got != want {
+			t.Errorf("Expected hex encoding of %d bytes to match hex.EncodeToString", n)
+		}
+		if got, want := encodeField(b, EncodingBase64), base64.RawStdEncoding.EncodeToString(b); got != want {
+			t.Errorf("Expected base64 encoding of %d bytes to match base64.RawStdEncoding", n)
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package container
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Key wrapping algorithms recorded in WrappedKey.Algorithm.
+const (
+	// KeyWrapPasswordKW wraps the content key with AES Key Wrap (RFC 3394)
+	// under a key derived from a password as described by
+	// WrappedKey.DeriveInfo. It is used for all new password recipients.
+	KeyWrapPasswordKW = "password-aes-kw"
+)
+
+// WrappedKey holds the content key of an envelope container, encrypted for
+// one recipient so that only the holder of its password or private key can
+// recover it.
+type WrappedKey struct {
+	Algorithm  string  `json:"Algorithm"`
+	DeriveInfo *Derive `json:"DeriveInfo,omitempty"`
+	// EphemeralKey is the sender's ephemeral X25519 public key for
+	// KeyWrapX25519.
+	EphemeralKey string `json:"EphemeralKey,omitempty"`
+	Nonce        string `json:"Nonce,omitempty"`
+	Key          string `json:"Key"`
+}
+
+// createEnvelope encrypts plaintext under a random content key and stores
+// that key as wrapped by wrap, once per recipient.
+func createEnvelope(plaintext []byte, o *options, wrap func(cek []byte) ([]WrappedKey, error)) ([]byte, error) {
+	ivLen, err := ivSize(o.cipher)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := readRandomBytes(o.rand, ivLen)
+	if err != nil {
+		return nil, fmt.Errorf("generating IV: %w", err)
+	}
+	cek, err := readRandomBytes(o.rand, o.keySize())
+	if err != nil {
+		return nil, fmt.Errorf("generating content key: %w", err)
+	}
+	defer zeroize(cek)
+
+	wrapped, err := wrap(cek)
+	if err != nil {
+		return nil, err
+	}
+	encKey, macKey, err := splitKey(cek)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(encKey)
+	defer zeroize(macKey)
+
+	container := &Container{WrappedKeys: wrapped}
+	return sealContainer(container, plaintext, nil, iv, encKey, macKey, o)
+}
+
+// wrapWithPassword wraps cek under a key derived from password with the KDF
+// configured in o and a fresh salt.
+func wrapWithPassword(cek []byte, password string, o *options) (*WrappedKey, error) {
+	salt, err := readRandomBytes(o.rand, o.saltLen)
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	derive := o.derive
+	derive.Salt = hex.EncodeToString(salt)
+	if derive.isPBKDF2() && derive.Iters == 0 {
+		derive.Iters = defaultIterations()
+	}
+	return wrapWithDerive(o.ctx, cek, password, derive)
+}
+
+// wrapWithDerive wraps cek with AES Key Wrap under the key derived from
+// password as described by derive.
+func wrapWithDerive(ctx context.Context, cek []byte, password string, derive Derive) (*WrappedKey, error) {
+	salt, err := decodeHex(derive.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.DeriveInfo.Salt: %w", err)
+	}
+	pw := []byte(password)
+	defer zeroize(pw)
+	kek, err := deriveKey(ctx, pw, salt, &derive, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(kek)
+
+	key, err := wrapKey(kek, cek)
+	if err != nil {
+		return nil, err
+	}
+	return &WrappedKey{Algorithm: KeyWrapPasswordKW, DeriveInfo: &derive, Key: hex.EncodeToString(key)}, nil
+}
+
+// sealKey encrypts cek with AES-256-GCM under kek into w, binding
+// w.Algorithm as additional data.
+func sealKey(w *WrappedKey, kek, cek []byte, r io.Reader) error {
+	aead, err := newKeyWrapAEAD(kek)
+	if err != nil {
+		return err
+	}
+	nonce, err := readRandomBytes(r, aead.NonceSize())
+	if err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+	w.Nonce = hex.EncodeToString(nonce)
+	w.Key = hex.EncodeToString(aead.Seal(nil, nonce, cek, []byte(w.Algorithm)))
+	return nil
+}
+
+// openKey reverses sealKey, returning ErrHMACMismatch if kek is wrong or w
+// was modified.
+func openKey(w *WrappedKey, kek []byte) ([]byte, error) {
+	nonce, err := decodeHex(w.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.Nonce: %w", err)
+	}
+	sealed, err := decodeHex(w.Key)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.Key: %w", err)
+	}
+	aead, err := newKeyWrapAEAD(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, ErrHMACMismatch
+	}
+	cek, err := aead.Open(nil, nonce, sealed, []byte(w.Algorithm))
+	if err != nil {
+		return nil, ErrHMACMismatch
+	}
+	return cek, nil
+}
+
+func newKeyWrapAEAD(kek []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// errNotRecipient is returned by an unwrap function for wrapped keys it
+// cannot open, such as those wrapped for a different kind of recipient.
+var errNotRecipient = errors.New("not a recipient of this wrapped key")
+
+// unwrapWithPassword recovers the content key from a password-wrapped key.
+func unwrapWithPassword(ctx context.Context, w *WrappedKey, password string) ([]byte, error) {
+	if w.Algorithm != KeyWrapPasswordKW {
+		return nil, errNotRecipient
+	}
+	if w.DeriveInfo == nil {
+		return nil, fmt.Errorf("%w: missing WrappedKey.DeriveInfo", ErrMalformedContainer)
+	}
+	if !knownKDF(w.DeriveInfo.kdfName()) {
+		return nil, fmt.Errorf("%w: KDF %q", ErrUnsupportedAlgorithm, w.DeriveInfo.KDF)
+	}
+	salt, err := decodeHex(w.DeriveInfo.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.DeriveInfo.Salt: %w", err)
+	}
+	pw := []byte(password)
+	defer zeroize(pw)
+	kek, err := deriveKey(ctx, pw, salt, w.DeriveInfo, keyLen)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(kek)
+	wrapped, err := decodeHex(w.Key)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.Key: %w", err)
+	}
+	return unwrapKey(kek, wrapped)
+}
+
+// findContentKey tries unwrap on every wrapped key of c in turn and returns
+// the first content key it recovers along with the index of its slot.
+func findContentKey(c *Container, unwrap func(w *WrappedKey) ([]byte, error)) (cek []byte, slot int, err error) {
+	if len(c.WrappedKeys) == 0 {
+		return nil, 0, fmt.Errorf("%w: missing WrappedKeys", ErrMalformedContainer)
+	}
+	for i := range c.WrappedKeys {
+		cek, err := unwrap(&c.WrappedKeys[i])
+		switch {
+		case err == nil:
+			return cek, i, nil
+		case errors.Is(err, ErrHMACMismatch), errors.Is(err, errNotRecipient):
+			continue
+		default:
+			return nil, 0, err
+		}
+	}
+	return nil, 0, ErrHMACMismatch
+}
+
+// unwrapKeys recovers the content key of an envelope container with unwrap
+// and derives the data keys from it.
+func unwrapKeys(c *Container, unwrap func(w *WrappedKey) ([]byte, error), keySize int) (encKey, macKey []byte, err error) {
+	cek, _, err := findContentKey(c, unwrap)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer zeroize(cek)
+	if len(cek) != keySize {
+		return nil, nil, fmt.Errorf("%w: content key is %d bytes, want %d", ErrMalformedContainer, len(cek), keySize)
+	}
+	return splitKey(cek)
+}
+
+// CreateContainerMulti encrypts plaintext into an envelope container that
+// any one of passwords can open. The content key is wrapped once per
+// password, each with its own salt. opts are applied as for CreateContainer.
+func CreateContainerMulti(plaintext string, passwords []string, opts ...Option) (string, error) {
+	if len(passwords) == 0 {
+		return "", errors.New("at least one password is required")
+	}
+	o, err := newOptions(append([]Option{WithEnvelope()}, opts...))
+	if err != nil {
+		return "", err
+	}
+	for _, password := range passwords {
+		if err := o.checkPassword(password); err != nil {
+			return "", err
+		}
+	}
+	pt := []byte(plaintext)
+	defer zeroize(pt)
+	b, err := createEnvelope(pt, o, func(cek []byte) ([]WrappedKey, error) {
+		wrapped := make([]WrappedKey, 0, len(passwords))
+		for _, password := range passwords {
+			w, err := wrapWithPassword(cek, password, o)
+			if err != nil {
+				return nil, err
+			}
+			wrapped = append(wrapped, *w)
+		}
+		return wrapped, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// parseEnvelope unmarshals an envelope container.
+func parseEnvelope(containerJSON string) (*Container, error) {
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if container.ContainerMeta.Version != versionEnvelope {
+		return nil, fmt.Errorf("%w: %q is not an envelope container", ErrUnsupportedVersion, container.ContainerMeta.Version)
+	}
+	return &container, nil
+}
+
+// rewrapWithPassword wraps cek for password using the KDF parameters of w
+// with a fresh salt of the same length.
+func rewrapWithPassword(cek []byte, password string, w *WrappedKey) (*WrappedKey, error) {
+	derive := *w.DeriveInfo
+	oldSalt, err := decodeHex(derive.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.DeriveInfo.Salt: %w", err)
+	}
+	salt, err := generateRandomBytes(len(oldSalt))
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	derive.Salt = hex.EncodeToString(salt)
+	return wrapWithDerive(context.Background(), cek, password, derive)
+}
+
+// RekeyContainer changes a password of an envelope container created with
+// WithEnvelope or CreateContainerMulti. Only the wrapped content key that
+// oldPassword opens is replaced; the encrypted data is neither read nor
+// rewritten, so this is cheap for large containers. The new wrapping uses
+// the same KDF parameters as the old one with a fresh salt.
+func RekeyContainer(containerJSON, oldPassword, newPassword string) (string, error) {
+	return updateRecipients(containerJSON, oldPassword, newPassword, true)
+}
+
+// AddRecipient grants newPassword access to an envelope container that
+// existingPassword can already open, without re-encrypting the data. The
+// new wrapping uses the same KDF parameters as that of existingPassword.
+func AddRecipient(containerJSON, existingPassword, newPassword string) (string, error) {
+	return updateRecipients(containerJSON, existingPassword, newPassword, false)
+}
+
+// updateRecipients wraps the content key that password opens for
+// newPassword, either replacing the slot of password or adding a new one.
+func updateRecipients(containerJSON, password, newPassword string, replace bool) (string, error) {
+	container, err := parseEnvelope(containerJSON)
+	if err != nil {
+		return "", err
+	}
+	cek, slot, err := findContentKey(container, func(w *WrappedKey) ([]byte, error) {
+		return unwrapWithPassword(context.Background(), w, password)
+	})
+	if err != nil {
+		return "", err
+	}
+	defer zeroize(cek)
+
+	wrapped, err := rewrapWithPassword(cek, newPassword, &container.WrappedKeys[slot])
+	if err != nil {
+		return "", err
+	}
+	if replace {
+		container.WrappedKeys[slot] = *wrapped
+	} else {
+		container.WrappedKeys = append(container.WrappedKeys, *wrapped)
+	}
+
+	b, err := container.marshal()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
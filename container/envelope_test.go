@@ -0,0 +1,201 @@
+package container
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestEnvelopeRoundTrip checks if envelope containers decrypt with every cipher and expose their wrapped key in the header.
+func TestEnvelopeRoundTrip(t *testing.T) {
+	plaintext := "hello envelope"
+	password := "password123"
+
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM, CipherXChaCha20Poly1305} {
+		containerJSON := mustCreate(t, plaintext, password, WithEnvelope(), WithCipher(cipherName), WithIterations(minIterations))
+
+		header, err := ParseHeader(containerJSON)
+		if err != nil {
+			t.Fatalf("%s: error parsing header: %v", cipherName, err)
+		}
+		if header.ContainerMeta.Version != versionEnvelope || len(header.WrappedKeys) != 1 || header.WrappedKeys[0].Algorithm != KeyWrapPasswordKW {
+			t.Errorf("%s: expected a %s container with a password-wrapped key, got %+v", cipherName, versionEnvelope, header)
+		}
+
+		decryptedText, err := Decrypt(containerJSON, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting container: %v", cipherName, err)
+		}
+		if decryptedText != plaintext {
+			t.Errorf("%s: expected %q, got %q", cipherName, plaintext, decryptedText)
+		}
+		if _, err := Decrypt(containerJSON, "wrong password"); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("%s: expected ErrHMACMismatch for a wrong password, got: %v", cipherName, err)
+		}
+	}
+}
+
+// TestRekeyContainer checks if rekeying swaps the password without touching the encrypted data.
+func TestRekeyContainer(t *testing.T) {
+	plaintext := "hello rekey"
+	containerJSON := mustCreate(t, plaintext, "old password", WithEnvelope(), WithCipher(CipherAES256GCM), WithIterations(minIterations))
+
+	rekeyed, err := RekeyContainer(containerJSON, "old password", "new password")
+	if err != nil {
+		t.Fatalf("Error rekeying container: %v", err)
+	}
+
+	decryptedText, err := Decrypt(rekeyed, "new password")
+	if err != nil {
+		t.Fatalf("Error decrypting rekeyed container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected %q, got %q", plaintext, decryptedText)
+	}
+	if _, err := Decrypt(rekeyed, "old password"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the old password, got: %v", err)
+	}
+
+	var before, after Container
+	if err := json.Unmarshal([]byte(containerJSON), &before); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if err := json.Unmarshal([]byte(rekeyed), &after); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if before.ContainedData != after.ContainedData || before.EncryptionInfo != after.EncryptionInfo {
+		t.Errorf("Expected the encrypted data to be unchanged by rekeying")
+	}
+	if len(after.WrappedKeys) != 1 || before.WrappedKeys[0].DeriveInfo.Salt == after.WrappedKeys[0].DeriveInfo.Salt {
+		t.Errorf("Expected a fresh salt for the new password")
+	}
+
+	if _, err := RekeyContainer(containerJSON, "wrong password", "new password"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a wrong old password, got: %v", err)
+	}
+	if _, err := RekeyContainer(mustCreate(t, plaintext, "old password"), "old password", "new password"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion for a non-envelope container, got: %v", err)
+	}
+}
+
+// TestCreateContainerMulti checks if each of several passwords opens a multi-recipient container independently.
+func TestCreateContainerMulti(t *testing.T) {
+	plaintext := "hello team"
+	passwords := []string{"alice password", "bob password"}
+
+	containerJSON, err := CreateContainerMulti(plaintext, passwords, WithCipher(CipherAES256GCM), WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if len(header.WrappedKeys) != len(passwords) {
+		t.Fatalf("Expected %d wrapped keys, got %d", len(passwords), len(header.WrappedKeys))
+	}
+	if header.WrappedKeys[0].DeriveInfo.Salt == header.WrappedKeys[1].DeriveInfo.Salt {
+		t.Errorf("Expected every recipient to have its own salt")
+	}
+
+	for _, password := range passwords {
+		decryptedText, err := Decrypt(containerJSON, password)
+		if err != nil {
+			t.Fatalf("Error decrypting container with %q: %v", password, err)
+		}
+		if decryptedText != plaintext {
+			t.Errorf("Expected %q, got %q", plaintext, decryptedText)
+		}
+	}
+	if _, err := Decrypt(containerJSON, "mallory password"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for an unknown password, got: %v", err)
+	}
+
+	// Rekeying one recipient leaves the other untouched.
+	rekeyed, err := RekeyContainer(containerJSON, "bob password", "bob new password")
+	if err != nil {
+		t.Fatalf("Error rekeying container: %v", err)
+	}
+	for password, want := range map[string]error{"alice password": nil, "bob new password": nil, "bob password": ErrHMACMismatch} {
+		if _, err := Decrypt(rekeyed, password); !errors.Is(err, want) {
+			t.Errorf("Expected %v for %q after rekeying, got: %v", want, password, err)
+		}
+	}
+
+	if _, err := CreateContainerMulti(plaintext, nil); err == nil {
+		t.Errorf("Expected an error without passwords")
+	}
+}
+
+// TestAddRecipient checks if a recipient can be added later without changing the encrypted data.
+func TestAddRecipient(t *testing.T) {
+	plaintext := "hello newcomer"
+	containerJSON := mustCreate(t, plaintext, "alice password", WithEnvelope(), WithIterations(minIterations))
+
+	updated, err := AddRecipient(containerJSON, "alice password", "carol password")
+	if err != nil {
+		t.Fatalf("Error adding recipient: %v", err)
+	}
+	for _, password := range []string{"alice password", "carol password"} {
+		decryptedText, err := Decrypt(updated, password)
+		if err != nil {
+			t.Fatalf("Error decrypting container with %q: %v", password, err)
+		}
+		if decryptedText != plaintext {
+			t.Errorf("Expected %q, got %q", plaintext, decryptedText)
+		}
+	}
+
+	var before, after Container
+	if err := json.Unmarshal([]byte(containerJSON), &before); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if err := json.Unmarshal([]byte(updated), &after); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if before.ContainedData != after.ContainedData {
+		t.Errorf("Expected the encrypted data to be unchanged by adding a recipient")
+	}
+
+	if _, err := AddRecipient(containerJSON, "wrong password", "carol password"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a wrong existing password, got: %v", err)
+	}
+}
+
+// TestPasswordWrapRequiresAESKW checks if a password-wrapped key sealed with anything but AES-KW is not accepted.
+func TestPasswordWrapRequiresAESKW(t *testing.T) {
+	password := "password123"
+	var container Container
+	if err := json.Unmarshal([]byte(mustCreate(t, "hello wrap", password, WithEnvelope(), WithIterations(minIterations))), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+
+	w := &container.WrappedKeys[0]
+	salt, err := decodeHex(w.DeriveInfo.Salt)
+	if err != nil {
+		t.Fatalf("Error decoding salt: %v", err)
+	}
+	kek, err := deriveKey(context.Background(), []byte(password), salt, w.DeriveInfo, keyLen)
+	if err != nil {
+		t.Fatalf("Error deriving KEK: %v", err)
+	}
+	wrapped, err := decodeHex(w.Key)
+	if err != nil {
+		t.Fatalf("Error decoding wrapped key: %v", err)
+	}
+	cek, err := unwrapKey(kek, wrapped)
+	if err != nil {
+		t.Fatalf("Error unwrapping key: %v", err)
+	}
+	w.Algorithm = "password"
+	if err := sealKey(w, kek, cek, rand.Reader); err != nil {
+		t.Fatalf("Error sealing key: %v", err)
+	}
+
+	if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a GCM-wrapped password key, got: %v", err)
+	}
+}
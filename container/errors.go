@@ -0,0 +1,90 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by this package. They are usually wrapped with details, so
+// compare against them with errors.Is.
+var (
+	// ErrHMACMismatch is returned when a container fails authentication,
+	// because the password is wrong or the container was modified.
+	ErrHMACMismatch = errors.New("HMAC mismatch")
+	// ErrMalformedContainer is returned when a container is structurally
+	// invalid, for example when its ciphertext is too short to have been
+	// produced by CreateContainer.
+	ErrMalformedContainer = errors.New("malformed container")
+	// ErrUnsupportedVersion is returned for containers in a format version
+	// this package cannot read.
+	ErrUnsupportedVersion = errors.New("unsupported container version")
+	// ErrInvalidHex is returned when a hex-encoded field cannot be decoded.
+	ErrInvalidHex = errors.New("invalid hex")
+	// ErrInvalidBase64 is returned when a field of a container written with
+	// EncodingBase64 cannot be decoded.
+	ErrInvalidBase64 = errors.New("invalid base64")
+	// ErrUnsupportedAlgorithm is returned for a cipher or KDF name this
+	// package does not implement.
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	// ErrExpired is returned for an authentic container whose expiry time,
+	// set with WithExpiry, has passed.
+	ErrExpired = errors.New("container expired")
+	// ErrIterationsTooHigh is returned for a PBKDF2 iteration count above
+	// MaxIterations.
+	ErrIterationsTooHigh = errors.New("iteration count too high")
+	// ErrKDFParamsTooHigh is returned for Argon2id or scrypt parameters
+	// above MaxArgon2Memory, MaxArgon2Time, MaxScryptMemory or MaxScryptP.
+	ErrKDFParamsTooHigh = errors.New("KDF parameters too high")
+	// ErrInvalidSignature is returned by VerifySignature when a container is
+	// unsigned, was modified after signing or was signed by another key.
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrWeakPassword is returned when a password is weaker than the
+	// threshold set with WithMinPasswordEntropy.
+	ErrWeakPassword = errors.New("password too weak")
+	// ErrWeakKDFParams is returned for Argon2id or scrypt parameters below
+	// the recommended minimums, unless WithAllowWeakParams is given.
+	ErrWeakKDFParams = errors.New("KDF parameters too weak")
+	// ErrKeyTypeMismatch is returned when a container created with
+	// CreateContainerWithKey is opened with a password, or a password
+	// container is opened with DecryptContainerWithKey.
+	ErrKeyTypeMismatch = errors.New("container uses a different kind of key")
+	// ErrChecksumMismatch is returned by VerifyChecksum when a container
+	// has no checksum or was changed since it was computed.
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrSelfTestFailed is returned by SelfTest when a primitive gives a
+	// wrong answer for a known input.
+	ErrSelfTestFailed = errors.New("self-test failed")
+	// ErrInvalidUTF8 is returned when WithValidateUTF8 is given and the
+	// plaintext is not valid UTF-8. The container itself is authentic.
+	ErrInvalidUTF8 = errors.New("plaintext is not valid UTF-8")
+	// ErrNestedContainer is returned when WithRejectNestedContainer is given
+	// and the plaintext is itself a container.
+	ErrNestedContainer = errors.New("plaintext is already a container")
+)
+
+// ErrChunkAuth is returned by DecryptStream when a chunk fails
+// authentication. Index is the zero-based index of the first failing chunk.
+// It matches ErrHMACMismatch with errors.Is.
+type ErrChunkAuth struct {
+	Index uint64
+}
+
+func (e ErrChunkAuth) Error() string {
+	return fmt.Sprintf("chunk %d: %v", e.Index, ErrHMACMismatch)
+}
+
+func (e ErrChunkAuth) Unwrap() error { return ErrHMACMismatch }
+
+// ErrEntry is returned by DecryptMulti for an entry that fails to decrypt.
+// Index is the zero-based index of the entry and Err the reason, which
+// errors.Is and errors.As see through.
+type ErrEntry struct {
+	Index int
+	Err   error
+}
+
+func (e ErrEntry) Error() string {
+	return fmt.Sprintf("entry %d: %v", e.Index, e.Err)
+}
+
+func (e ErrEntry) Unwrap() error { return e.Err }
@@ -0,0 +1,95 @@
+package container
+
+import (
+	"crypto/aes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+// EstimateSize returns the size in bytes of the JSON container that
+// CreateContainer would produce for a plaintext of plaintextLen bytes with
+// opts, without encrypting anything. It accounts for the encoding, cipher
+// overhead, KDF parameters, wrapped keys and indentation. Compression is
+// assumed not to change the size, since it depends on the data. A PBKDF2
+// iteration count that is not given is calibrated once per process, as for
+// CreateContainer. It returns 0 if opts are invalid.
+func EstimateSize(plaintextLen int, opts ...Option) int {
+	o, err := newOptions(opts)
+	if err != nil || plaintextLen < 0 {
+		return 0
+	}
+	ivLen, err := ivSize(o.cipher)
+	if err != nil {
+		return 0
+	}
+
+	// Field lengths are all that matter, so zero bytes stand in for the
+	// random and secret values.
+	derive := o.derive
+	if derive.isPBKDF2() && derive.Iters == 0 {
+		derive.Iters = defaultIterations()
+	}
+	var dedupTag string
+	if o.dedupKey != nil || o.dedupTag != "" {
+		dedupTag = strings.Repeat("0", hex.EncodedLen(32))
+	}
+	container := Container{
+		ContainerMeta:  o.meta(plaintextLen, dedupTag),
+		EncryptionInfo: Encryption{IV: encodeField(make([]byte, ivLen), o.encoding), Cipher: o.cipher, KeySize: o.keySizeOpt},
+	}
+	container.ContainerMeta.Checksum = strings.Repeat("0", hex.EncodedLen(32))
+	if o.envelope {
+		derive.Salt = hex.EncodeToString(make([]byte, o.saltLen))
+		container.WrappedKeys = []WrappedKey{{
+			Algorithm:  KeyWrapPasswordKW,
+			DeriveInfo: &derive,
+			Key:        hex.EncodeToString(make([]byte, o.keySize()+8)),
+		}}
+	} else {
+		derive.Salt = encodeField(make([]byte, o.saltLen), o.encoding)
+		container.DeriveInfo = derive
+	}
+	if usesHMAC(o.cipher) {
+		container.ContainedData.HMAC = encodeField(make([]byte, macHashes[o.macHash]().Size()), o.encoding)
+	}
+
+	var b []byte
+	if o.indentPrefix == "" && o.indent == "" {
+		b, err = json.Marshal(&container)
+	} else {
+		b, err = json.MarshalIndent(&container, o.indentPrefix, o.indent)
+	}
+	if err != nil {
+		return 0
+	}
+	if o.external != nil {
+		return len(b)
+	}
+	n := plaintextLen
+	if o.padding != 0 {
+		n = paddedLen(n, o.padding)
+	}
+	n = ciphertextLen(o.cipher, n)
+	if o.encoding == EncodingBase64 {
+		return len(b) + base64.RawStdEncoding.EncodedLen(n)
+	}
+	return len(b) + hex.EncodedLen(n)
+}
+
+// ciphertextLen returns the length of the ciphertext cipherName produces
+// for a plaintext of n bytes.
+func ciphertextLen(cipherName string, n int) int {
+	switch cipherName {
+	case CipherAES256CTR:
+		return n
+	case CipherAES256CBC:
+		return n + aes.BlockSize - n%aes.BlockSize
+	}
+	aead, err := newAEAD(cipherName, make([]byte, keyLen))
+	if err != nil {
+		return n
+	}
+	return n + aead.Overhead()
+}
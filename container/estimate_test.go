@@ -0,0 +1,36 @@
+package container
+
+import "testing"
+
+// TestEstimateSize checks if EstimateSize matches the size of real containers across lengths, ciphers, encodings and layouts.
+func TestEstimateSize(t *testing.T) {
+	password := "password123"
+	iters := WithIterations(minIterations)
+	configs := map[string][]Option{
+		"default":  {iters},
+		"cbc":      {iters, WithCipher(CipherAES256CBC)},
+		"gcm":      {iters, WithCipher(CipherAES256GCM), WithEncoding(EncodingBase64)},
+		"xchacha":  {iters, WithCipher(CipherXChaCha20Poly1305)},
+		"argon2":   {WithArgon2Params(testArgon2Params)},
+		"envelope": {iters, WithEnvelope()},
+		"indent":   {iters, WithIndent("", "  "), WithAnnotations(map[string]string{"owner": "ops"})},
+		"sha512":   {iters, WithMACHash(MACHashSHA512)},
+		"external": {iters, WithExternalCiphertext(new([]byte))},
+	}
+	for name, opts := range configs {
+		for _, n := range []int{0, 1, 15, 16, 100, 4096} {
+			// A difference of a byte or two is allowed for fields whose
+			// width depends on the values, such as timestamps.
+			const tolerance = 2
+			got := EstimateSize(n, opts...)
+			want := len(mustCreate(t, string(make([]byte, n)), password, opts...))
+			if got < want-tolerance || got > want+tolerance {
+				t.Errorf("%s/%d: expected an estimate near %d, got %d", name, n, want, got)
+			}
+		}
+	}
+
+	if got := EstimateSize(10, WithSaltLen(1)); got != 0 {
+		t.Errorf("Expected 0 for invalid options, got %d", got)
+	}
+}
@@ -0,0 +1,56 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ExtractCiphertext returns the decoded ciphertext held inline in
+// containerJSON, for split-storage designs that keep the bytes apart from the
+// header. It does not authenticate anything. Containers created with
+// WithExternalCiphertext hold no ciphertext and give ErrMalformedContainer.
+func ExtractCiphertext(containerJSON string) ([]byte, error) {
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if container.ContainerMeta.External {
+		return nil, fmt.Errorf("%w: ciphertext is stored externally", ErrMalformedContainer)
+	}
+	return decodeField(container.ContainedData.EncryptedData, container.ContainerMeta.Encoding)
+}
+
+// DecryptContainerExternal decrypts a container created with
+// WithExternalCiphertext, given the ciphertext stored apart from it. The
+// ciphertext is authenticated along with the header, so the wrong bytes give
+// ErrHMACMismatch. Like Decrypt it accepts any cipher.
+func DecryptContainerExternal(containerJSON string, ciphertext []byte, password string, opts ...DecryptOption) (string, error) {
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if container.ContainedData.EncryptedData != "" {
+		return "", fmt.Errorf("%w: container already holds its ciphertext", ErrMalformedContainer)
+	}
+	container.ContainedData.EncryptedData = encodeField(ciphertext, container.ContainerMeta.Encoding)
+	p := newDecryptParams("", opts)
+	p.external = true
+	plaintext, err := openContainer(&container, password, p)
+	if err != nil {
+		return "", err
+	}
+	defer zeroize(plaintext)
+	return p.plaintextString(plaintext)
+}
+
+// takeCiphertext moves the ciphertext of a sealed container into *dst for
+// WithExternalCiphertext, leaving EncryptedData empty.
+func takeCiphertext(container *Container, dst *[]byte) error {
+	ciphertext, err := decodeField(container.ContainedData.EncryptedData, container.ContainerMeta.Encoding)
+	if err != nil {
+		return err
+	}
+	*dst = ciphertext
+	container.ContainedData.EncryptedData = ""
+	return nil
+}
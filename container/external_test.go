@@ -0,0 +1,59 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestWithExternalCiphertext checks if a container split from its ciphertext round-trips with DecryptContainerExternal and matches ExtractCiphertext of an inline container.
+func TestWithExternalCiphertext(t *testing.T) {
+	password := "password123"
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		var ciphertext []byte
+		containerJSON := mustCreate(t, "hello external", password, WithCipher(cipherName), WithExternalCiphertext(&ciphertext), WithIterations(minIterations))
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		if !container.ContainerMeta.External || container.ContainedData.EncryptedData != "" {
+			t.Errorf("%s: expected an external container without EncryptedData, got %+v", cipherName, container)
+		}
+		if len(ciphertext) == 0 {
+			t.Fatalf("%s: expected the ciphertext to be returned", cipherName)
+		}
+
+		decryptedText, err := DecryptContainerExternal(containerJSON, ciphertext, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting external container: %v", cipherName, err)
+		}
+		if decryptedText != "hello external" {
+			t.Errorf("%s: expected 'hello external', got '%s'", cipherName, decryptedText)
+		}
+
+		if _, err := Decrypt(containerJSON, password); !errors.Is(err, ErrMalformedContainer) {
+			t.Errorf("%s: expected ErrMalformedContainer without the ciphertext, got: %v", cipherName, err)
+		}
+		if _, err := ExtractCiphertext(containerJSON); !errors.Is(err, ErrMalformedContainer) {
+			t.Errorf("%s: expected ErrMalformedContainer extracting external ciphertext, got: %v", cipherName, err)
+		}
+		tampered := bytes.Clone(ciphertext)
+		tampered[0] ^= 1
+		if _, err := DecryptContainerExternal(containerJSON, tampered, password); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("%s: expected ErrHMACMismatch for tampered ciphertext, got: %v", cipherName, err)
+		}
+	}
+
+	inline := mustCreate(t, "hello inline", password, WithEncoding(EncodingBase64), WithIterations(minIterations))
+	ciphertext, err := ExtractCiphertext(inline)
+	if err != nil {
+		t.Fatalf("Error extracting ciphertext: %v", err)
+	}
+	if len(ciphertext) != len("hello inline") {
+		t.Errorf("Expected %d ciphertext bytes, got %d", len("hello inline"), len(ciphertext))
+	}
+	if _, err := DecryptContainerExternal(inline, ciphertext, password); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for an inline container, got: %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package container
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// EncryptFile encrypts the file at srcPath into dstPath using EncryptStream.
+// The result is written to a temporary file next to dstPath and renamed into
+// place only on success, so dstPath never holds a partial container. The
+// destination is created with 0600 permissions.
+func EncryptFile(srcPath, dstPath, password string, opts ...Option) error {
+	return transformFile(srcPath, dstPath, func(dst io.Writer, src io.Reader) error {
+		return EncryptStream(dst, src, password, opts...)
+	})
+}
+
+// DecryptFile decrypts a file produced by EncryptFile into dstPath, with the
+// same atomic-write guarantees. It accepts the same options as DecryptStream.
+func DecryptFile(srcPath, dstPath, password string, opts ...DecryptOption) error {
+	return transformFile(srcPath, dstPath, func(dst io.Writer, src io.Reader) error {
+		return DecryptStream(dst, src, password, opts...)
+	})
+}
+
+func transformFile(srcPath, dstPath string, transform func(dst io.Writer, src io.Reader) error) (err error) {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), "."+filepath.Base(dstPath)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	if err = tmp.Chmod(0600); err != nil {
+		return err
+	}
+	if err = transform(tmp, src); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), dstPath)
+}
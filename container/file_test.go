@@ -0,0 +1,88 @@
+package container
+
+import (
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func fileDigest(t *testing.T, path string) [sha256.Size]byte {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Error reading %s: %v", path, err)
+	}
+	return sha256.Sum256(data)
+}
+
+// TestEncryptDecryptFile checks if a file round-trips through EncryptFile and DecryptFile with 0600 permissions.
+func TestEncryptDecryptFile(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "plain.txt")
+	encPath := filepath.Join(dir, "plain.txt.container")
+	outPath := filepath.Join(dir, "plain.out")
+	password := "password123"
+
+	data := make([]byte, 2*streamChunkSize+123)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	if err := os.WriteFile(srcPath, data, 0644); err != nil {
+		t.Fatalf("Error writing source file: %v", err)
+	}
+
+	if err := EncryptFile(srcPath, encPath, password, WithIterations(minIterations)); err != nil {
+		t.Fatalf("Error encrypting file: %v", err)
+	}
+	if err := DecryptFile(encPath, outPath, password); err != nil {
+		t.Fatalf("Error decrypting file: %v", err)
+	}
+
+	if fileDigest(t, srcPath) != fileDigest(t, outPath) {
+		t.Errorf("Decrypted file does not match the original")
+	}
+
+	if runtime.GOOS != "windows" {
+		for _, path := range []string{encPath, outPath} {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("Error stating %s: %v", path, err)
+			}
+			if perm := info.Mode().Perm(); perm != 0600 {
+				t.Errorf("Expected %s to have permissions 0600, got %o", path, perm)
+			}
+		}
+	}
+}
+
+// TestDecryptFileFailureLeavesNoOutput checks if a failed decryption removes its temporary file and creates no destination.
+func TestDecryptFileFailureLeavesNoOutput(t *testing.T) {
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "plain.txt")
+	encPath := filepath.Join(dir, "plain.txt.container")
+	outPath := filepath.Join(dir, "plain.out")
+
+	if err := os.WriteFile(srcPath, []byte("hello world"), 0644); err != nil {
+		t.Fatalf("Error writing source file: %v", err)
+	}
+	if err := EncryptFile(srcPath, encPath, "password123", WithIterations(minIterations)); err != nil {
+		t.Fatalf("Error encrypting file: %v", err)
+	}
+
+	if err := DecryptFile(encPath, outPath, "wrongpassword"); err == nil {
+		t.Fatalf("DecryptFile did not return an error with the wrong password")
+	}
+	if _, err := os.Stat(outPath); !os.IsNotExist(err) {
+		t.Errorf("Expected no destination file after a failure, got: %v", err)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Error reading directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the source and encrypted files to remain, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,52 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Header is the unencrypted part of a container: everything needed to decide
+// how to open it, without the ciphertext or MAC.
+type Header struct {
+	ContainerMeta  Meta         `json:"ContainerMeta"`
+	DeriveInfo     Derive       `json:"DeriveInfo"`
+	EncryptionInfo Encryption   `json:"EncryptionInfo"`
+	WrappedKeys    []WrappedKey `json:"WrappedKeys,omitempty"`
+}
+
+// ParseHeader returns the header of containerJSON without deriving a key or
+// decrypting anything, so it needs no password and is cheap.
+func ParseHeader(containerJSON string) (Header, error) {
+	var header Header
+	if err := json.Unmarshal([]byte(containerJSON), &header); err != nil {
+		return Header{}, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if err := header.checkRequired(); err != nil {
+		return Header{}, err
+	}
+	return header, nil
+}
+
+func (h *Header) checkRequired() error {
+	if h.ContainerMeta.Version == versionEnvelope {
+		// The password KDF parameters live in the wrapped key.
+		switch {
+		case len(h.WrappedKeys) == 0:
+			return fmt.Errorf("%w: missing WrappedKeys", ErrMalformedContainer)
+		case h.EncryptionInfo.IV == "":
+			return fmt.Errorf("%w: missing EncryptionInfo.IV", ErrMalformedContainer)
+		}
+		return nil
+	}
+	switch {
+	case h.ContainerMeta.Version == "":
+		return fmt.Errorf("%w: missing ContainerMeta.Version", ErrMalformedContainer)
+	case h.DeriveInfo.Salt == "" && h.DeriveInfo.KDF != KDFNone:
+		return fmt.Errorf("%w: missing DeriveInfo.Salt", ErrMalformedContainer)
+	case h.DeriveInfo.isPBKDF2() && h.DeriveInfo.Iters == 0:
+		return fmt.Errorf("%w: missing DeriveInfo.Iters", ErrMalformedContainer)
+	case h.EncryptionInfo.IV == "":
+		return fmt.Errorf("%w: missing EncryptionInfo.IV", ErrMalformedContainer)
+	}
+	return nil
+}
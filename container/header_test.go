@@ -0,0 +1,280 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseHeader checks if ParseHeader returns the stored iteration count and version without a password.
+func TestParseHeader(t *testing.T) {
+	containerJSON, err := CreateContainer("hello world", "password123", WithIterations(123456))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if header.DeriveInfo.Iters != 123456 {
+		t.Errorf("Expected 123456 iterations, got %d", header.DeriveInfo.Iters)
+	}
+	if header.ContainerMeta.Version != "v1.1" {
+		t.Errorf("Expected version v1.1, got %q", header.ContainerMeta.Version)
+	}
+	if header.DeriveInfo.kdfName() != KDFPBKDF2 {
+		t.Errorf("Expected KDF %q, got %q", KDFPBKDF2, header.DeriveInfo.kdfName())
+	}
+}
+
+// TestParseHeaderMalformed checks if invalid JSON and missing required fields return ErrMalformedContainer.
+func TestParseHeaderMalformed(t *testing.T) {
+	containerJSON := mustCreate(t, "hello world", "password123")
+
+	remove := func(path ...string) string {
+		var m map[string]map[string]any
+		if err := json.Unmarshal([]byte(containerJSON), &m); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		if len(path) == 1 {
+			delete(m, path[0])
+		} else {
+			delete(m[path[0]], path[1])
+		}
+		b, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("Failed to marshal container: %v", err)
+		}
+		return string(b)
+	}
+
+	tests := map[string]string{
+		"invalid JSON":   "{not json",
+		"wrong type":     `{"ContainerMeta":"v1.1"}`,
+		"no meta":        remove("ContainerMeta"),
+		"no derive info": remove("DeriveInfo"),
+		"no salt":        remove("DeriveInfo", "Salt"),
+		"no iterations":  remove("DeriveInfo", "Iters"),
+		"no IV":          remove("EncryptionInfo", "IV"),
+	}
+	for name, input := range tests {
+		if _, err := ParseHeader(input); !errors.Is(err, ErrMalformedContainer) {
+			t.Errorf("%s: expected ErrMalformedContainer, got: %v", name, err)
+		}
+	}
+}
+
+// TestCreatedAt checks if the creation time is recorded, exposed by ParseHeader and authenticated.
+func TestCreatedAt(t *testing.T) {
+	before := time.Now().UTC()
+	containerJSON := mustCreate(t, "hello world", "password123", WithIterations(minIterations))
+
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	createdAt, err := time.Parse(time.RFC3339, header.ContainerMeta.CreatedAt)
+	if err != nil {
+		t.Fatalf("Error parsing CreatedAt %q: %v", header.ContainerMeta.CreatedAt, err)
+	}
+	if d := createdAt.Sub(before); d < -time.Second || d > time.Second {
+		t.Errorf("Expected CreatedAt within a second of %v, got %v", before, createdAt)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.CreatedAt = createdAt.Add(-time.Hour).Format(time.RFC3339)
+	tampered, err := json.Marshal(container)
+	if err != nil {
+		t.Fatalf("Failed to marshal tampered container: %v", err)
+	}
+	if _, err := DecryptContainer(string(tampered), "password123"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch, got: %v", err)
+	}
+}
+
+// TestAnnotations checks if annotations are readable without a password and authenticated.
+func TestAnnotations(t *testing.T) {
+	annotations := map[string]string{"owner": "alice", "purpose": "backup"}
+	containerJSON := mustCreate(t, "hello world", "password123", WithIterations(minIterations), WithAnnotations(annotations))
+
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if len(header.ContainerMeta.Annotations) != len(annotations) {
+		t.Errorf("Expected %d annotations, got %v", len(annotations), header.ContainerMeta.Annotations)
+	}
+	for k, v := range annotations {
+		if header.ContainerMeta.Annotations[k] != v {
+			t.Errorf("Expected annotation %q to be %q, got %q", k, v, header.ContainerMeta.Annotations[k])
+		}
+	}
+	if _, err := DecryptContainer(containerJSON, "password123"); err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+
+	for name, tamper := range map[string]func(m map[string]string){
+		"modify": func(m map[string]string) { m["owner"] = "mallory" },
+		"add":    func(m map[string]string) { m["extra"] = "" },
+		"remove": func(m map[string]string) { delete(m, "purpose") },
+		"rename": func(m map[string]string) { m["Owner"] = m["owner"]; delete(m, "owner") },
+	} {
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		tamper(container.ContainerMeta.Annotations)
+		tampered, err := json.Marshal(container)
+		if err != nil {
+			t.Fatalf("Failed to marshal tampered container: %v", err)
+		}
+		if _, err := DecryptContainer(string(tampered), "password123"); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("%s: expected ErrHMACMismatch, got: %v", name, err)
+		}
+	}
+}
+
+// TestKeyID checks if the key ID round-trips through ParseHeader and the binary format and is covered by the MAC.
+func TestKeyID(t *testing.T) {
+	password := "password123"
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		containerJSON := mustCreate(t, "hello key id", password, WithCipher(cipherName), WithKeyID("backup-2026"), WithIterations(minIterations))
+
+		header, err := ParseHeader(containerJSON)
+		if err != nil {
+			t.Fatalf("%s: error parsing header: %v", cipherName, err)
+		}
+		if header.ContainerMeta.KeyID != "backup-2026" {
+			t.Errorf("%s: expected key ID 'backup-2026', got %q", cipherName, header.ContainerMeta.KeyID)
+		}
+
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		b, err := container.MarshalBinary()
+		if err != nil {
+			t.Fatalf("%s: error marshaling binary container: %v", cipherName, err)
+		}
+		if _, err := DecryptContainerBinary(b, password); err != nil {
+			t.Errorf("%s: error decrypting binary container: %v", cipherName, err)
+		}
+
+		for _, keyID := range []string{"backup-2027", ""} {
+			container.ContainerMeta.KeyID = keyID
+			if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("%s: expected ErrHMACMismatch for key ID %q, got: %v", cipherName, keyID, err)
+			}
+		}
+	}
+
+	if _, err := CreateContainer("hello", password, WithKeyID("id-"+password)); err == nil {
+		t.Errorf("Expected an error for a key ID containing the password")
+	}
+	if _, err := CreateContainer("hello", password, WithKeyID(strings.Repeat("k", maxKeyIDLen+1))); err == nil {
+		t.Errorf("Expected an error for a key ID longer than %d bytes", maxKeyIDLen)
+	}
+}
+
+// TestStrictParsing checks if unknown fields are rejected under WithStrictParsing but ignored by default.
+func TestStrictParsing(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello world", password, WithIterations(minIterations))
+
+	var m map[string]any
+	if err := json.Unmarshal([]byte(containerJSON), &m); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	m["backdoor"] = "x"
+	extended, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("Failed to marshal container: %v", err)
+	}
+
+	if _, err := DecryptContainer(string(extended), password); err != nil {
+		t.Errorf("Expected the lenient default to ignore unknown fields, got: %v", err)
+	}
+	_, err = DecryptContainer(string(extended), password, WithStrictParsing())
+	if !errors.Is(err, ErrMalformedContainer) || !strings.Contains(err.Error(), "backdoor") {
+		t.Errorf("Expected ErrMalformedContainer naming the field, got: %v", err)
+	}
+	if _, err := Decrypt(containerJSON+"{}", password, WithStrictParsing()); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for trailing data, got: %v", err)
+	}
+	if _, err := Decrypt(containerJSON, password, WithStrictParsing()); err != nil {
+		t.Errorf("Expected a well-formed container to pass strict parsing, got: %v", err)
+	}
+}
+
+// TestContainerStringRedacts checks if String omits the secret fields that Dump prints.
+func TestContainerStringRedacts(t *testing.T) {
+	containerJSON := mustCreate(t, "hello redaction", "password123", WithIterations(minIterations))
+	var c Container
+	if err := json.Unmarshal([]byte(containerJSON), &c); err != nil {
+		t.Fatalf("Error unmarshalling container: %v", err)
+	}
+	secrets := []string{c.DeriveInfo.Salt, c.EncryptionInfo.IV, c.ContainedData.EncryptedData, c.ContainedData.HMAC}
+
+	for _, s := range []string{c.String(), fmt.Sprintf("%v", c), fmt.Sprintf("%+v", &c), fmt.Sprintf("%#v", c)} {
+		if !strings.Contains(s, c.ContainerMeta.Version) || !strings.Contains(s, redacted) {
+			t.Errorf("Expected version and redaction marker in %q", s)
+		}
+		for _, secret := range secrets {
+			if strings.Contains(s, secret) {
+				t.Errorf("Redacted string %q leaks %q", s, secret)
+			}
+		}
+	}
+
+	dump := c.Dump()
+	for _, secret := range secrets {
+		if !strings.Contains(dump, secret) {
+			t.Errorf("Expected Dump to contain %q", secret)
+		}
+	}
+}
+
+// TestPlaintextLen checks if WithPlaintextLen records the uncompressed length, editing it causes ErrHMACMismatch and it is unset by default.
+func TestPlaintextLen(t *testing.T) {
+	password := "password123"
+	plaintext := strings.Repeat("hello length ", 40)
+
+	header, err := ParseHeader(mustCreate(t, plaintext, password, WithIterations(minIterations)))
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if header.ContainerMeta.PlaintextLen != 0 {
+		t.Errorf("Expected no PlaintextLen without WithPlaintextLen, got %d", header.ContainerMeta.PlaintextLen)
+	}
+	if _, err := CreateContainer(plaintext, password, WithPlaintextLen(), WithPadding(64)); err == nil {
+		t.Errorf("Expected WithPlaintextLen and WithPadding to conflict")
+	}
+
+	for _, opts := range [][]Option{
+		{WithCipher(CipherAES256CTR)},
+		{WithCipher(CipherAES256GCM), WithCompression(CompressionGzip)},
+	} {
+		containerJSON := mustCreate(t, plaintext, password, append(opts, WithPlaintextLen(), WithIterations(minIterations))...)
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		if container.ContainerMeta.PlaintextLen != len(plaintext) {
+			t.Errorf("Expected PlaintextLen %d, got %d", len(plaintext), container.ContainerMeta.PlaintextLen)
+		}
+
+		for _, n := range []int{0, len(plaintext) - 1, len(plaintext) + 1} {
+			container.ContainerMeta.PlaintextLen = n
+			if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("Expected ErrHMACMismatch with PlaintextLen %d, got: %v", n, err)
+			}
+		}
+	}
+}
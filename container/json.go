@@ -0,0 +1,46 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ContentTypeJSON is the Meta.ContentType of containers holding JSON.
+const ContentTypeJSON = "application/json"
+
+// CreateContainerJSON marshals v to JSON and encrypts it like CreateContainer,
+// recording ContentTypeJSON in Meta.ContentType.
+func CreateContainerJSON(v any, password string, opts ...Option) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	defer zeroize(b)
+	containerJSON, err := CreateContainerBytes(b, password, append(opts, func(o *options) { o.contentType = ContentTypeJSON })...)
+	if err != nil {
+		return "", err
+	}
+	return string(containerJSON), nil
+}
+
+// DecryptContainerJSON decrypts a container like Decrypt and unmarshals its
+// plaintext into v. Containers with a Meta.ContentType other than
+// ContentTypeJSON are rejected; those without one are accepted, so JSON
+// encrypted with CreateContainer can be read too.
+func DecryptContainerJSON(containerJSON, password string, v any, opts ...DecryptOption) error {
+	p := newDecryptParams("", opts)
+	container, err := unmarshalContainer([]byte(containerJSON), p.strict)
+	if err != nil {
+		return err
+	}
+	plaintext, err := openContainer(container, password, p)
+	if err != nil {
+		return err
+	}
+	defer zeroize(plaintext)
+	// The content type is only trusted once the header has been authenticated.
+	if ct := container.ContainerMeta.ContentType; ct != "" && ct != ContentTypeJSON {
+		return fmt.Errorf("content type %q is not %s", ct, ContentTypeJSON)
+	}
+	return json.Unmarshal(plaintext, v)
+}
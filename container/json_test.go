@@ -0,0 +1,98 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type jsonTestRecord struct {
+	Name    string
+	Tags    []string
+	Limits  map[string]int
+	Owner   *jsonTestOwner
+	Members []jsonTestOwner
+}
+
+type jsonTestOwner struct {
+	Email string
+	Roles map[string][]string
+}
+
+// TestContainerJSON checks if a nested struct with slices and maps round-trips and the content type is recorded.
+func TestContainerJSON(t *testing.T) {
+	password := "password123"
+	in := jsonTestRecord{
+		Name:   "service",
+		Tags:   []string{"a", "b"},
+		Limits: map[string]int{"cpu": 2, "memory": 512},
+		Owner:  &jsonTestOwner{Email: "alice@example.com", Roles: map[string][]string{"admin": {"read", "write"}}},
+		Members: []jsonTestOwner{
+			{Email: "bob@example.com"},
+			{Email: "carol@example.com", Roles: map[string][]string{"viewer": {"read"}}},
+		},
+	}
+
+	containerJSON, err := CreateContainerJSON(in, password, WithCipher(CipherAES256GCM), WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if header.ContainerMeta.ContentType != ContentTypeJSON {
+		t.Errorf("Expected content type %q, got %q", ContentTypeJSON, header.ContainerMeta.ContentType)
+	}
+
+	var out jsonTestRecord
+	if err := DecryptContainerJSON(containerJSON, password, &out); err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("Expected %+v, got %+v", in, out)
+	}
+
+	// The content type survives the binary format.
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	b, err := container.MarshalBinary()
+	if err != nil {
+		t.Fatalf("Error marshaling binary container: %v", err)
+	}
+	if _, err := DecryptContainerBinary(b, password); err != nil {
+		t.Errorf("Error decrypting binary container: %v", err)
+	}
+
+	// The content type is authenticated.
+	container.ContainerMeta.ContentType = "text/plain"
+	if err := DecryptContainerJSON(mustMarshal(t, &container), password, &out); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a changed content type, got: %v", err)
+	}
+}
+
+// TestDecryptContainerJSONContentType checks if other content types are rejected and a missing one is accepted.
+func TestDecryptContainerJSONContentType(t *testing.T) {
+	password := "password123"
+	var out map[string]string
+
+	textJSON := mustCreate(t, `{"a":"b"}`, password, WithIterations(minIterations), func(o *options) { o.contentType = "text/plain" })
+	if err := DecryptContainerJSON(textJSON, password, &out); err == nil {
+		t.Errorf("Expected an error for content type text/plain")
+	}
+
+	plainJSON := mustCreate(t, `{"a":"b"}`, password, WithIterations(minIterations))
+	if err := DecryptContainerJSON(plainJSON, password, &out); err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if out["a"] != "b" {
+		t.Errorf("Expected a=b, got %v", out)
+	}
+
+	if _, err := CreateContainerJSON(make(chan int), password); err == nil {
+		t.Errorf("Expected an error for a value that cannot be marshaled")
+	}
+}
@@ -0,0 +1,339 @@
+package container
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// KDF names recorded in Derive.KDF. Containers without a KDF name predate
+// the field and use PBKDF2-SHA256.
+const (
+	KDFPBKDF2 = "pbkdf2-sha256"
+	// KDFPBKDF2SHA512 is PBKDF2 with HMAC-SHA512, as selected by
+	// WithPRF(PRFSHA512).
+	KDFPBKDF2SHA512 = "pbkdf2-sha512"
+	KDFArgon2id     = "argon2id"
+	KDFScrypt       = "scrypt"
+	// KDFNone marks containers created with CreateContainerWithKey, whose
+	// key is supplied directly instead of derived from a password.
+	KDFNone = "none"
+)
+
+// PRFs accepted by WithPRF.
+const (
+	PRFSHA256 = "sha256"
+	PRFSHA512 = "sha512"
+)
+
+// pbkdf2Hashes maps the name of each PBKDF2 variant to its PRF hash.
+var pbkdf2Hashes = map[string]func() hash.Hash{
+	KDFPBKDF2:       sha256.New,
+	KDFPBKDF2SHA512: sha512.New,
+}
+
+const (
+	// minIterations is the lowest PBKDF2-SHA256 iteration count we produce,
+	// following the OWASP recommendation.
+	minIterations = 100000
+	// defaultCalibrationTarget is how long a default PBKDF2 derivation
+	// should take on the machine creating the container.
+	defaultCalibrationTarget = 100 * time.Millisecond
+	calibrationProbeIters    = 20000
+	// minArgon2Memory and minArgon2Time are the weakest Argon2id
+	// parameters accepted without WithAllowWeakParams, following the OWASP
+	// recommendation of 19MiB and two passes.
+	minArgon2Memory = 19 * 1024
+	minArgon2Time   = 2
+	// minScryptN is the lowest scrypt cost accepted without
+	// WithAllowWeakParams.
+	minScryptN = 1 << 14
+	// pbkdf2CheckInterval is how many PBKDF2 iterations run between checks
+	// for cancellation.
+	pbkdf2CheckInterval = 10000
+)
+
+// MaxIterations is the highest PBKDF2 iteration count accepted, both when
+// creating and when decrypting a container. It stops a crafted container
+// from tying up the CPU for hours, and may be raised by programs that need
+// more.
+var MaxIterations = 10000000
+
+// MaxArgon2Memory, MaxArgon2Time, MaxScryptMemory and MaxScryptP bound the
+// Argon2id and scrypt costs accepted, like MaxIterations does for PBKDF2, so
+// a crafted container cannot make decryption allocate gigabytes or run for
+// hours. MaxArgon2Memory is in KiB; MaxScryptMemory is in bytes and bounds
+// the 128·N·r bytes scrypt allocates.
+var (
+	MaxArgon2Memory uint32 = 1 << 20
+	MaxArgon2Time   uint32 = 64
+	MaxScryptMemory        = 1 << 30
+	MaxScryptP             = 64
+)
+
+// fixedIterations, when non-zero, replaces the calibrated PBKDF2 iteration
+// count used when none is given. Tests set it to keep key derivation fast and
+// reproducible.
+var fixedIterations int
+
+// defaultIterations returns the PBKDF2 iteration count used when none is
+// given with WithIterations.
+func defaultIterations() int {
+	if fixedIterations != 0 {
+		return fixedIterations
+	}
+	return CalibrateIterations(defaultCalibrationTarget)
+}
+
+var pbkdf2Cost struct {
+	once    sync.Once
+	perIter float64 // nanoseconds per iteration
+}
+
+// CalibrateIterations returns the PBKDF2-SHA256 iteration count that makes a
+// key derivation take roughly target on this machine, but never less than
+// 100000 or more than MaxIterations. The per-iteration cost is measured once
+// and reused, so the result grows monotonically with target.
+func CalibrateIterations(target time.Duration) int {
+	pbkdf2Cost.once.Do(func() {
+		salt := make([]byte, saltLen)
+		start := time.Now()
+		pbkdf2Key(context.Background(), sha256.New, []byte("calibration"), salt, calibrationProbeIters, keyLen)
+		pbkdf2Cost.perIter = float64(time.Since(start).Nanoseconds()) / calibrationProbeIters
+		if pbkdf2Cost.perIter <= 0 {
+			pbkdf2Cost.perIter = 1
+		}
+	})
+
+	iters := float64(target.Nanoseconds()) / pbkdf2Cost.perIter
+	if iters < minIterations {
+		return minIterations
+	}
+	if iters > float64(MaxIterations) {
+		return MaxIterations
+	}
+	return int(iters)
+}
+
+// Argon2Params are the Argon2id cost parameters. Memory is in KiB.
+type Argon2Params struct {
+	Memory  uint32
+	Time    uint32
+	Threads uint32
+}
+
+func (p Argon2Params) validate() error {
+	if p.Memory == 0 || p.Time == 0 {
+		return errors.New("argon2: memory and time must be positive")
+	}
+	if p.Threads == 0 || p.Threads > 255 {
+		return errors.New("argon2: threads must be between 1 and 255")
+	}
+	return nil
+}
+
+func validateScrypt(N, r, p int) error {
+	if N <= 1 || N&(N-1) != 0 {
+		return fmt.Errorf("scrypt: N must be a power of two greater than 1, got %d", N)
+	}
+	if r <= 0 || p <= 0 {
+		return fmt.Errorf("scrypt: r and p must be positive, got r=%d p=%d", r, p)
+	}
+	return nil
+}
+
+// checkArgon2Cost rejects Argon2id parameters above MaxArgon2Memory or
+// MaxArgon2Time.
+func checkArgon2Cost(p Argon2Params) error {
+	if p.Memory > MaxArgon2Memory {
+		return fmt.Errorf("%w: argon2id memory %d KiB exceeds %d", ErrKDFParamsTooHigh, p.Memory, MaxArgon2Memory)
+	}
+	if p.Time > MaxArgon2Time {
+		return fmt.Errorf("%w: argon2id time %d exceeds %d", ErrKDFParamsTooHigh, p.Time, MaxArgon2Time)
+	}
+	return nil
+}
+
+// checkScryptCost rejects scrypt parameters that need more than
+// MaxScryptMemory or whose p exceeds MaxScryptP. N and r must already be
+// positive.
+func checkScryptCost(N, r, p int) error {
+	if N > MaxScryptMemory/128/r {
+		return fmt.Errorf("%w: scrypt N=%d r=%d needs more than %d bytes", ErrKDFParamsTooHigh, N, r, MaxScryptMemory)
+	}
+	if p > MaxScryptP {
+		return fmt.Errorf("%w: scrypt p=%d exceeds %d", ErrKDFParamsTooHigh, p, MaxScryptP)
+	}
+	return nil
+}
+
+// checkIterations rejects PBKDF2 iteration counts that are not positive or
+// exceed MaxIterations.
+func checkIterations(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("%w: iteration count %d is not positive", ErrMalformedContainer, n)
+	}
+	if n > MaxIterations {
+		return fmt.Errorf("%w: %d exceeds %d", ErrIterationsTooHigh, n, MaxIterations)
+	}
+	return nil
+}
+
+// kdfName returns the KDF d describes, treating an empty name as the v1.0
+// default of PBKDF2-SHA256.
+func (d *Derive) kdfName() string {
+	if d.KDF == "" {
+		return KDFPBKDF2
+	}
+	return d.KDF
+}
+
+// isPBKDF2 reports whether d describes either PBKDF2 variant.
+func (d *Derive) isPBKDF2() bool {
+	_, ok := pbkdf2Hashes[d.kdfName()]
+	return ok
+}
+
+func knownKDF(name string) bool {
+	_, pbkdf2 := pbkdf2Hashes[name]
+	return pbkdf2 || name == KDFArgon2id || name == KDFScrypt
+}
+
+// DeriveKey returns the 32-byte master key that password and the KDF
+// parameters in d derive, as stored in Container.DeriveInfo with its salt
+// encoded with enc, the container's Meta.Encoding. Containers split this key
+// into their encryption and MAC keys, so it lets callers verify a password
+// against a stored key hash or reuse the key elsewhere. d is checked like a
+// container being decrypted: iteration counts and cost parameters out of
+// bounds are rejected rather than adjusted.
+func DeriveKey(password string, d Derive, enc Encoding) ([]byte, error) {
+	if err := d.validate(); err != nil {
+		return nil, err
+	}
+	salt, err := decodeField(d.Salt, enc)
+	if err != nil {
+		return nil, fmt.Errorf("decoding Salt: %w", err)
+	}
+	if len(salt) == 0 {
+		return nil, fmt.Errorf("%w: missing Salt", ErrMalformedContainer)
+	}
+	pw := []byte(password)
+	defer zeroize(pw)
+	return deriveKey(context.Background(), pw, salt, &d, keyLen)
+}
+
+// deriveKey runs the key derivation described by d over password and salt,
+// producing a key of length bytes. It gives up with ctx.Err() once ctx is
+// done.
+func deriveKey(ctx context.Context, password, salt []byte, d *Derive, length int) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	switch name := d.kdfName(); name {
+	case KDFPBKDF2, KDFPBKDF2SHA512:
+		if err := checkIterations(d.Iters); err != nil {
+			return nil, err
+		}
+		return pbkdf2Key(ctx, pbkdf2Hashes[name], password, salt, d.Iters, length)
+	case KDFArgon2id:
+		p := Argon2Params{Memory: d.Memory, Time: d.Time, Threads: d.Threads}
+		if err := p.validate(); err != nil {
+			return nil, err
+		}
+		if err := checkArgon2Cost(p); err != nil {
+			return nil, err
+		}
+		return runKDF(ctx, password, func(pw []byte) ([]byte, error) {
+			return argon2.IDKey(pw, salt, p.Time, p.Memory, uint8(p.Threads), uint32(length)), nil
+		})
+	case KDFScrypt:
+		if err := validateScrypt(d.N, d.R, d.P); err != nil {
+			return nil, err
+		}
+		if err := checkScryptCost(d.N, d.R, d.P); err != nil {
+			return nil, err
+		}
+		return runKDF(ctx, password, func(pw []byte) ([]byte, error) {
+			return scrypt.Key(pw, salt, d.N, d.R, d.P, length)
+		})
+	case KDFNone:
+		return nil, fmt.Errorf("%w: the container needs a raw key, not a password", ErrKeyTypeMismatch)
+	}
+	return nil, fmt.Errorf("%w: KDF %q", ErrUnsupportedAlgorithm, d.KDF)
+}
+
+// pbkdf2Key is PBKDF2 with HMAC over h as in golang.org/x/crypto/pbkdf2,
+// except that it checks ctx every pbkdf2CheckInterval iterations and on
+// cancellation wipes its partial output and returns ctx.Err().
+func pbkdf2Key(ctx context.Context, h func() hash.Hash, password, salt []byte, iter, keyLen int) ([]byte, error) {
+	prf := hmac.New(h, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	defer zeroize(u)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		prf.Write(binary.BigEndian.AppendUint32(nil, uint32(block)))
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iter; n++ {
+			if n%pbkdf2CheckInterval == 0 {
+				if err := ctx.Err(); err != nil {
+					zeroize(dk)
+					return nil, err
+				}
+			}
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen], nil
+}
+
+// runKDF runs kdf on a copy of password. When ctx can be cancelled, kdf runs
+// in its own goroutine and runKDF returns ctx.Err() as soon as ctx is done;
+// the abandoned result is wiped once kdf finishes.
+func runKDF(ctx context.Context, password []byte, kdf func(pw []byte) ([]byte, error)) ([]byte, error) {
+	if ctx.Done() == nil {
+		return kdf(password)
+	}
+
+	type result struct {
+		key []byte
+		err error
+	}
+	pw := append([]byte(nil), password...)
+	done := make(chan result, 1)
+	go func() {
+		defer zeroize(pw)
+		key, err := kdf(pw)
+		done <- result{key, err}
+	}()
+
+	select {
+	case r := <-done:
+		return r.key, r.err
+	case <-ctx.Done():
+		go func() { zeroize((<-done).key) }()
+		return nil, ctx.Err()
+	}
+}
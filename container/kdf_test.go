@@ -0,0 +1,565 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+var testArgon2Params = Argon2Params{Memory: 19 * 1024, Time: 2, Threads: 1}
+
+// TestArgon2RoundTrip checks if an Argon2id container records its parameters and decrypts correctly.
+func TestArgon2RoundTrip(t *testing.T) {
+	plaintext := "hello argon2"
+	password := "password123"
+
+	containerJSON, err := CreateContainerArgon2(plaintext, password, testArgon2Params)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	d := container.DeriveInfo
+	if d.KDF != KDFArgon2id || d.Memory != testArgon2Params.Memory || d.Time != testArgon2Params.Time || d.Threads != testArgon2Params.Threads {
+		t.Errorf("Unexpected DeriveInfo: %+v", d)
+	}
+
+	decryptedText, err := DecryptContainer(containerJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected decrypted text to be '%s', got '%s'", plaintext, decryptedText)
+	}
+
+	// The Argon2 parameters are authenticated.
+	container.DeriveInfo.Memory++
+	tampered, err := json.Marshal(container)
+	if err != nil {
+		t.Fatalf("Failed to marshal tampered container: %v", err)
+	}
+	_, err = DecryptContainer(string(tampered), password)
+	if !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected HMAC mismatch error, got: %v", err)
+	}
+}
+
+// TestArgon2InvalidParams checks if zero or out-of-range Argon2 parameters are rejected.
+func TestArgon2InvalidParams(t *testing.T) {
+	for _, params := range []Argon2Params{
+		{Memory: 0, Time: 2, Threads: 1},
+		{Memory: 19 * 1024, Time: 0, Threads: 1},
+		{Memory: 19 * 1024, Time: 2, Threads: 0},
+		{Memory: 19 * 1024, Time: 2, Threads: 256},
+	} {
+		if _, err := CreateContainerArgon2("x", "password123", params); err == nil {
+			t.Errorf("Expected an error for params %+v", params)
+		}
+	}
+}
+
+// TestKDFDefaultsToPBKDF2 checks if new containers record PBKDF2 by name, an empty name resolves to it and the old "pbkdf2" name is unsupported.
+func TestKDFDefaultsToPBKDF2(t *testing.T) {
+	containerJSON, err := CreateContainer("hello world", "password123")
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.DeriveInfo.KDF != KDFPBKDF2 {
+		t.Errorf("Expected KDF %q, got %q", KDFPBKDF2, container.DeriveInfo.KDF)
+	}
+	if d := (Derive{}); d.kdfName() != KDFPBKDF2 {
+		t.Errorf("Expected an empty KDF to resolve to %q, got %q", KDFPBKDF2, d.kdfName())
+	}
+	container.DeriveInfo.KDF = "pbkdf2"
+	if _, err := DecryptContainer(mustMarshal(t, &container), "password123"); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm for KDF \"pbkdf2\", got: %v", err)
+	}
+}
+
+// TestScryptRoundTrip checks if a scrypt container records N, r and p and decrypts correctly.
+func TestScryptRoundTrip(t *testing.T) {
+	plaintext := "hello scrypt"
+	password := "password123"
+
+	containerJSON, err := CreateContainerScrypt(plaintext, password, 16384, 8, 1)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	d := container.DeriveInfo
+	if d.KDF != KDFScrypt || d.N != 16384 || d.R != 8 || d.P != 1 {
+		t.Errorf("Unexpected DeriveInfo: %+v", d)
+	}
+
+	decryptedText, err := DecryptContainer(containerJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected decrypted text to be '%s', got '%s'", plaintext, decryptedText)
+	}
+}
+
+// TestScryptRejectsInvalidN checks if an N that is not a power of two is rejected.
+func TestScryptRejectsInvalidN(t *testing.T) {
+	for _, N := range []int{12345, 1, 0, -16} {
+		if _, err := CreateContainerScrypt("x", "password123", N, 8, 1); err == nil {
+			t.Errorf("Expected an error for N=%d", N)
+		}
+	}
+}
+
+// TestCalibrateIterations checks if the calibrated count respects the minimum and grows with the target duration.
+func TestCalibrateIterations(t *testing.T) {
+	previous := 0
+	for _, target := range []time.Duration{time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, time.Second} {
+		iters := CalibrateIterations(target)
+		if iters < minIterations {
+			t.Errorf("Expected at least %d iterations for %v, got %d", minIterations, target, iters)
+		}
+		if iters < previous {
+			t.Errorf("Expected iterations to grow with target: %v gave %d after %d", target, iters, previous)
+		}
+		previous = iters
+	}
+}
+
+// TestCreateContainerWithIterations checks if an explicit iteration count, even one below the calibration floor, is stored and decrypts.
+func TestCreateContainerWithIterations(t *testing.T) {
+	containerJSON, err := CreateContainerWithIterations("hello world", "password123", 123456)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.DeriveInfo.Iters != 123456 {
+		t.Errorf("Expected 123456 iterations, got %d", container.DeriveInfo.Iters)
+	}
+
+	lowJSON, err := CreateContainerWithIterations("hello world", "password123", 1000)
+	if err != nil {
+		t.Fatalf("Error creating container with 1000 iterations: %v", err)
+	}
+	if err := json.Unmarshal([]byte(lowJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.DeriveInfo.Iters != 1000 {
+		t.Errorf("Expected 1000 iterations, got %d", container.DeriveInfo.Iters)
+	}
+	decryptedText, err := DecryptContainer(lowJSON, "password123")
+	if err != nil {
+		t.Fatalf("Error decrypting container with 1000 iterations: %v", err)
+	}
+	if decryptedText != "hello world" {
+		t.Errorf("Expected decrypted text to be 'hello world', got '%s'", decryptedText)
+	}
+
+	if _, err := CreateContainerWithIterations("hello world", "password123", -1); err == nil {
+		t.Errorf("Expected an error for a negative iteration count")
+	}
+}
+
+// TestMaxIterations checks if containers with huge or non-positive iteration counts are rejected without running PBKDF2.
+func TestMaxIterations(t *testing.T) {
+	containerJSON := mustCreate(t, "hello world", "password123", WithIterations(minIterations))
+
+	for _, tt := range []struct {
+		iters int
+		want  error
+	}{
+		{1 << 30, ErrIterationsTooHigh},
+		{0, ErrMalformedContainer},
+		{-1, ErrMalformedContainer},
+	} {
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		container.DeriveInfo.Iters = tt.iters
+		tampered, err := json.Marshal(container)
+		if err != nil {
+			t.Fatalf("Failed to marshal tampered container: %v", err)
+		}
+
+		start := time.Now()
+		_, err = DecryptContainer(string(tampered), "password123")
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Iters %d: expected %v, got: %v", tt.iters, tt.want, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Iters %d: expected a fast rejection, took %v", tt.iters, elapsed)
+		}
+	}
+
+	if _, err := CreateContainer("hello world", "password123", WithIterations(MaxIterations+1)); !errors.Is(err, ErrIterationsTooHigh) {
+		t.Errorf("Expected ErrIterationsTooHigh from CreateContainer, got: %v", err)
+	}
+	if iters := CalibrateIterations(24 * time.Hour); iters != MaxIterations {
+		t.Errorf("Expected calibration to be capped at %d, got %d", MaxIterations, iters)
+	}
+}
+
+// TestMaxKDFParams checks if Argon2id and scrypt costs above the caps are rejected in the container header, wrapped keys and stream header without running the KDF.
+func TestMaxKDFParams(t *testing.T) {
+	password := "password123"
+	argon2JSON := mustCreate(t, "hello world", password, WithArgon2Params(testArgon2Params))
+	scryptJSON, err := CreateContainerScrypt("hello world", password, 16384, 8, 1)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	envelopeJSON := mustCreate(t, "hello world", password, WithEnvelope(), WithIterations(minIterations))
+
+	tests := []struct {
+		name          string
+		containerJSON string
+		tamper        func(c *Container)
+	}{
+		{"argon2 memory", argon2JSON, func(c *Container) { c.DeriveInfo.Memory = 1<<32 - 1 }},
+		{"argon2 time", argon2JSON, func(c *Container) { c.DeriveInfo.Time = 1 << 30 }},
+		{"scrypt N", scryptJSON, func(c *Container) { c.DeriveInfo.N = 1 << 40 }},
+		{"scrypt p", scryptJSON, func(c *Container) { c.DeriveInfo.P = 1 << 20 }},
+		{"wrapped key", envelopeJSON, func(c *Container) {
+			*c.WrappedKeys[0].DeriveInfo = Derive{KDF: KDFArgon2id, Salt: c.WrappedKeys[0].DeriveInfo.Salt, Memory: 1<<32 - 1, Time: 2, Threads: 1}
+		}},
+	}
+	for _, tt := range tests {
+		var container Container
+		if err := json.Unmarshal([]byte(tt.containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		tt.tamper(&container)
+
+		start := time.Now()
+		_, err := Decrypt(mustMarshal(t, &container), password)
+		if !errors.Is(err, ErrKDFParamsTooHigh) {
+			t.Errorf("%s: expected ErrKDFParamsTooHigh, got: %v", tt.name, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("%s: expected a fast rejection, took %v", tt.name, elapsed)
+		}
+	}
+
+	header := streamHeader{
+		Version:    streamVersion,
+		Cipher:     CipherAES256GCM,
+		ChunkSize:  streamChunkSize,
+		Nonce:      strings.Repeat("00", 12),
+		DeriveInfo: Derive{KDF: KDFScrypt, Salt: strings.Repeat("11", saltLen), N: 1 << 30, R: 8, P: 1},
+	}
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("Error marshaling header: %v", err)
+	}
+	if _, err := newStreamState(&header, rawHeader, password); !errors.Is(err, ErrKDFParamsTooHigh) {
+		t.Errorf("Stream header: expected ErrKDFParamsTooHigh, got: %v", err)
+	}
+
+	if _, err := CreateContainer("hello world", password, WithArgon2Params(Argon2Params{Memory: MaxArgon2Memory + 1, Time: 2, Threads: 1})); !errors.Is(err, ErrKDFParamsTooHigh) {
+		t.Errorf("Expected ErrKDFParamsTooHigh from CreateContainer, got: %v", err)
+	}
+}
+
+// TestPBKDF2MatchesReference checks if the cancellable PBKDF2 produces the same keys as golang.org/x/crypto/pbkdf2.
+func TestPBKDF2MatchesReference(t *testing.T) {
+	password := []byte("password123")
+	salt := []byte("salt")
+	for name, h := range pbkdf2Hashes {
+		for _, iters := range []int{1, 2, pbkdf2CheckInterval, pbkdf2CheckInterval + 1} {
+			for _, length := range []int{16, 32, 64, 80} {
+				got, err := pbkdf2Key(context.Background(), h, password, salt, iters, length)
+				if err != nil {
+					t.Fatalf("Error deriving key: %v", err)
+				}
+				if want := pbkdf2.Key(password, salt, iters, length, h); !bytes.Equal(got, want) {
+					t.Errorf("%s: key mismatch for %d iterations and length %d", name, iters, length)
+				}
+			}
+		}
+	}
+}
+
+// TestContextCancellation checks if cancelling the context stops a long key derivation promptly.
+func TestContextCancellation(t *testing.T) {
+	plaintext := "hello context"
+	password := "password123"
+
+	slow := mustCreate(t, plaintext, password, WithIterations(minIterations))
+	var c Container
+	if err := json.Unmarshal([]byte(slow), &c); err != nil {
+		t.Fatalf("Error unmarshalling container: %v", err)
+	}
+	c.DeriveInfo.Iters = MaxIterations
+	raw, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Error marshalling container: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{"create pbkdf2", func(ctx context.Context) error {
+			_, err := CreateContainerContext(ctx, plaintext, password, WithIterations(MaxIterations))
+			return err
+		}},
+		{"create argon2id", func(ctx context.Context) error {
+			_, err := CreateContainerContext(ctx, plaintext, password, WithArgon2Params(Argon2Params{Memory: 64 * 1024, Time: 20, Threads: 1}))
+			return err
+		}},
+		{"decrypt pbkdf2", func(ctx context.Context) error {
+			_, err := DecryptContainerContext(ctx, string(raw), password)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			err := tt.run(ctx)
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("Expected prompt return after cancellation, took %v", elapsed)
+			}
+		})
+	}
+
+	// A context that is already cancelled fails before any derivation.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := CreateContainerContext(ctx, plaintext, password, WithIterations(minIterations)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// BenchmarkPBKDF2 measures PBKDF2-SHA256 at a fixed iteration count, so results are comparable across machines.
+func BenchmarkPBKDF2(b *testing.B) {
+	password := []byte("password123")
+	salt := make([]byte, saltLen)
+	for i := 0; i < b.N; i++ {
+		if _, err := pbkdf2Key(context.Background(), sha256.New, password, salt, minIterations, keyLen); err != nil {
+			b.Fatalf("Error deriving key: %v", err)
+		}
+	}
+}
+
+// BenchmarkArgon2 measures Argon2id with DefaultArgon2Params.
+func BenchmarkArgon2(b *testing.B) {
+	password := []byte("password123")
+	salt := make([]byte, saltLen)
+	p := DefaultArgon2Params
+	d := &Derive{KDF: KDFArgon2id, Memory: p.Memory, Time: p.Time, Threads: p.Threads}
+	for i := 0; i < b.N; i++ {
+		if _, err := deriveKey(context.Background(), password, salt, d, keyLen); err != nil {
+			b.Fatalf("Error deriving key: %v", err)
+		}
+	}
+}
+
+// TestPRF checks if both PBKDF2 PRFs round-trip, are recorded in DeriveInfo and derive the expected key.
+func TestPRF(t *testing.T) {
+	plaintext := "hello prf"
+	password := "password123"
+
+	tests := []struct {
+		prf     string
+		wantKDF string
+		hash    func() hash.Hash
+	}{
+		{PRFSHA256, KDFPBKDF2, sha256.New},
+		{PRFSHA512, KDFPBKDF2SHA512, sha512.New},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prf, func(t *testing.T) {
+			containerJSON := mustCreate(t, plaintext, password, WithPRF(tt.prf), WithIterations(minIterations))
+
+			var c Container
+			if err := json.Unmarshal([]byte(containerJSON), &c); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			if c.DeriveInfo.KDF != tt.wantKDF {
+				t.Errorf("Expected KDF %q, got %q", tt.wantKDF, c.DeriveInfo.KDF)
+			}
+
+			// The container's MAC only verifies under the key from the matching hash.
+			salt, _ := hex.DecodeString(c.DeriveInfo.Salt)
+			iv, _ := hex.DecodeString(c.EncryptionInfo.IV)
+			encrypted, _ := hex.DecodeString(c.ContainedData.EncryptedData)
+			_, macKey, err := splitKey(pbkdf2.Key([]byte(password), salt, c.DeriveInfo.Iters, keyLen, tt.hash))
+			if err != nil {
+				t.Fatalf("Error splitting key: %v", err)
+			}
+			if hex.EncodeToString(computeMAC(macKey, macInput(&c, salt, iv, nil, encrypted))) != c.ContainedData.HMAC {
+				t.Errorf("Expected the key to be derived with %s", tt.prf)
+			}
+
+			decryptedText, err := DecryptContainer(containerJSON, password)
+			if err != nil {
+				t.Fatalf("Error decrypting container: %v", err)
+			}
+			if decryptedText != plaintext {
+				t.Errorf("Expected decrypted text to be %q, got %q", plaintext, decryptedText)
+			}
+		})
+	}
+
+	// WithKDF(KDFPBKDF2SHA512) is the same as WithPRF(PRFSHA512).
+	containerJSON := mustCreate(t, plaintext, password, WithKDF(KDFPBKDF2SHA512), WithIterations(minIterations))
+	if header, err := ParseHeader(containerJSON); err != nil || header.DeriveInfo.KDF != KDFPBKDF2SHA512 {
+		t.Errorf("Expected KDF %q, got %+v, %v", KDFPBKDF2SHA512, header.DeriveInfo, err)
+	}
+}
+
+// TestUnsupportedPRF checks if unknown PRFs are rejected when creating and decrypting, and PRFs conflicting with the KDF are rejected.
+func TestUnsupportedPRF(t *testing.T) {
+	password := "password123"
+	if _, err := CreateContainer("hello prf", password, WithPRF("md5")); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+	for _, opts := range [][]Option{
+		{WithPRF(PRFSHA512), WithKDF(KDFScrypt)},
+		{WithPRF(PRFSHA256), WithKDF(KDFPBKDF2SHA512)},
+	} {
+		if _, err := CreateContainer("hello prf", password, opts...); err == nil {
+			t.Errorf("Expected an error for conflicting PRF and KDF")
+		}
+	}
+
+	var c Container
+	if err := json.Unmarshal([]byte(mustCreate(t, "hello prf", password, WithIterations(minIterations))), &c); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	c.DeriveInfo.KDF = "pbkdf2-md5"
+	raw, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Failed to marshal container: %v", err)
+	}
+	if _, err := DecryptContainer(string(raw), password); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm, got %v", err)
+	}
+}
+
+// TestWeakKDFParams checks if Argon2id and scrypt parameters below the recommended minimums are rejected unless WithAllowWeakParams is given.
+func TestWeakKDFParams(t *testing.T) {
+	password := "password123"
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{"argon2 memory", WithArgon2Params(Argon2Params{Memory: 8 * 1024, Time: 2, Threads: 1})},
+		{"argon2 time", WithArgon2Params(Argon2Params{Memory: 19 * 1024, Time: 1, Threads: 1})},
+		{"scrypt N", WithScryptParams(1<<10, 8, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateContainer("hello weak", password, tt.opt)
+			if !errors.Is(err, ErrWeakKDFParams) {
+				t.Fatalf("Expected ErrWeakKDFParams, got %v", err)
+			}
+			if !strings.Contains(err.Error(), "need at least") {
+				t.Errorf("Expected the recommended minimum in %q", err)
+			}
+
+			containerJSON, err := CreateContainer("hello weak", password, tt.opt, WithAllowWeakParams())
+			if err != nil {
+				t.Fatalf("Expected WithAllowWeakParams to accept the parameters, got %v", err)
+			}
+			if decrypted, err := Decrypt(containerJSON, password); err != nil || decrypted != "hello weak" {
+				t.Errorf("Expected the weak container to decrypt, got %q, %v", decrypted, err)
+			}
+		})
+	}
+
+	// The minimums themselves are accepted.
+	if _, err := CreateContainer("hello weak", password, WithArgon2Params(testArgon2Params)); err != nil {
+		t.Errorf("Expected the minimum Argon2id parameters to be accepted, got %v", err)
+	}
+	if _, err := CreateContainer("hello weak", password, WithScryptParams(minScryptN, 8, 1)); err != nil {
+		t.Errorf("Expected the minimum scrypt N to be accepted, got %v", err)
+	}
+}
+
+// TestDeriveKey checks if identical parameters derive identical keys, the key opens the container it came from, base64 salts are decoded and out-of-range parameters are rejected.
+func TestDeriveKey(t *testing.T) {
+	password := "password123"
+	for _, opts := range [][]Option{
+		{WithIterations(minIterations)},
+		{WithArgon2Params(testArgon2Params)},
+		{WithIterations(minIterations), WithEncoding(EncodingBase64)},
+	} {
+		var c Container
+		if err := json.Unmarshal([]byte(mustCreate(t, "hello derive", password, opts...)), &c); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+
+		first, err := DeriveKey(password, c.DeriveInfo, c.ContainerMeta.Encoding)
+		if err != nil {
+			t.Fatalf("%s: error deriving key: %v", c.DeriveInfo.KDF, err)
+		}
+		second, err := DeriveKey(password, c.DeriveInfo, c.ContainerMeta.Encoding)
+		if err != nil {
+			t.Fatalf("%s: error deriving key: %v", c.DeriveInfo.KDF, err)
+		}
+		if len(first) != keyLen || !bytes.Equal(first, second) {
+			t.Errorf("%s: expected identical %d-byte keys, got %x and %x", c.DeriveInfo.KDF, keyLen, first, second)
+		}
+		if other, err := DeriveKey("wrongpassword", c.DeriveInfo, c.ContainerMeta.Encoding); err != nil || bytes.Equal(other, first) {
+			t.Errorf("%s: expected a different key for another password, err: %v", c.DeriveInfo.KDF, err)
+		}
+
+		_, macKey, err := splitKey(first)
+		if err != nil {
+			t.Fatalf("Error splitting key: %v", err)
+		}
+		f, err := c.decodeFields()
+		if err != nil {
+			t.Fatalf("Error decoding fields: %v", err)
+		}
+		mac, err := containerMAC(macKey, &c, f.salt, f.iv, nil, f.ciphertext)
+		if err != nil {
+			t.Fatalf("Error computing MAC: %v", err)
+		}
+		if !bytes.Equal(mac, f.mac) {
+			t.Errorf("%s: expected the derived key to authenticate its container", c.DeriveInfo.KDF)
+		}
+	}
+
+	d := Derive{Salt: strings.Repeat("00", saltLen), Iters: MaxIterations + 1}
+	if _, err := DeriveKey(password, d, EncodingHex); err == nil {
+		t.Errorf("Expected an error for %d iterations", d.Iters)
+	}
+	if _, err := DeriveKey(password, Derive{Iters: minIterations}, EncodingHex); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for a missing salt, got: %v", err)
+	}
+	if _, err := DeriveKey(password, Derive{Salt: "c2FsdHNhbHRzYWx0c2FsdA", Iters: minIterations}, EncodingHex); !errors.Is(err, ErrInvalidHex) {
+		t.Errorf("Expected ErrInvalidHex for a base64 salt read as hex, got: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+package container
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+)
+
+// kekContext separates the passphrase mixing from any other HMAC under the
+// same KEK.
+const kekContext = "go-crypto-container kek v1\x00"
+
+// minKEKLen is the shortest KEK accepted.
+const minKEKLen = 16
+
+// kekPassword mixes kek into passphrase with HMAC-SHA256, giving the
+// password that is actually fed to the KDF. Without kek it cannot be
+// computed, so guessing the passphrase alone gets an attacker nowhere.
+func kekPassword(passphrase string, kek []byte) (string, error) {
+	if len(kek) < minKEKLen {
+		return "", errors.New("KEK must be at least 16 bytes")
+	}
+	mac := hmac.New(sha256.New, kek)
+	mac.Write([]byte(kekContext))
+	mac.Write([]byte(passphrase))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// CreateContainerWrappedPassword is CreateContainer for a friendly
+// passphrase combined with kek, a long random secret such as one held in an
+// environment variable. kek is mixed into the passphrase before key
+// derivation, so the container opens only with both; Meta.KEK records that a
+// KEK is needed. kek must be at least 16 bytes. Password policy options such
+// as WithMinPasswordEntropy apply to the passphrase.
+func CreateContainerWrappedPassword(plaintext, passphrase string, kek []byte, opts ...Option) (string, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	if err := o.checkPassword(passphrase); err != nil {
+		return "", err
+	}
+	password, err := kekPassword(passphrase, kek)
+	if err != nil {
+		return "", err
+	}
+	o.kek = true
+	pt := []byte(plaintext)
+	defer zeroize(pt)
+	b, err := createContainer(pt, password, o)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecryptContainerWrappedPassword decrypts a container created with
+// CreateContainerWrappedPassword, given the same passphrase and KEK. A wrong
+// passphrase or KEK gives ErrHMACMismatch, as does a container made without
+// a KEK.
+func DecryptContainerWrappedPassword(containerJSON, passphrase string, kek []byte, opts ...DecryptOption) (string, error) {
+	password, err := kekPassword(passphrase, kek)
+	if err != nil {
+		return "", err
+	}
+	p := newDecryptParams("", opts)
+	p.kek = true
+	return decryptString(containerJSON, password, p)
+}
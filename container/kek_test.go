@@ -0,0 +1,51 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestWrappedPassword checks if a KEK-wrapped container opens only with both the passphrase and the KEK.
+func TestWrappedPassword(t *testing.T) {
+	passphrase := "correct horse"
+	kek := bytes.Repeat([]byte{0x42}, 32)
+
+	containerJSON, err := CreateContainerWrappedPassword("hello kek", passphrase, kek, WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if !header.ContainerMeta.KEK {
+		t.Errorf("Expected Meta.KEK to be set")
+	}
+
+	decryptedText, err := DecryptContainerWrappedPassword(containerJSON, passphrase, kek)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != "hello kek" {
+		t.Errorf("Expected %q, got %q", "hello kek", decryptedText)
+	}
+
+	if _, err := DecryptContainerWrappedPassword(containerJSON, passphrase, bytes.Repeat([]byte{0x43}, 32)); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong KEK, got: %v", err)
+	}
+	if _, err := DecryptContainerWrappedPassword(containerJSON, "wrong horse", kek); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong passphrase, got: %v", err)
+	}
+	if _, err := Decrypt(containerJSON, passphrase); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch without the KEK, got: %v", err)
+	}
+	if _, err := DecryptContainerWrappedPassword(containerJSON, passphrase, nil); err == nil {
+		t.Errorf("Expected an error for an absent KEK")
+	}
+
+	plain := mustCreate(t, "hello kek", passphrase, WithIterations(minIterations))
+	if _, err := DecryptContainerWrappedPassword(plain, passphrase, kek); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a container made without a KEK, got: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package container
+
+import (
+	"crypto/aes"
+	"crypto/subtle"
+	"encoding/binary"
+	"fmt"
+)
+
+// keyWrapIV is the default initial value of RFC 3394, checked on unwrapping.
+var keyWrapIV = []byte{0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6, 0xa6}
+
+// wrapKey wraps cek under kek with AES Key Wrap (RFC 3394). cek must be a
+// multiple of 8 bytes and at least 16 bytes long; the result is 8 bytes
+// longer.
+func wrapKey(kek, cek []byte) ([]byte, error) {
+	if len(cek) < 16 || len(cek)%8 != 0 {
+		return nil, fmt.Errorf("key to wrap is %d bytes, want a multiple of 8 of at least 16", len(cek))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(cek) / 8
+	out := make([]byte, 8+len(cek))
+	copy(out, keyWrapIV)
+	copy(out[8:], cek)
+
+	var b [aes.BlockSize]byte
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(b[:8], out[:8])
+			copy(b[8:], out[8*i:8*i+8])
+			block.Encrypt(b[:], b[:])
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(out[:8], binary.BigEndian.Uint64(b[:8])^t)
+			copy(out[8*i:8*i+8], b[8:])
+		}
+	}
+	return out, nil
+}
+
+// unwrapKey reverses wrapKey, returning ErrHMACMismatch if the integrity
+// check of RFC 3394 fails because kek is wrong or wrapped was modified.
+func unwrapKey(kek, wrapped []byte) ([]byte, error) {
+	if len(wrapped) < 24 || len(wrapped)%8 != 0 {
+		return nil, fmt.Errorf("%w: wrapped key is %d bytes", ErrMalformedContainer, len(wrapped))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+	n := len(wrapped)/8 - 1
+	var a [8]byte
+	copy(a[:], wrapped[:8])
+	cek := make([]byte, 8*n)
+	copy(cek, wrapped[8:])
+
+	var b [aes.BlockSize]byte
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			t := uint64(n*j + i)
+			binary.BigEndian.PutUint64(b[:8], binary.BigEndian.Uint64(a[:])^t)
+			copy(b[8:], cek[8*(i-1):8*i])
+			block.Decrypt(b[:], b[:])
+			copy(a[:], b[:8])
+			copy(cek[8*(i-1):8*i], b[8:])
+		}
+	}
+	zeroize(b[:])
+	if subtle.ConstantTimeCompare(a[:], keyWrapIV) != 1 {
+		zeroize(cek)
+		return nil, ErrHMACMismatch
+	}
+	return cek, nil
+}
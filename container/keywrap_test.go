@@ -0,0 +1,65 @@
+package container
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// TestWrapKeyRFC3394 checks if wrapKey and unwrapKey reproduce the AES-256 test vectors of RFC 3394.
+func TestWrapKeyRFC3394(t *testing.T) {
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	tests := []struct {
+		cek, wrapped string
+	}{
+		{"00112233445566778899AABBCCDDEEFF", "64E8C3F9CE0F5BA263E9777905818A2A93C8191E7D6E8AE7"},
+		{"00112233445566778899AABBCCDDEEFF0001020304050607", "A8F9BC1612C68B3FF6E6F4FBE30E71E4769C8B80A32CB8958CD5D17D6B254DA1"},
+		{"00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F", "28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21"},
+	}
+	for _, tt := range tests {
+		cek, _ := hex.DecodeString(tt.cek)
+		want, _ := hex.DecodeString(tt.wrapped)
+
+		wrapped, err := wrapKey(kek, cek)
+		if err != nil {
+			t.Fatalf("Error wrapping key: %v", err)
+		}
+		if !bytes.Equal(wrapped, want) {
+			t.Errorf("Expected wrapped key %X, got %X", want, wrapped)
+		}
+		got, err := unwrapKey(kek, wrapped)
+		if err != nil {
+			t.Fatalf("Error unwrapping key: %v", err)
+		}
+		if !bytes.Equal(got, cek) {
+			t.Errorf("Expected unwrapped key %X, got %X", cek, got)
+		}
+	}
+}
+
+// TestUnwrapKeyTampered checks if unwrapKey fails its integrity check for a modified wrapped key or the wrong KEK.
+func TestUnwrapKeyTampered(t *testing.T) {
+	kek := bytes.Repeat([]byte{1}, 32)
+	wrapped, err := wrapKey(kek, bytes.Repeat([]byte{2}, 64))
+	if err != nil {
+		t.Fatalf("Error wrapping key: %v", err)
+	}
+
+	for i := range wrapped {
+		tampered := bytes.Clone(wrapped)
+		tampered[i] ^= 0x01
+		if _, err := unwrapKey(kek, tampered); !errors.Is(err, ErrHMACMismatch) {
+			t.Fatalf("Expected ErrHMACMismatch with byte %d modified, got: %v", i, err)
+		}
+	}
+	if _, err := unwrapKey(bytes.Repeat([]byte{3}, 32), wrapped); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong KEK, got: %v", err)
+	}
+	if _, err := unwrapKey(kek, wrapped[:len(wrapped)-1]); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for a truncated wrapped key, got: %v", err)
+	}
+	if _, err := wrapKey(kek, make([]byte, 12)); err == nil {
+		t.Errorf("Expected an error wrapping a key that is not a multiple of 8 bytes")
+	}
+}
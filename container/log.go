@@ -0,0 +1,48 @@
+package container
+
+import (
+	"context"
+	"log/slog"
+)
+
+// logContainer records the parameters of c on logger, if set. Only the
+// names and costs of the algorithms are logged, never the password, keys,
+// salt, IV, ciphertext, MAC or annotations.
+func logContainer(ctx context.Context, logger *slog.Logger, msg string, c *Container, err error) {
+	if logger == nil {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("version", c.ContainerMeta.Version),
+		slog.String("cipher", c.cipherName()),
+		slog.Int("key_size", c.EncryptionInfo.keySize()),
+	}
+	derive := &c.DeriveInfo
+	if len(c.WrappedKeys) > 0 {
+		attrs = append(attrs, slog.Int("recipients", len(c.WrappedKeys)))
+		if c.WrappedKeys[0].DeriveInfo != nil {
+			derive = c.WrappedKeys[0].DeriveInfo
+		}
+	}
+	attrs = append(attrs, slog.String("kdf", derive.kdfName()))
+	switch {
+	case derive.isPBKDF2():
+		attrs = append(attrs, slog.Int("iters", derive.Iters))
+	case derive.kdfName() == KDFArgon2id:
+		attrs = append(attrs, slog.Any("memory", derive.Memory), slog.Any("time", derive.Time), slog.Any("threads", derive.Threads))
+	case derive.kdfName() == KDFScrypt:
+		attrs = append(attrs, slog.Int("n", derive.N), slog.Int("r", derive.R), slog.Int("p", derive.P))
+	}
+	if enc := c.ContainerMeta.Encoding; enc != "" {
+		attrs = append(attrs, slog.String("encoding", string(enc)))
+	}
+	if c.ContainerMeta.Compression != "" {
+		attrs = append(attrs, slog.String("compression", c.ContainerMeta.Compression))
+	}
+	level := slog.LevelInfo
+	if err != nil {
+		level = slog.LevelWarn
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	logger.LogAttrs(ctx, level, msg, attrs...)
+}
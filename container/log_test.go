@@ -0,0 +1,79 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// captureHandler is a slog.Handler that keeps every record it handles.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(context.Context, slog.Level) bool { return true }
+
+func (h *captureHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+func (h *captureHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+func (h *captureHandler) WithGroup(string) slog.Handler      { return h }
+
+// TestWithLogger checks if creation and decryption records carry iters but no secret fields or values.
+func TestWithLogger(t *testing.T) {
+	password := "password123"
+	h := &captureHandler{}
+	logger := slog.New(h)
+
+	containerJSON := mustCreate(t, "hello logger", password, WithLogger(logger), WithIterations(minIterations))
+	if _, err := Decrypt(containerJSON, password, WithDecryptLogger(logger)); err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if _, err := Decrypt(containerJSON, "wrongpassword", WithDecryptLogger(logger)); !errors.Is(err, ErrHMACMismatch) {
+		t.Fatalf("Expected ErrHMACMismatch, got: %v", err)
+	}
+	if len(h.records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(h.records))
+	}
+
+	var c Container
+	if err := json.Unmarshal([]byte(containerJSON), &c); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	secrets := []string{password, c.DeriveInfo.Salt, c.EncryptionInfo.IV, c.ContainedData.EncryptedData, c.ContainedData.HMAC}
+	for _, r := range h.records {
+		attrs := make(map[string]string)
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.String()
+			return true
+		})
+		if attrs["iters"] != strconv.Itoa(minIterations) {
+			t.Errorf("%s: expected iters %d, got %v", r.Message, minIterations, attrs)
+		}
+		for _, key := range []string{"password", "key", "salt", "iv", "ciphertext", "mac"} {
+			if _, ok := attrs[key]; ok {
+				t.Errorf("%s: expected no %s attribute", r.Message, key)
+			}
+		}
+		for key, value := range attrs {
+			for _, secret := range secrets {
+				if strings.Contains(value, secret) {
+					t.Errorf("%s: attribute %s leaks a secret", r.Message, key)
+				}
+			}
+		}
+	}
+	if last := h.records[2]; last.Level != slog.LevelWarn {
+		t.Errorf("Expected a failed decryption to be logged as a warning, got %s", last.Level)
+	}
+}
@@ -0,0 +1,33 @@
+package container
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+)
+
+// MAC hashes accepted by WithMACHash and recorded in Meta.MACHash.
+const (
+	MACHashSHA256 = "sha256"
+	MACHashSHA384 = "sha384"
+	MACHashSHA512 = "sha512"
+)
+
+// macHashes maps each Meta.MACHash to its hash. Containers without one use
+// HMAC-SHA256.
+var macHashes = map[string]func() hash.Hash{
+	"":            sha256.New,
+	MACHashSHA256: sha256.New,
+	MACHashSHA384: sha512.New384,
+	MACHashSHA512: sha512.New,
+}
+
+// macHash returns the hash for the MAC hash name, or ErrUnsupportedAlgorithm.
+func macHash(name string) (func() hash.Hash, error) {
+	h, ok := macHashes[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: MAC hash %q", ErrUnsupportedAlgorithm, name)
+	}
+	return h, nil
+}
@@ -0,0 +1,69 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestWithMACHash checks if each MAC hash round-trips, is recorded in the header and gives a MAC of its size.
+func TestWithMACHash(t *testing.T) {
+	password := "password123"
+	for _, tt := range []struct {
+		name     string
+		macLen   int
+		cipher   string
+		recorded string
+	}{
+		{MACHashSHA256, 32, CipherAES256CTR, ""},
+		{MACHashSHA384, 48, CipherAES256CTR, MACHashSHA384},
+		{MACHashSHA512, 64, CipherAES256CTR, MACHashSHA512},
+		{MACHashSHA512, 64, CipherAES256CBC, MACHashSHA512},
+	} {
+		containerJSON := mustCreate(t, "hello mac hash", password, WithCipher(tt.cipher), WithMACHash(tt.name), WithIterations(minIterations))
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		if container.ContainerMeta.MACHash != tt.recorded {
+			t.Errorf("%s/%s: expected MACHash %q, got %q", tt.cipher, tt.name, tt.recorded, container.ContainerMeta.MACHash)
+		}
+		if got := len(container.ContainedData.HMAC) / 2; got != tt.macLen {
+			t.Errorf("%s/%s: expected a %d-byte MAC, got %d", tt.cipher, tt.name, tt.macLen, got)
+		}
+
+		decryptedText, err := Decrypt(containerJSON, password)
+		if err != nil {
+			t.Fatalf("%s/%s: error decrypting container: %v", tt.cipher, tt.name, err)
+		}
+		if decryptedText != "hello mac hash" {
+			t.Errorf("%s/%s: expected 'hello mac hash', got '%s'", tt.cipher, tt.name, decryptedText)
+		}
+	}
+}
+
+// TestWithMACHashRejects checks if unknown MAC hashes are rejected on create and decrypt, and an edited MAC hash fails authentication.
+func TestWithMACHashRejects(t *testing.T) {
+	password := "password123"
+	if _, err := CreateContainer("x", password, WithMACHash("md5"), WithIterations(minIterations)); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm for an unknown MAC hash, got: %v", err)
+	}
+	if _, err := CreateContainer("x", password, WithCipher(CipherAES256GCM), WithMACHash(MACHashSHA512), WithIterations(minIterations)); err == nil {
+		t.Errorf("Expected an error for a MAC hash with an AEAD cipher")
+	}
+
+	containerJSON := mustCreate(t, "hello mac hash", password, WithMACHash(MACHashSHA512), WithIterations(minIterations))
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+
+	container.ContainerMeta.MACHash = "md5"
+	if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("Expected ErrUnsupportedAlgorithm for an unknown MAC hash, got: %v", err)
+	}
+	container.ContainerMeta.MACHash = MACHashSHA384
+	if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for an edited MAC hash, got: %v", err)
+	}
+}
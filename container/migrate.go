@@ -0,0 +1,125 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Reencrypt decrypts a container in any readable format, and re-encrypts its
+// plaintext under the same password in the format opts select, with the same
+// defaults as CreateContainer. The old container is fully authenticated
+// first, so tampered data is never re-encrypted.
+//
+// Unlike the decryption functions, it reads legacy v1.0 containers without
+// WithLegacyV10, since converting them is what it is for. See WithLegacyV10
+// for what their check does and does not catch.
+func Reencrypt(oldJSON, password string, opts ...Option) (newJSON string, err error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := decryptContainer([]byte(oldJSON), password, decryptParams{legacy: true})
+	if err != nil {
+		return "", err
+	}
+	defer zeroize(plaintext)
+
+	b, err := createContainer(plaintext, password, o)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// UpgradeContainer is Reencrypt to the v2 format with AES-256-GCM. opts are
+// applied on top of that default.
+func UpgradeContainer(oldJSON, password string, opts ...Option) (newJSON string, err error) {
+	return Reencrypt(oldJSON, password, append([]Option{WithCipher(CipherAES256GCM)}, opts...)...)
+}
+
+// RefreshContainer re-encrypts containerJSON under the same password with a
+// fresh salt and IV, and for PBKDF2 a freshly calibrated iteration count, so
+// the ciphertext is re-randomized as routine hygiene. The cipher, KDF,
+// encoding, compression, expiry, annotations, content type, key ID and dedup
+// tag are kept. The container is fully authenticated first. Containers bound to
+// associated data, and envelope containers with more than one recipient, are
+// rejected, since they cannot be rebuilt from the password alone. So are
+// containers whose password was combined with a KEK, whose ciphertext is
+// stored externally or that were created with a raw key, which fail with
+// ErrUnsupportedAlgorithm.
+func RefreshContainer(containerJSON, password string) (string, error) {
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	opts, err := refreshOptions(&container)
+	if err != nil {
+		return "", err
+	}
+	return Reencrypt(containerJSON, password, opts...)
+}
+
+// refreshOptions returns the options that recreate the settings of c.
+func refreshOptions(c *Container) ([]Option, error) {
+	if c.ContainerMeta.AAD {
+		return nil, errors.New("cannot refresh a container bound to associated data")
+	}
+	switch {
+	case c.ContainerMeta.KEK:
+		return nil, fmt.Errorf("%w: RefreshContainer does not support containers with a KEK", ErrUnsupportedAlgorithm)
+	case c.ContainerMeta.External:
+		return nil, fmt.Errorf("%w: RefreshContainer does not support external ciphertext", ErrUnsupportedAlgorithm)
+	case c.DeriveInfo.KDF == KDFNone:
+		return nil, fmt.Errorf("%w: RefreshContainer does not support raw-key containers", ErrUnsupportedAlgorithm)
+	}
+	derive := c.DeriveInfo
+	var opts []Option
+	if c.ContainerMeta.Version == versionEnvelope {
+		if len(c.WrappedKeys) != 1 || c.WrappedKeys[0].DeriveInfo == nil {
+			return nil, errors.New("cannot refresh an envelope container with other recipients; use RekeyContainer")
+		}
+		derive = *c.WrappedKeys[0].DeriveInfo
+		opts = append(opts, WithEnvelope())
+	}
+	// Dropping the iteration count makes PBKDF2 calibrate a new one.
+	derive.Iters = 0
+	kdfOpts, err := deriveOptions(derive)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, kdfOpts...)
+	opts = append(opts, WithCipher(c.cipherName()))
+	if c.EncryptionInfo.KeySize != 0 {
+		opts = append(opts, WithKeySize(c.EncryptionInfo.KeySize))
+	}
+	if c.ContainerMeta.Compression != "" {
+		opts = append(opts, WithCompression(c.ContainerMeta.Compression))
+	}
+	if c.ContainerMeta.Padding != 0 {
+		opts = append(opts, WithPadding(c.ContainerMeta.Padding))
+	}
+	if c.ContainerMeta.PlaintextLen != 0 {
+		opts = append(opts, WithPlaintextLen())
+	}
+	if c.ContainerMeta.MACHash != "" {
+		opts = append(opts, WithMACHash(c.ContainerMeta.MACHash))
+	}
+	if c.ContainerMeta.Encoding != "" {
+		opts = append(opts, WithEncoding(c.ContainerMeta.Encoding))
+	}
+	if len(c.ContainerMeta.Annotations) > 0 {
+		opts = append(opts, WithAnnotations(c.ContainerMeta.Annotations))
+	}
+	if c.ContainerMeta.KeyID != "" {
+		opts = append(opts, WithKeyID(c.ContainerMeta.KeyID))
+	}
+	meta := c.ContainerMeta
+	opts = append(opts, func(o *options) {
+		o.expiresAt = meta.ExpiresAt
+		o.contentType = meta.ContentType
+		o.dedupTag = meta.DedupTag
+	})
+	return opts, nil
+}
@@ -0,0 +1,152 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestUpgradeContainer checks if a v1.0 container is upgraded to a v2 GCM container that decrypts to the same plaintext.
+func TestUpgradeContainer(t *testing.T) {
+	password := "password123"
+
+	newJSON, err := UpgradeContainer(legacyV10Container, password)
+	if err != nil {
+		t.Fatalf("Error upgrading container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(newJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.ContainerMeta.Version != "v2" || container.EncryptionInfo.Cipher != CipherAES256GCM {
+		t.Errorf("Expected a v2 %s container, got %+v", CipherAES256GCM, container.ContainerMeta)
+	}
+
+	decryptedText, err := DecryptContainerGCM(newJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting upgraded container: %v", err)
+	}
+	if decryptedText != "legacy v1.0 container" {
+		t.Errorf("Expected decrypted text to be 'legacy v1.0 container', got '%s'", decryptedText)
+	}
+}
+
+// TestUpgradeContainerRejectsTampered checks if a tampered or wrongly unlocked container is not upgraded.
+func TestUpgradeContainerRejectsTampered(t *testing.T) {
+	var container Container
+	if err := json.Unmarshal([]byte(legacyV10Container), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainedData.EncryptedData = flipLastHexDigit(container.ContainedData.EncryptedData)
+	tampered, err := json.Marshal(container)
+	if err != nil {
+		t.Fatalf("Failed to marshal tampered container: %v", err)
+	}
+
+	if _, err := UpgradeContainer(string(tampered), "password123"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a tampered container, got: %v", err)
+	}
+	if _, err := UpgradeContainer(legacyV10Container, "wrongpassword"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong password, got: %v", err)
+	}
+}
+
+// TestReencrypt checks if a v1.0 CTR container is rotated to an Argon2id GCM container that decrypts to the same plaintext.
+func TestReencrypt(t *testing.T) {
+	password := "password123"
+
+	newJSON, err := Reencrypt(legacyV10Container, password, WithCipher(CipherAES256GCM), WithArgon2Params(testArgon2Params))
+	if err != nil {
+		t.Fatalf("Error re-encrypting container: %v", err)
+	}
+
+	header, err := ParseHeader(newJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if header.EncryptionInfo.Cipher != CipherAES256GCM || header.DeriveInfo.KDF != KDFArgon2id {
+		t.Errorf("Expected a %s container with %s, got %s with %s", CipherAES256GCM, KDFArgon2id, header.EncryptionInfo.Cipher, header.DeriveInfo.KDF)
+	}
+
+	decryptedText, err := Decrypt(newJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting re-encrypted container: %v", err)
+	}
+	if decryptedText != "legacy v1.0 container" {
+		t.Errorf("Expected decrypted text to be 'legacy v1.0 container', got '%s'", decryptedText)
+	}
+
+	if _, err := Reencrypt(legacyV10Container, "wrongpassword", WithCipher(CipherAES256GCM)); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong password, got: %v", err)
+	}
+}
+
+// TestRefreshContainer checks if a refreshed container has a new salt and IV, keeps its settings and decrypts to the same plaintext.
+func TestRefreshContainer(t *testing.T) {
+	password := "password123"
+	annotations := map[string]string{"owner": "ops"}
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		oldJSON := mustCreate(t, "hello refresh", password, WithCipher(cipherName), WithEncoding(EncodingBase64), WithAnnotations(annotations), WithIterations(minIterations))
+
+		newJSON, err := RefreshContainer(oldJSON, password)
+		if err != nil {
+			t.Fatalf("%s: error refreshing container: %v", cipherName, err)
+		}
+		var before, after Container
+		if err := json.Unmarshal([]byte(oldJSON), &before); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		if err := json.Unmarshal([]byte(newJSON), &after); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		if after.DeriveInfo.Salt == before.DeriveInfo.Salt || after.EncryptionInfo.IV == before.EncryptionInfo.IV {
+			t.Errorf("%s: expected a fresh salt and IV", cipherName)
+		}
+		if after.cipherName() != cipherName || after.ContainerMeta.Encoding != EncodingBase64 || after.ContainerMeta.Annotations["owner"] != "ops" {
+			t.Errorf("%s: expected settings to be kept, got %+v", cipherName, after.ContainerMeta)
+		}
+
+		decryptedText, err := Decrypt(newJSON, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting refreshed container: %v", cipherName, err)
+		}
+		if decryptedText != "hello refresh" {
+			t.Errorf("%s: expected %q, got %q", cipherName, "hello refresh", decryptedText)
+		}
+	}
+
+	if _, err := RefreshContainer(legacyV10Container, "wrongpassword"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong password, got: %v", err)
+	}
+}
+
+// TestRefreshContainerRejects checks if containers that cannot be rebuilt from the password alone are rejected up front with ErrUnsupportedAlgorithm.
+func TestRefreshContainerRejects(t *testing.T) {
+	password := "password123"
+	kek := bytes.Repeat([]byte{0x24}, keyLen)
+	kekJSON, err := CreateContainerWrappedPassword("hello refresh", password, kek, WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating KEK container: %v", err)
+	}
+	var ciphertext []byte
+	externalJSON := mustCreate(t, "hello refresh", password, WithExternalCiphertext(&ciphertext), WithIterations(minIterations))
+	rawJSON, err := CreateContainerWithKey("hello refresh", bytes.Repeat([]byte{0x42}, keyLen))
+	if err != nil {
+		t.Fatalf("Error creating raw-key container: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name          string
+		containerJSON string
+	}{
+		{"kek", kekJSON},
+		{"external", externalJSON},
+		{"raw key", rawJSON},
+	} {
+		if _, err := RefreshContainer(tt.containerJSON, password); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Errorf("%s: expected ErrUnsupportedAlgorithm, got: %v", tt.name, err)
+		}
+	}
+}
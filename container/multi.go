@@ -0,0 +1,51 @@
+package container
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DecryptMulti decrypts newline-delimited containers read from r, as written
+// by log shippers that append one container per line, and returns their
+// plaintexts in order. Blank lines are skipped and do not count as entries.
+// Like Decrypt it accepts containers of any format.
+//
+// By default it stops at the first entry that fails, returning the plaintexts
+// before it and an ErrEntry with its index. With WithCollectErrors it decrypts
+// every entry instead, leaving "" for those that fail, and returns their
+// ErrEntry errors joined.
+func DecryptMulti(r io.Reader, password string, opts ...DecryptOption) ([]string, error) {
+	p := newDecryptParams("", opts)
+	br := bufio.NewReader(r)
+	var plaintexts []string
+	var errs []error
+	for index := 0; ; {
+		line, readErr := br.ReadBytes('\n')
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			plaintext, err := decryptContainer(line, password, p)
+			var s string
+			if err == nil {
+				s, err = p.plaintextString(plaintext)
+			}
+			if err != nil {
+				err = ErrEntry{Index: index, Err: err}
+				if !p.collect {
+					return plaintexts, err
+				}
+				errs = append(errs, err)
+			}
+			plaintexts = append(plaintexts, s)
+			zeroize(plaintext)
+			index++
+		}
+		if readErr == io.EOF {
+			return plaintexts, errors.Join(errs...)
+		}
+		if readErr != nil {
+			return plaintexts, fmt.Errorf("reading containers: %w", readErr)
+		}
+	}
+}
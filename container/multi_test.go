@@ -0,0 +1,66 @@
+package container
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// multiTestInput returns three containers, one per line, with the middle one tampered.
+func multiTestInput(t *testing.T, password string) string {
+	t.Helper()
+	var lines []string
+	for _, plaintext := range []string{"first", "second", "third"} {
+		lines = append(lines, mustCreate(t, plaintext, password, WithIterations(minIterations)))
+	}
+	lines[1] = strings.Replace(lines[1], `"HMAC":"`, `"HMAC":"00`, 1)
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// TestDecryptMulti checks if newline-delimited containers decrypt in order and blank lines are skipped.
+func TestDecryptMulti(t *testing.T) {
+	password := "password123"
+	input := "\n" + mustCreate(t, "first", password, WithIterations(minIterations)) + "\n\n" +
+		mustCreate(t, "second", password, WithCipher(CipherAES256GCM), WithIterations(minIterations)) + "\r\n" +
+		mustCreate(t, "third", password, WithIterations(minIterations))
+
+	plaintexts, err := DecryptMulti(strings.NewReader(input), password)
+	if err != nil {
+		t.Fatalf("Error decrypting containers: %v", err)
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(plaintexts, want) {
+		t.Errorf("Expected %q, got %q", want, plaintexts)
+	}
+
+	plaintexts, err = DecryptMulti(strings.NewReader(""), password)
+	if err != nil || len(plaintexts) != 0 {
+		t.Errorf("Expected no plaintexts and no error for empty input, got %q and %v", plaintexts, err)
+	}
+}
+
+// TestDecryptMultiTampered checks if a tampered entry is reported by index, stopping by default and collected with WithCollectErrors.
+func TestDecryptMultiTampered(t *testing.T) {
+	password := "password123"
+	input := multiTestInput(t, password)
+
+	plaintexts, err := DecryptMulti(strings.NewReader(input), password)
+	var entryErr ErrEntry
+	if !errors.As(err, &entryErr) || entryErr.Index != 1 {
+		t.Fatalf("Expected ErrEntry for entry 1, got: %v", err)
+	}
+	if !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrEntry to wrap ErrHMACMismatch, got: %v", err)
+	}
+	if want := []string{"first"}; !reflect.DeepEqual(plaintexts, want) {
+		t.Errorf("Expected %q before the failing entry, got %q", want, plaintexts)
+	}
+
+	plaintexts, err = DecryptMulti(strings.NewReader(input), password, WithCollectErrors())
+	if !errors.As(err, &entryErr) || entryErr.Index != 1 {
+		t.Fatalf("Expected ErrEntry for entry 1, got: %v", err)
+	}
+	if want := []string{"first", "", "third"}; !reflect.DeepEqual(plaintexts, want) {
+		t.Errorf("Expected %q, got %q", want, plaintexts)
+	}
+}
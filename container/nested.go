@@ -0,0 +1,23 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// looksLikeContainer reports whether plaintext is itself a container, as
+// JSON with a valid header, in the binary format or PEM-armored. It is a
+// heuristic for WithRejectNestedContainer, not a full validation.
+func looksLikeContainer(plaintext []byte) bool {
+	trimmed := bytes.TrimSpace(plaintext)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var header Header
+		return json.Unmarshal(trimmed, &header) == nil && header.checkRequired() == nil
+	case bytes.HasPrefix(plaintext, []byte(binaryMagic)):
+		return len(plaintext) > len(binaryMagic) && plaintext[len(binaryMagic)] == binaryVersion
+	case bytes.HasPrefix(trimmed, []byte("-----BEGIN "+pemType+"-----")):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package container
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestWithRejectNestedContainer checks if a container fed back in as plaintext is rejected only under the option.
+func TestWithRejectNestedContainer(t *testing.T) {
+	password := "password123"
+	inner := mustCreate(t, "hello nested", password, WithIterations(minIterations))
+	binary, err := CreateContainerBinary([]byte("hello nested"), password, WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating binary container: %v", err)
+	}
+	armored, err := EncodePEM(inner)
+	if err != nil {
+		t.Fatalf("Error armoring container: %v", err)
+	}
+
+	for name, plaintext := range map[string]string{"json": "\n" + inner, "binary": string(binary), "pem": armored} {
+		if _, err := CreateContainer(plaintext, password, WithRejectNestedContainer(), WithIterations(minIterations)); !errors.Is(err, ErrNestedContainer) {
+			t.Errorf("%s: expected ErrNestedContainer, got: %v", name, err)
+		}
+		if _, err := CreateContainer(plaintext, password, WithIterations(minIterations)); err != nil {
+			t.Errorf("%s: expected nesting to be allowed by default, got: %v", name, err)
+		}
+	}
+
+	for _, plaintext := range []string{`{"name": "not a container"}`, "hello world", ""} {
+		if _, err := CreateContainer(plaintext, password, WithRejectNestedContainer(), WithIterations(minIterations)); err != nil {
+			t.Errorf("Expected %q to be accepted, got: %v", plaintext, err)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+)
+
+type observation struct {
+	duration time.Duration
+	err      error
+}
+
+type fakeObserver struct {
+	mu       sync.Mutex
+	encrypts []observation
+	decrypts []observation
+}
+
+func (f *fakeObserver) ObserveEncrypt(duration time.Duration, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.encrypts = append(f.encrypts, observation{duration, err})
+}
+
+func (f *fakeObserver) ObserveDecrypt(duration time.Duration, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.decrypts = append(f.decrypts, observation{duration, err})
+}
+
+// TestObserver checks if the observer is called once per encryption and decryption with a non-zero duration and the outcome.
+func TestObserver(t *testing.T) {
+	password := "password123"
+	obs := &fakeObserver{}
+
+	containerJSON := mustCreate(t, "hello observer", password, WithIterations(minIterations), WithObserver(obs))
+	if len(obs.encrypts) != 1 {
+		t.Fatalf("Expected 1 encrypt observation, got %d", len(obs.encrypts))
+	}
+	if o := obs.encrypts[0]; o.duration <= 0 || o.err != nil {
+		t.Errorf("Expected a positive duration and no error, got %v and %v", o.duration, o.err)
+	}
+
+	if _, err := Decrypt(containerJSON, password, WithDecryptObserver(obs)); err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if _, err := Decrypt(containerJSON, "wrongpassword", WithDecryptObserver(obs)); !errors.Is(err, ErrHMACMismatch) {
+		t.Fatalf("Expected ErrHMACMismatch, got: %v", err)
+	}
+	if len(obs.decrypts) != 2 {
+		t.Fatalf("Expected 2 decrypt observations, got %d", len(obs.decrypts))
+	}
+	if o := obs.decrypts[0]; o.duration <= 0 || o.err != nil {
+		t.Errorf("Expected a positive duration and no error, got %v and %v", o.duration, o.err)
+	}
+	if o := obs.decrypts[1]; o.duration <= 0 || !errors.Is(o.err, ErrHMACMismatch) {
+		t.Errorf("Expected a positive duration and ErrHMACMismatch, got %v and %v", o.duration, o.err)
+	}
+	if len(obs.encrypts) != 1 {
+		t.Errorf("Expected decryption not to observe encryption, got %d encrypt observations", len(obs.encrypts))
+	}
+}
+
+// TestObserverEncryptStream checks if EncryptStream observes the stream once as one encryption.
+func TestObserverEncryptStream(t *testing.T) {
+	obs := &fakeObserver{}
+	encryptTestStream(t, []byte("hello observer"), "password123", WithObserver(obs))
+	if len(obs.encrypts) != 1 {
+		t.Fatalf("Expected 1 encrypt observation, got %d", len(obs.encrypts))
+	}
+	if o := obs.encrypts[0]; o.duration <= 0 || o.err != nil {
+		t.Errorf("Expected a positive duration and no error, got %v and %v", o.duration, o.err)
+	}
+}
+
+// TestObserverDecryptStream checks if DecryptStream observes the stream once with its outcome.
+func TestObserverDecryptStream(t *testing.T) {
+	password := "password123"
+	obs := &fakeObserver{}
+	encrypted := encryptTestStream(t, []byte("hello observer"), password)
+
+	if err := DecryptStream(io.Discard, bytes.NewReader(encrypted), password, WithDecryptObserver(obs)); err != nil {
+		t.Fatalf("Error decrypting stream: %v", err)
+	}
+	if err := DecryptStream(io.Discard, bytes.NewReader(encrypted), "wrongpassword", WithDecryptObserver(obs)); !errors.Is(err, ErrHMACMismatch) {
+		t.Fatalf("Expected ErrHMACMismatch, got: %v", err)
+	}
+	if len(obs.decrypts) != 2 {
+		t.Fatalf("Expected 2 decrypt observations, got %d", len(obs.decrypts))
+	}
+	if o := obs.decrypts[0]; o.duration <= 0 || o.err != nil {
+		t.Errorf("Expected a positive duration and no error, got %v and %v", o.duration, o.err)
+	}
+	if o := obs.decrypts[1]; !errors.Is(o.err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch, got %v", o.err)
+	}
+}
+
+// TestObserverDecryptRange checks if DecryptRange observes each range once.
+func TestObserverDecryptRange(t *testing.T) {
+	password := "password123"
+	obs := &fakeObserver{}
+	src := bytes.NewReader(encryptTestStream(t, []byte("hello observer"), password))
+
+	if _, err := DecryptRange(src, password, 6, 8, WithDecryptObserver(obs)); err != nil {
+		t.Fatalf("Error decrypting range: %v", err)
+	}
+	if len(obs.decrypts) != 1 {
+		t.Fatalf("Expected 1 decrypt observation, got %d", len(obs.decrypts))
+	}
+	if o := obs.decrypts[0]; o.duration <= 0 || o.err != nil {
+		t.Errorf("Expected a positive duration and no error, got %v and %v", o.duration, o.err)
+	}
+}
+
+// TestObserverRawKey checks if CreateContainerWithKey observes the container it creates.
+func TestObserverRawKey(t *testing.T) {
+	obs := &fakeObserver{}
+	if _, err := CreateContainerWithKey("hello observer", bytes.Repeat([]byte{0x42}, keyLen), WithObserver(obs)); err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	if len(obs.encrypts) != 1 {
+		t.Fatalf("Expected 1 encrypt observation, got %d", len(obs.encrypts))
+	}
+	if o := obs.encrypts[0]; o.duration <= 0 || o.err != nil {
+		t.Errorf("Expected a positive duration and no error, got %v and %v", o.duration, o.err)
+	}
+}
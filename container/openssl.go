@@ -0,0 +1,62 @@
+package container
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/md5"
+	"fmt"
+)
+
+// opensslMagic starts the output of openssl enc, followed by an 8-byte salt.
+const opensslMagic = "Salted__"
+
+// ImportOpenSSL decrypts data written by
+//
+//	openssl enc -aes-256-ctr -md md5 -pass pass:PASSWORD
+//
+// and re-encrypts the plaintext under the same password into a container as
+// CreateContainer does with opts, for migrating away from openssl enc.
+//
+// This is legacy interop only and is insecure: OpenSSL's EVP_BytesToKey
+// derives the key with a single round of MD5, and openssl enc output has no
+// MAC, so a wrong password or corrupted data is not detected and imports as
+// garbage. Only reading this format is supported, never writing it.
+func ImportOpenSSL(data []byte, password string, opts ...Option) (string, error) {
+	if !bytes.HasPrefix(data, []byte(opensslMagic)) || len(data) < len(opensslMagic)+8 {
+		return "", fmt.Errorf("%w: missing %s header", ErrMalformedContainer, opensslMagic)
+	}
+	salt := data[len(opensslMagic) : len(opensslMagic)+8]
+	ciphertext := data[len(opensslMagic)+8:]
+
+	keyIV := evpBytesToKey([]byte(password), salt, keyLen+aes.BlockSize)
+	defer zeroize(keyIV)
+	block, err := aes.NewCipher(keyIV[:keyLen])
+	if err != nil {
+		return "", err
+	}
+	plaintext := make([]byte, len(ciphertext))
+	defer zeroize(plaintext)
+	cipher.NewCTR(block, keyIV[keyLen:]).XORKeyStream(plaintext, ciphertext)
+
+	b, err := CreateContainerBytes(plaintext, password, opts...)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// evpBytesToKey is OpenSSL's EVP_BytesToKey with MD5 and one iteration,
+// returning n bytes of key material.
+func evpBytesToKey(password, salt []byte, n int) []byte {
+	var out, prev []byte
+	for len(out) < n {
+		h := md5.New()
+		h.Write(prev)
+		h.Write(password)
+		h.Write(salt)
+		prev = h.Sum(nil)
+		out = append(out, prev...)
+	}
+	return out[:n]
+}
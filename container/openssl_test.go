@@ -0,0 +1,39 @@
+package container
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+// TestImportOpenSSL checks if output of openssl enc -aes-256-ctr -md md5 is imported into a container holding the same plaintext.
+func TestImportOpenSSL(t *testing.T) {
+	// printf 'hello from openssl enc\n' | openssl enc -aes-256-ctr -md md5 -pass pass:password123
+	data, _ := hex.DecodeString("53616c7465645f5ffcac5694bc47e61fa8bd5880f849b5f8ec935f94b8d9c53e7b291f6a620f58")
+
+	containerJSON, err := ImportOpenSSL(data, "password123", WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error importing OpenSSL data: %v", err)
+	}
+	decryptedText, err := Decrypt(containerJSON, "password123")
+	if err != nil {
+		t.Fatalf("Error decrypting imported container: %v", err)
+	}
+	if decryptedText != "hello from openssl enc\n" {
+		t.Errorf("Expected %q, got %q", "hello from openssl enc\n", decryptedText)
+	}
+
+	if _, err := ImportOpenSSL(data[8:], "password123"); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer without the Salted__ header, got: %v", err)
+	}
+}
+
+// TestEVPBytesToKey checks if evpBytesToKey matches the key and IV openssl enc -P prints.
+func TestEVPBytesToKey(t *testing.T) {
+	salt, _ := hex.DecodeString("0102030405060708")
+	got := evpBytesToKey([]byte("password123"), salt, 48)
+	want := "31cc9139806998b2aac366cf401b49dc0d37bd5c2252c7cb4401c65d44c1da61" + "3d72c90048ea450c9b3bb01c304fa3a4"
+	if hex.EncodeToString(got) != want {
+		t.Errorf("Expected key and IV %s, got %x", want, got)
+	}
+}
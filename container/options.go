@@ -0,0 +1,544 @@
+package container
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// Option configures CreateContainer.
+//
+// Options are applied in order, so when the same option is given twice the
+// last one wins. Options that tune a key derivation (WithIterations,
+// WithArgon2Params, WithScryptParams) select that KDF implicitly, but it is an
+// error to combine them with a WithKDF naming a different KDF or with each
+// other, whatever their order.
+type Option func(*options)
+
+type scryptParams struct {
+	N, r, p int
+}
+
+type options struct {
+	cipher  string
+	kdf     string
+	iters   int
+	argon2  *Argon2Params
+	scrypt  *scryptParams
+	saltLen int
+	rand    io.Reader
+	// keySizeOpt is the key size requested by WithKeySize, or 0.
+	keySizeOpt  int
+	compression string
+	aad         []byte
+	clock       Clock
+	expiresAt   string
+	annotations map[string]string
+	envelope    bool
+	encoding    Encoding
+	ctx         context.Context
+	progress    func(bytesProcessed int64)
+	prf         string
+	minEntropy  float64
+	allowWeak   bool
+	contentType string
+	observer    Observer
+	logger      *slog.Logger
+	padding     int
+	macHash     string
+	// plaintextLen is set by WithPlaintextLen.
+	plaintextLen bool
+	// rejectNested is set by WithRejectNestedContainer.
+	rejectNested bool
+	// external receives the ciphertext for WithExternalCiphertext.
+	external *[]byte
+	// kek is set by CreateContainerWrappedPassword.
+	kek      bool
+	keyID    string
+	dedupKey []byte
+	// dedupTag is a tag kept by RefreshContainer, used when dedupKey is nil.
+	dedupTag string
+	// indentPrefix and indent are set by WithIndent.
+	indentPrefix string
+	indent       string
+	// info is filled in by sealContainer for CreateContainerWithInfo.
+	info *CreateInfo
+
+	// derive is resolved from the fields above by newOptions.
+	derive Derive
+}
+
+// maxKeyIDLen is the longest key ID WithKeyID accepts.
+const maxKeyIDLen = 256
+
+// DefaultArgon2Params are used by WithKDF(KDFArgon2id) when no explicit
+// parameters are given.
+var DefaultArgon2Params = Argon2Params{Memory: 64 * 1024, Time: 3, Threads: 4}
+
+// Default scrypt parameters used by WithKDF(KDFScrypt).
+const (
+	DefaultScryptN = 32768
+	DefaultScryptR = 8
+	DefaultScryptP = 1
+)
+
+// WithCipher selects the cipher, one of CipherAES256CTR (the default),
+// CipherAES256GCM, CipherXChaCha20Poly1305 or CipherAES256CBC.
+func WithCipher(name string) Option {
+	return func(o *options) { o.cipher = name }
+}
+
+// WithKDF selects the key derivation function, one of KDFPBKDF2 (the
+// default), KDFPBKDF2SHA512, KDFArgon2id or KDFScrypt, with its default
+// parameters.
+func WithKDF(name string) Option {
+	return func(o *options) { o.kdf = name }
+}
+
+// WithIterations sets an explicit PBKDF2 iteration count instead of
+// calibrating one. Any positive count up to MaxIterations is accepted, so
+// deliberately cheap test vectors and containers for systems that use fewer
+// iterations can be made; calibrated counts never go below 100000.
+func WithIterations(n int) Option {
+	return func(o *options) { o.iters = n }
+}
+
+// WithPRF selects the hash PBKDF2 uses, PRFSHA256 (the default) or
+// PRFSHA512. The choice is recorded in DeriveInfo.KDF as KDFPBKDF2 or
+// KDFPBKDF2SHA512.
+func WithPRF(prf string) Option {
+	return func(o *options) { o.prf = prf }
+}
+
+// WithArgon2Params derives the key with Argon2id using params.
+func WithArgon2Params(params Argon2Params) Option {
+	return func(o *options) { o.argon2 = &params }
+}
+
+// WithScryptParams derives the key with scrypt using N, r and p.
+func WithScryptParams(N, r, p int) Option {
+	return func(o *options) { o.scrypt = &scryptParams{N: N, r: r, p: p} }
+}
+
+// WithKeySize sets the encryption key size in bytes: 16, 24 or 32 (the
+// default) for AES-128, AES-192 or AES-256. It is recorded in
+// EncryptionInfo.KeySize, while EncryptionInfo.Cipher keeps naming the mode,
+// such as CipherAES256GCM. XChaCha20-Poly1305 only supports 32.
+func WithKeySize(n int) Option {
+	return func(o *options) { o.keySizeOpt = n }
+}
+
+// WithCompression compresses the plaintext with algorithm, currently only
+// CompressionGzip, before encrypting it. Decryption decompresses only after
+// the container has been authenticated.
+func WithCompression(algorithm string) Option {
+	return func(o *options) { o.compression = algorithm }
+}
+
+// WithAAD binds the container to associated data, such as a file name or a
+// tenant ID, that is authenticated but not stored. The same data must be
+// passed to DecryptContainerWithAAD to open the container.
+func WithAAD(data []byte) Option {
+	return func(o *options) { o.aad = append([]byte(nil), data...) }
+}
+
+// WithExpiry makes the container fail to decrypt with ErrExpired from t on.
+// The expiry is authenticated, so it cannot be extended without the password.
+func WithExpiry(t time.Time) Option {
+	return func(o *options) { o.expiresAt = t.UTC().Format(time.RFC3339) }
+}
+
+// WithAnnotations attaches key/value labels, such as an owner, to the
+// container. They are stored in plaintext in Meta.Annotations, where
+// ParseHeader can read them, and are covered by the MAC.
+func WithAnnotations(annotations map[string]string) Option {
+	return func(o *options) {
+		o.annotations = make(map[string]string, len(annotations))
+		for k, v := range annotations {
+			o.annotations[k] = v
+		}
+	}
+}
+
+// WithKeyID records id in Meta.KeyID to say which password or key the
+// container was made for, so decryptors holding several can pick the right
+// one with ParseHeader before deriving anything. The ID is stored in
+// plaintext and covered by the MAC, so it must be a name, never key material;
+// IDs containing the password are rejected. It may be up to 256 bytes long.
+func WithKeyID(id string) Option {
+	return func(o *options) { o.keyID = id }
+}
+
+// WithDedupTag stores in Meta.DedupTag an HMAC-SHA256 of the plaintext under
+// key, so deduplication systems holding key can tell containers with the same
+// plaintext apart without decrypting them. Without key the tags reveal
+// nothing, and containers made under different keys never share a tag. key
+// must be at least 16 bytes and should not be derived from the password.
+func WithDedupTag(key []byte) Option {
+	return func(o *options) { o.dedupKey = append([]byte(nil), key...) }
+}
+
+// WithPadding pads the plaintext, after any compression, with random bytes
+// to a multiple of blockSize before encryption, so the ciphertext reveals
+// only how many blocks it spans rather than its exact length. The real
+// length is encrypted and authenticated along with the data, so it cannot be
+// combined with WithPlaintextLen. blockSize must be between 1 and 1MiB.
+func WithPadding(blockSize int) Option {
+	return func(o *options) { o.padding = blockSize }
+}
+
+// WithPlaintextLen records the length of the plaintext before compression in
+// Meta.PlaintextLen, so decryptors can size buffers from the header and
+// decryption fails if the plaintext does not match it. The length is
+// authenticated but readable without the password.
+func WithPlaintextLen() Option {
+	return func(o *options) { o.plaintextLen = true }
+}
+
+// WithMACHash selects the hash of the HMAC that authenticates the container,
+// MACHashSHA256 (the default), MACHashSHA384 or MACHashSHA512. It is recorded
+// in Meta.MACHash and applies only to ciphers without built-in
+// authentication, AES-256-CTR and AES-256-CBC.
+func WithMACHash(name string) Option {
+	return func(o *options) { o.macHash = name }
+}
+
+// WithRejectNestedContainer makes CreateContainer and the functions built on
+// it fail with ErrNestedContainer when the plaintext is itself a container,
+// as JSON, in the binary format or PEM-armored, to catch data encrypted
+// twice by mistake. The check is a best-effort heuristic and off by default.
+func WithRejectNestedContainer() Option {
+	return func(o *options) { o.rejectNested = true }
+}
+
+// WithExternalCiphertext stores the ciphertext in *dst instead of the
+// container, for split-storage designs that keep the bytes apart from the
+// JSON. The container records that its ciphertext is external and leaves
+// EncryptedData empty; decrypt it with DecryptContainerExternal. The
+// ciphertext is still authenticated, so it cannot be swapped unnoticed. A
+// nil dst is ignored.
+func WithExternalCiphertext(dst *[]byte) Option {
+	return func(o *options) {
+		if dst != nil {
+			o.external = dst
+		}
+	}
+}
+
+// WithEnvelope encrypts the data under a random content key and stores that
+// key wrapped by the password in Container.WrappedKeys, so RekeyContainer
+// can later change the password without re-encrypting the data.
+func WithEnvelope() Option {
+	return func(o *options) { o.envelope = true }
+}
+
+// WithEncoding selects how the salt, IV, ciphertext and MAC are written,
+// EncodingHex (the default) or the more compact EncodingBase64.
+func WithEncoding(enc Encoding) Option {
+	return func(o *options) { o.encoding = enc }
+}
+
+// WithIndent makes CreateContainer pretty-print the container JSON as
+// json.MarshalIndent does with prefix and indent, for containers meant to be
+// read by eye. Compact JSON remains the default since it is smaller; either
+// form decrypts the same way.
+func WithIndent(prefix, indent string) Option {
+	return func(o *options) { o.indentPrefix, o.indent = prefix, indent }
+}
+
+// WithClock makes the container record the time c reports as its CreatedAt
+// instead of the current time. A nil c is ignored.
+func WithClock(c Clock) Option {
+	return func(o *options) {
+		if c != nil {
+			o.clock = c
+		}
+	}
+}
+
+// WithProgress makes EncryptStream call fn after each chunk with the number
+// of plaintext bytes processed so far. It is not called after an error, and a
+// nil fn is ignored.
+func WithProgress(fn func(bytesProcessed int64)) Option {
+	return func(o *options) { o.progress = fn }
+}
+
+// Observer receives the duration and outcome of each encryption and
+// decryption, so services can record metrics without this package depending
+// on a metrics library. Its methods may be called concurrently.
+type Observer interface {
+	ObserveEncrypt(duration time.Duration, err error)
+	ObserveDecrypt(duration time.Duration, err error)
+}
+
+// WithObserver makes CreateContainer, CreateContainerWithKey, EncryptStream
+// and the functions built on them call obs.ObserveEncrypt once per container
+// or stream, timing the key derivation and encryption.
+func WithObserver(obs Observer) Option {
+	return func(o *options) { o.observer = obs }
+}
+
+// WithLogger makes CreateContainer and the functions built on it log the
+// cipher, KDF and cost parameters of each container they create to logger,
+// as an audit trail. Secrets and the data derived from them, such as the
+// salt, IV and ciphertext, are never logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// WithMinPasswordEntropy rejects passwords whose PasswordEntropy is below
+// bits with ErrWeakPassword. The check is off by default.
+func WithMinPasswordEntropy(bits float64) Option {
+	return func(o *options) { o.minEntropy = bits }
+}
+
+// WithAllowWeakParams accepts Argon2id and scrypt parameters below the
+// recommended minimums, which are otherwise rejected with ErrWeakKDFParams.
+// It is meant for tests and constrained devices.
+func WithAllowWeakParams() Option {
+	return func(o *options) { o.allowWeak = true }
+}
+
+// WithSaltLen sets the length of the random KDF salt in bytes, between 8 and
+// 64. The default is 16. The length is implicit in the stored salt, so
+// decryption needs no matching option.
+func WithSaltLen(n int) Option {
+	return func(o *options) { o.saltLen = n }
+}
+
+// WithRandReader reads the salt and IV from r instead of crypto/rand. It is
+// meant for deterministic tests; r must be cryptographically secure otherwise.
+func WithRandReader(r io.Reader) Option {
+	return func(o *options) { o.rand = r }
+}
+
+// keySize returns the encryption key size to use.
+func (o *options) keySize() int {
+	if o.keySizeOpt == 0 {
+		return keyLen
+	}
+	return o.keySizeOpt
+}
+
+func newOptions(opts []Option) (*options, error) {
+	o := &options{
+		cipher:  CipherAES256CTR,
+		saltLen: saltLen,
+		rand:    rand.Reader,
+		clock:   SystemClock,
+		ctx:     context.Background(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if err := o.resolve(); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// resolve validates o and fills in o.derive.
+func (o *options) resolve() error {
+	if _, err := ivSize(o.cipher); err != nil {
+		return err
+	}
+	if o.keySizeOpt != 0 {
+		if !validKeySize(o.keySizeOpt) {
+			return fmt.Errorf("invalid key size %d: must be 16, 24 or 32", o.keySizeOpt)
+		}
+		if o.cipher == CipherXChaCha20Poly1305 && o.keySizeOpt != keyLen {
+			return fmt.Errorf("%s requires a %d-byte key", o.cipher, keyLen)
+		}
+	}
+	if o.compression != "" && o.compression != CompressionGzip {
+		return errors.New("unsupported compression: " + o.compression)
+	}
+	if !o.encoding.valid() {
+		return fmt.Errorf("unsupported encoding: %q", o.encoding)
+	}
+	if o.saltLen < minSaltLen || o.saltLen > maxSaltLen {
+		return fmt.Errorf("salt length %d is outside %d to %d bytes", o.saltLen, minSaltLen, maxSaltLen)
+	}
+	if o.rand == nil {
+		return errors.New("random reader must not be nil")
+	}
+	if len(o.keyID) > maxKeyIDLen {
+		return fmt.Errorf("key ID is %d bytes, more than %d", len(o.keyID), maxKeyIDLen)
+	}
+	if _, err := macHash(o.macHash); err != nil {
+		return err
+	}
+	if o.macHash == MACHashSHA256 {
+		// The default is left unrecorded.
+		o.macHash = ""
+	}
+	if o.macHash != "" && !usesHMAC(o.cipher) {
+		return fmt.Errorf("%s has no separate MAC to set a hash for", o.cipher)
+	}
+	if o.padding < 0 || o.padding > maxPadding {
+		return fmt.Errorf("padding block size %d is outside 1 to %d", o.padding, maxPadding)
+	}
+	if o.plaintextLen && o.padding != 0 {
+		return errors.New("WithPlaintextLen would record the length WithPadding hides")
+	}
+	if o.dedupKey != nil && len(o.dedupKey) < minDedupKeyLen {
+		return fmt.Errorf("dedup tag key is %d bytes, less than %d", len(o.dedupKey), minDedupKeyLen)
+	}
+
+	// Work out which KDF the parameter options imply.
+	var implied []string
+	if o.iters != 0 {
+		implied = append(implied, KDFPBKDF2)
+	}
+	if o.argon2 != nil {
+		implied = append(implied, KDFArgon2id)
+	}
+	if o.scrypt != nil {
+		implied = append(implied, KDFScrypt)
+	}
+	if len(implied) > 1 {
+		return fmt.Errorf("conflicting KDF options: %v", implied)
+	}
+	kdf := o.kdf
+	prf := o.prf
+	if kdf == KDFPBKDF2SHA512 {
+		// WithKDF(KDFPBKDF2SHA512) is shorthand for WithPRF(PRFSHA512).
+		if prf != "" && prf != PRFSHA512 {
+			return fmt.Errorf("PRF %s given with KDF %s", prf, kdf)
+		}
+		kdf, prf = KDFPBKDF2, PRFSHA512
+	}
+	if len(implied) == 1 {
+		if kdf != "" && kdf != implied[0] {
+			return fmt.Errorf("%s parameters given with KDF %s", implied[0], kdf)
+		}
+		kdf = implied[0]
+	}
+
+	if prf != "" && kdf != "" && kdf != KDFPBKDF2 {
+		return fmt.Errorf("PRF %s given with KDF %s", prf, kdf)
+	}
+
+	switch kdf {
+	case "", KDFPBKDF2:
+		if o.iters < 0 {
+			return fmt.Errorf("iteration count %d is not positive", o.iters)
+		}
+		if o.iters > MaxIterations {
+			return fmt.Errorf("%w: %d exceeds %d", ErrIterationsTooHigh, o.iters, MaxIterations)
+		}
+		name := KDFPBKDF2
+		switch prf {
+		case "", PRFSHA256:
+		case PRFSHA512:
+			name = KDFPBKDF2SHA512
+		default:
+			return fmt.Errorf("%w: PRF %q", ErrUnsupportedAlgorithm, prf)
+		}
+		o.derive = Derive{KDF: name, Iters: o.iters}
+	case KDFArgon2id:
+		p := DefaultArgon2Params
+		if o.argon2 != nil {
+			p = *o.argon2
+		}
+		if err := p.validate(); err != nil {
+			return err
+		}
+		if err := checkArgon2Cost(p); err != nil {
+			return err
+		}
+		if !o.allowWeak && (p.Memory < minArgon2Memory || p.Time < minArgon2Time) {
+			return fmt.Errorf("%w: argon2id with %d KiB and %d passes, need at least %d KiB and %d passes",
+				ErrWeakKDFParams, p.Memory, p.Time, minArgon2Memory, minArgon2Time)
+		}
+		o.derive = Derive{KDF: KDFArgon2id, Memory: p.Memory, Time: p.Time, Threads: p.Threads}
+	case KDFScrypt:
+		p := scryptParams{N: DefaultScryptN, r: DefaultScryptR, p: DefaultScryptP}
+		if o.scrypt != nil {
+			p = *o.scrypt
+		}
+		if err := validateScrypt(p.N, p.r, p.p); err != nil {
+			return err
+		}
+		if err := checkScryptCost(p.N, p.r, p.p); err != nil {
+			return err
+		}
+		if !o.allowWeak && p.N < minScryptN {
+			return fmt.Errorf("%w: scrypt with N=%d, need at least N=%d", ErrWeakKDFParams, p.N, minScryptN)
+		}
+		o.derive = Derive{KDF: KDFScrypt, N: p.N, R: p.r, P: p.p}
+	default:
+		return fmt.Errorf("%w: KDF %q", ErrUnsupportedAlgorithm, kdf)
+	}
+	return nil
+}
+
+// DecryptOption configures DecryptContainer, DecryptContainerBytes and
+// Decrypt.
+type DecryptOption func(*decryptParams)
+
+// WithStrictParsing rejects containers that contain JSON fields this package
+// does not know, or data after the container, with ErrMalformedContainer.
+// By default such fields are ignored.
+func WithStrictParsing() DecryptOption {
+	return func(p *decryptParams) { p.strict = true }
+}
+
+// WithDecryptObserver makes decryption call obs.ObserveDecrypt once per
+// container, stream or range, timing the key derivation and decryption. It
+// is the decryption counterpart of WithObserver.
+func WithDecryptObserver(obs Observer) DecryptOption {
+	return func(p *decryptParams) { p.observer = obs }
+}
+
+// WithDecryptLogger makes decryption log the parameters of each container it
+// opens to logger, along with the error if it fails. It is the decryption
+// counterpart of WithLogger.
+func WithDecryptLogger(logger *slog.Logger) DecryptOption {
+	return func(p *decryptParams) { p.logger = logger }
+}
+
+// WithDecryptProgress makes DecryptStream call fn after each chunk with the
+// number of plaintext bytes written so far. It is the DecryptStream
+// counterpart of WithProgress.
+func WithDecryptProgress(fn func(bytesProcessed int64)) DecryptOption {
+	return func(p *decryptParams) { p.progress = fn }
+}
+
+// WithDecryptClock makes decryption check expiry against the time c reports
+// instead of the current time. It is the decryption counterpart of WithClock.
+// A nil c is ignored.
+func WithDecryptClock(c Clock) DecryptOption {
+	return func(p *decryptParams) {
+		if c != nil {
+			p.clock = c
+		}
+	}
+}
+
+// WithValidateUTF8 makes the decryption functions that return a string fail
+// with ErrInvalidUTF8 when the plaintext is not valid UTF-8, instead of
+// returning its bytes as they are. DecryptContainerBytes ignores it.
+func WithValidateUTF8() DecryptOption {
+	return func(p *decryptParams) { p.validateUTF8 = true }
+}
+
+// WithLegacyV10 lets decryption read containers in the legacy v1.0 format of
+// the first release, which are rejected with ErrUnsupportedVersion by
+// default. Their HMAC field is an unkeyed SHA-256 of the plaintext, which
+// catches a wrong password and accidental corruption but not deliberate
+// tampering by someone who knows the plaintext, so only use it for
+// containers from a trusted source, ideally to upgrade them.
+func WithLegacyV10() DecryptOption {
+	return func(p *decryptParams) { p.legacy = true }
+}
+
+// WithCollectErrors makes DecryptMulti decrypt every entry instead of
+// stopping at the first one that fails.
+func WithCollectErrors() DecryptOption {
+	return func(p *decryptParams) { p.collect = true }
+}
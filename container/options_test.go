@@ -0,0 +1,252 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	mathrnd "math/rand"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// TestCreateContainerOptions checks if cipher, KDF and salt options are reflected in the container and it decrypts.
+func TestCreateContainerOptions(t *testing.T) {
+	plaintext := "hello options"
+	password := "password123"
+
+	containerJSON, err := CreateContainer(plaintext, password,
+		WithCipher(CipherAES256GCM),
+		WithKDF(KDFArgon2id),
+		WithArgon2Params(testArgon2Params),
+		WithSaltLen(16),
+	)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if container.EncryptionInfo.Cipher != CipherAES256GCM {
+		t.Errorf("Expected cipher %q, got %q", CipherAES256GCM, container.EncryptionInfo.Cipher)
+	}
+	if container.DeriveInfo.KDF != KDFArgon2id || container.DeriveInfo.Memory != testArgon2Params.Memory {
+		t.Errorf("Unexpected DeriveInfo: %+v", container.DeriveInfo)
+	}
+	if len(container.DeriveInfo.Salt) != 32 {
+		t.Errorf("Expected a 16-byte salt, got %q", container.DeriveInfo.Salt)
+	}
+
+	decryptedText, err := Decrypt(containerJSON, password)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected decrypted text to be '%s', got '%s'", plaintext, decryptedText)
+	}
+}
+
+// TestOptionsLastWins checks if a repeated option overrides the earlier one.
+func TestOptionsLastWins(t *testing.T) {
+	o, err := newOptions([]Option{WithCipher(CipherAES256GCM), WithCipher(CipherXChaCha20Poly1305), WithIterations(200000), WithIterations(300000)})
+	if err != nil {
+		t.Fatalf("Error resolving options: %v", err)
+	}
+	if o.cipher != CipherXChaCha20Poly1305 {
+		t.Errorf("Expected cipher %q, got %q", CipherXChaCha20Poly1305, o.cipher)
+	}
+	if o.derive.Iters != 300000 {
+		t.Errorf("Expected 300000 iterations, got %d", o.derive.Iters)
+	}
+}
+
+// TestOptionsValidation checks if invalid or conflicting options are rejected.
+func TestOptionsValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"unknown cipher", []Option{WithCipher("rot13")}},
+		{"unknown KDF", []Option{WithKDF("md5")}},
+		{"iterations with scrypt", []Option{WithKDF(KDFScrypt), WithIterations(200000)}},
+		{"argon2 params with pbkdf2", []Option{WithArgon2Params(testArgon2Params), WithKDF(KDFPBKDF2)}},
+		{"argon2 and scrypt params", []Option{WithArgon2Params(testArgon2Params), WithScryptParams(16384, 8, 1)}},
+		{"negative iterations", []Option{WithIterations(-1)}},
+		{"zero salt length", []Option{WithSaltLen(0)}},
+		{"short salt", []Option{WithSaltLen(4)}},
+		{"long salt", []Option{WithSaltLen(65)}},
+		{"nil reader", []Option{WithRandReader(nil)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := CreateContainer("x", "password123", tt.opts...); err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+// TestCreateContainerDeterministic checks if a fixed-seed random reader and iteration count give byte-for-byte identical containers.
+func TestCreateContainerDeterministic(t *testing.T) {
+	create := func() string {
+		containerJSON, err := CreateContainer("hello world", "password123",
+			WithRandReader(mathrnd.New(mathrnd.NewSource(42))),
+			WithIterations(minIterations),
+			WithClock(fixedClock(time.Unix(1700000000, 0))),
+		)
+		if err != nil {
+			t.Fatalf("Error creating container: %v", err)
+		}
+		return containerJSON
+	}
+
+	first, second := create(), create()
+	if first != second {
+		t.Errorf("Expected identical containers, got:\n%s\n%s", first, second)
+	}
+
+	decryptedText, err := DecryptContainer(first, "password123")
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != "hello world" {
+		t.Errorf("Expected decrypted text to be 'hello world', got '%s'", decryptedText)
+	}
+}
+
+// TestCreateContainerRandReaderError checks if an error from the random reader is propagated for both the salt and the IV.
+func TestCreateContainerRandReaderError(t *testing.T) {
+	errBoom := errors.New("boom")
+
+	for _, n := range []int{0, 5, saltLen, saltLen + 3} {
+		r := io.MultiReader(bytes.NewReader(make([]byte, n)), iotest.ErrReader(errBoom))
+		_, err := CreateContainer("hello world", "password123", WithRandReader(r), WithIterations(minIterations))
+		if !errors.Is(err, errBoom) {
+			t.Errorf("After %d bytes: expected the reader error, got: %v", n, err)
+		}
+	}
+}
+
+// TestWithKeySize checks if every AES key size round-trips and is recorded in EncryptionInfo.
+func TestWithKeySize(t *testing.T) {
+	password := "password123"
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		for _, size := range []int{16, 24, 32} {
+			containerJSON := mustCreate(t, "hello key size", password, WithCipher(cipherName), WithKeySize(size), WithIterations(minIterations))
+
+			header, err := ParseHeader(containerJSON)
+			if err != nil {
+				t.Fatalf("Error parsing header: %v", err)
+			}
+			if header.EncryptionInfo.KeySize != size {
+				t.Errorf("%s/%d: expected KeySize %d, got %d", cipherName, size, size, header.EncryptionInfo.KeySize)
+			}
+
+			decryptedText, err := Decrypt(containerJSON, password)
+			if err != nil {
+				t.Fatalf("%s/%d: error decrypting container: %v", cipherName, size, err)
+			}
+			if decryptedText != "hello key size" {
+				t.Errorf("%s/%d: expected 'hello key size', got '%s'", cipherName, size, decryptedText)
+			}
+		}
+	}
+}
+
+// TestKeySizeMetadata checks if a 16-byte key is recorded as KeySize 16 next to the mode's cipher name, and editing KeySize fails authentication.
+func TestKeySizeMetadata(t *testing.T) {
+	password := "password123"
+	containerJSON, info, err := CreateContainerWithInfo("hello key size", password, WithCipher(CipherAES256GCM), WithKeySize(16), WithIterations(minIterations))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	if e := container.EncryptionInfo; e.Cipher != CipherAES256GCM || e.KeySize != 16 {
+		t.Errorf("Expected Cipher %q with KeySize 16, got %+v", CipherAES256GCM, e)
+	}
+	if info.KeySize != 16 {
+		t.Errorf("Expected CreateInfo.KeySize 16, got %d", info.KeySize)
+	}
+
+	container.EncryptionInfo.KeySize = 32
+	if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for an edited KeySize, got: %v", err)
+	}
+}
+
+// TestWithKeySizeInvalid checks if unsupported key sizes are rejected.
+func TestWithKeySizeInvalid(t *testing.T) {
+	tests := [][]Option{
+		{WithKeySize(20)},
+		{WithKeySize(64)},
+		{WithKeySize(16), WithCipher(CipherXChaCha20Poly1305)},
+	}
+	for _, opts := range tests {
+		if _, err := CreateContainer("x", "password123", opts...); err == nil {
+			t.Errorf("Expected an error for options %v", opts)
+		}
+	}
+}
+
+// TestSaltLen checks if the salt defaults to 16 bytes, honours WithSaltLen within its bounds and still decrypts.
+func TestSaltLen(t *testing.T) {
+	password := "password123"
+	for _, tt := range []struct {
+		opts []Option
+		want int
+	}{
+		{nil, 16},
+		{[]Option{WithSaltLen(minSaltLen)}, 8},
+		{[]Option{WithSaltLen(maxSaltLen)}, 64},
+	} {
+		containerJSON := mustCreate(t, "hello salt", password, append(tt.opts, WithIterations(minIterations))...)
+		header, err := ParseHeader(containerJSON)
+		if err != nil {
+			t.Fatalf("Error parsing header: %v", err)
+		}
+		if got := len(header.DeriveInfo.Salt) / 2; got != tt.want {
+			t.Errorf("Expected a %d-byte salt, got %d", tt.want, got)
+		}
+		if _, err := DecryptContainer(containerJSON, password); err != nil {
+			t.Errorf("Error decrypting container with a %d-byte salt: %v", tt.want, err)
+		}
+	}
+
+	if _, err := CreateContainer("hello salt", password, WithSaltLen(4)); err == nil {
+		t.Errorf("Expected an error for a 4-byte salt")
+	}
+}
+
+// TestWithIndent checks if an indented container is pretty-printed, keeps a valid checksum and still decrypts.
+func TestWithIndent(t *testing.T) {
+	password := "password123"
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		containerJSON := mustCreate(t, "hello indent", password, WithCipher(cipherName), WithIndent("", "  "), WithIterations(minIterations))
+		if !strings.Contains(containerJSON, "\n  \"") {
+			t.Errorf("%s: expected indented JSON, got %s", cipherName, containerJSON)
+		}
+		if err := VerifyChecksum(containerJSON); err != nil {
+			t.Errorf("%s: expected a valid checksum, got: %v", cipherName, err)
+		}
+		decryptedText, err := Decrypt(containerJSON, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting indented container: %v", cipherName, err)
+		}
+		if decryptedText != "hello indent" {
+			t.Errorf("%s: expected %q, got %q", cipherName, "hello indent", decryptedText)
+		}
+	}
+
+	if compact := mustCreate(t, "hello indent", password, WithIterations(minIterations)); strings.Contains(compact, "\n") {
+		t.Errorf("Expected compact JSON by default, got %s", compact)
+	}
+}
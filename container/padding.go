@@ -0,0 +1,46 @@
+package container
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxPadding is the largest block size WithPadding accepts.
+const maxPadding = 1 << 20
+
+// paddingHeaderLen is the size of the length prefix of a padded plaintext.
+const paddingHeaderLen = 8
+
+// paddedLen returns the length pad produces for n bytes and blockSize.
+func paddedLen(n, blockSize int) int {
+	n += paddingHeaderLen
+	return n + (blockSize-n%blockSize)%blockSize
+}
+
+// pad returns plaintext prefixed with its length as a big-endian uint64 and
+// followed by random bytes from r up to a multiple of blockSize. The length
+// is encrypted along with the data, so the header reveals no more than the
+// number of blocks.
+func pad(plaintext []byte, blockSize int, r io.Reader) ([]byte, error) {
+	out := make([]byte, paddedLen(len(plaintext), blockSize))
+	binary.BigEndian.PutUint64(out, uint64(len(plaintext)))
+	n := copy(out[paddingHeaderLen:], plaintext)
+	if _, err := io.ReadFull(r, out[paddingHeaderLen+n:]); err != nil {
+		zeroize(out)
+		return nil, fmt.Errorf("generating padding: %w", err)
+	}
+	return out, nil
+}
+
+// unpad returns a copy of the plaintext inside padded, as produced by pad.
+func unpad(padded []byte) ([]byte, error) {
+	if len(padded) < paddingHeaderLen {
+		return nil, fmt.Errorf("%w: padded plaintext is too short", ErrMalformedContainer)
+	}
+	n := binary.BigEndian.Uint64(padded)
+	if n > uint64(len(padded)-paddingHeaderLen) {
+		return nil, fmt.Errorf("%w: padded length %d exceeds the data", ErrMalformedContainer, n)
+	}
+	return append([]byte(nil), padded[paddingHeaderLen:paddingHeaderLen+int(n)]...), nil
+}
@@ -0,0 +1,77 @@
+package container
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestWithPadding checks if messages of different lengths give equal-length ciphertexts under the same padding block size and still decrypt.
+func TestWithPadding(t *testing.T) {
+	password := "password123"
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM, CipherXChaCha20Poly1305} {
+		lengths := map[int]bool{}
+		for _, plaintext := range []string{"hi", "hello there, padding"} {
+			containerJSON := mustCreate(t, plaintext, password, WithCipher(cipherName), WithPadding(64), WithIterations(minIterations))
+			var container Container
+			if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			if container.ContainerMeta.Padding != 64 || container.ContainerMeta.PlaintextLen != 0 {
+				t.Errorf("%s: expected Padding 64 and no PlaintextLen, got %+v", cipherName, container.ContainerMeta)
+			}
+			lengths[len(container.ContainedData.EncryptedData)] = true
+
+			decryptedText, err := Decrypt(containerJSON, password)
+			if err != nil {
+				t.Fatalf("%s: error decrypting padded container: %v", cipherName, err)
+			}
+			if decryptedText != plaintext {
+				t.Errorf("%s: expected %q, got %q", cipherName, plaintext, decryptedText)
+			}
+			if size := EstimateSize(len(plaintext), WithCipher(cipherName), WithPadding(64), WithIterations(minIterations)); size != len(containerJSON) {
+				t.Errorf("%s: expected EstimateSize %d, got %d", cipherName, len(containerJSON), size)
+			}
+		}
+		if len(lengths) != 1 {
+			t.Errorf("%s: expected equal ciphertext lengths, got %v", cipherName, lengths)
+		}
+	}
+
+	if _, err := CreateContainer("x", password, WithPadding(maxPadding+1)); err == nil {
+		t.Errorf("Expected an error for an oversized padding block")
+	}
+}
+
+// TestPaddingAuthenticated checks if editing the padding block size makes decryption fail.
+func TestPaddingAuthenticated(t *testing.T) {
+	password := "password123"
+	containerJSON := mustCreate(t, "hello padding", password, WithPadding(32), WithIterations(minIterations))
+	var container Container
+	if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.Padding = 16
+	if _, err := Decrypt(mustMarshal(t, &container), password); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for an edited padding block size, got: %v", err)
+	}
+}
+
+// TestUnpad checks if a length prefix beyond the padded data is rejected.
+func TestUnpad(t *testing.T) {
+	padded, err := pad([]byte("hello"), 16, bytes.NewReader(make([]byte, 16)))
+	if err != nil {
+		t.Fatalf("Error padding: %v", err)
+	}
+	if len(padded) != 16 {
+		t.Errorf("Expected 16 padded bytes, got %d", len(padded))
+	}
+	padded[7] = 9
+	if _, err := unpad(padded); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer, got: %v", err)
+	}
+	if _, err := unpad(padded[:4]); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for short data, got: %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// PasswordEntropy estimates the strength of password in bits. It multiplies
+// the number of characters by the bits per character of the character
+// classes used (lower case, upper case, digits, ASCII symbols and anything
+// else), but counts repeated characters and runs such as "abc" or "321" as a
+// single character. It is a rough guide that cannot detect dictionary words,
+// so it overestimates passwords like "Tr0ub4dor".
+func PasswordEntropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	length := 0
+	prev := rune(-1)
+	step := rune(0)
+	for _, r := range password {
+		switch {
+		case 'a' <= r && r <= 'z':
+			lower = true
+		case 'A' <= r && r <= 'Z':
+			upper = true
+		case '0' <= r && r <= '9':
+			digit = true
+		case r <= unicode.MaxASCII && unicode.IsPrint(r):
+			symbol = true
+		default:
+			other = true
+		}
+
+		d := r - prev
+		switch {
+		case d == 0, (d == 1 || d == -1) && d == step:
+			// Repeats and the third character of a run add nothing.
+		default:
+			length++
+		}
+		if d == 1 || d == -1 {
+			step = d
+		} else {
+			step = 0
+		}
+		prev = r
+	}
+
+	charset := 0
+	for _, c := range []struct {
+		used bool
+		size int
+	}{{lower, 26}, {upper, 26}, {digit, 10}, {symbol, 33}, {other, 100}} {
+		if c.used {
+			charset += c.size
+		}
+	}
+	if charset == 0 {
+		return 0
+	}
+	return float64(length) * math.Log2(float64(charset))
+}
+
+// checkPassword returns ErrWeakPassword if password falls below the
+// threshold set with WithMinPasswordEntropy. It also rejects a key ID that
+// contains the password, since the key ID is stored in plaintext.
+func (o *options) checkPassword(password string) error {
+	if o.keyID != "" && password != "" && strings.Contains(o.keyID, password) {
+		return errors.New("key ID must not contain the password")
+	}
+	if o.minEntropy <= 0 {
+		return nil
+	}
+	if bits := PasswordEntropy(password); bits < o.minEntropy {
+		return fmt.Errorf("%w: about %.0f bits, need %.0f", ErrWeakPassword, bits, o.minEntropy)
+	}
+	return nil
+}
+
+// CheckPassword reports whether password opens containerJSON, running the
+// same key derivation and authentication as decryption, and how long the
+// derivation took so callers can tune rate limiting. A wrong password gives
+// ok false and a nil error; err is only set when the container cannot be
+// checked at all, for example because it is malformed or uses an unsupported
+// algorithm. A tampered container is indistinguishable from a wrong password,
+// as is a container bound with WithAAD, whose data CheckPassword cannot
+// supply. Expiry is not checked, as it says nothing about the password.
+func CheckPassword(containerJSON, password string) (ok bool, cost time.Duration, err error) {
+	p := decryptParams{deriveTime: &cost}
+	plaintext, err := decryptContainer([]byte(containerJSON), password, p)
+	switch {
+	case err == nil:
+		zeroize(plaintext)
+		return true, cost, nil
+	case errors.Is(err, ErrExpired):
+		return true, cost, nil
+	case errors.Is(err, ErrHMACMismatch):
+		return false, cost, nil
+	}
+	return false, cost, err
+}
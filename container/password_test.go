@@ -0,0 +1,81 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestPasswordEntropy checks if the estimate grows with length and character classes and discounts repeats and runs.
+func TestPasswordEntropy(t *testing.T) {
+	if got := PasswordEntropy(""); got != 0 {
+		t.Errorf("Expected 0 bits for an empty password, got %f", got)
+	}
+	if PasswordEntropy("aaaaaaaaaaaa") >= PasswordEntropy("ab") {
+		t.Errorf("Expected a repeated character to count once")
+	}
+	if PasswordEntropy("abcdefghijkl") >= PasswordEntropy("aqzk") {
+		t.Errorf("Expected a run to count as little as a few characters")
+	}
+	if PasswordEntropy("kdwpqzmx") >= PasswordEntropy("kdwPqz4!") {
+		t.Errorf("Expected more character classes to give more entropy")
+	}
+}
+
+// TestMinPasswordEntropy checks if weak passwords are rejected at a 60-bit threshold while a long passphrase passes, and the check is off by default.
+func TestMinPasswordEntropy(t *testing.T) {
+	opts := []Option{WithMinPasswordEntropy(60), WithIterations(minIterations)}
+
+	if _, err := CreateContainer("hello", "password123", opts...); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("Expected ErrWeakPassword, got %v", err)
+	}
+	if _, err := CreateContainerMulti("hello", []string{"correct horse battery staple", "password123"}, opts...); !errors.Is(err, ErrWeakPassword) {
+		t.Errorf("Expected ErrWeakPassword for a weak recipient, got %v", err)
+	}
+	if _, err := CreateContainer("hello", "correct horse battery staple", opts...); err != nil {
+		t.Errorf("Expected a long passphrase to pass, got %v", err)
+	}
+	if _, err := CreateContainer("hello", "password123", WithIterations(minIterations)); err != nil {
+		t.Errorf("Expected no check by default, got %v", err)
+	}
+}
+
+// TestCheckPassword checks if CheckPassword tells a correct, a wrong password and a malformed container apart and reports the derivation cost.
+func TestCheckPassword(t *testing.T) {
+	password := "password123"
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM} {
+		containerJSON := mustCreate(t, "hello check", password, WithCipher(cipherName), WithIterations(minIterations))
+
+		ok, cost, err := CheckPassword(containerJSON, password)
+		if err != nil || !ok {
+			t.Errorf("%s: expected the correct password to match, got ok=%v err=%v", cipherName, ok, err)
+		}
+		if cost <= 0 {
+			t.Errorf("%s: expected a positive derivation cost, got %v", cipherName, cost)
+		}
+
+		ok, cost, err = CheckPassword(containerJSON, "wrongpassword")
+		if err != nil || ok {
+			t.Errorf("%s: expected the wrong password not to match, got ok=%v err=%v", cipherName, ok, err)
+		}
+		if cost <= 0 {
+			t.Errorf("%s: expected a positive derivation cost for the wrong password, got %v", cipherName, cost)
+		}
+	}
+
+	ok, _, err := CheckPassword("{not json", password)
+	if ok || !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for malformed JSON, got ok=%v err=%v", ok, err)
+	}
+
+	// A downgraded container is inconsistent, not a wrong password.
+	var container Container
+	if err := json.Unmarshal([]byte(mustCreate(t, "hello check", password, WithCipher(CipherAES256GCM), WithIterations(minIterations))), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	container.ContainerMeta.Version = versionCTR
+	ok, _, err = CheckPassword(mustMarshal(t, &container), password)
+	if ok || !errors.Is(err, ErrUnsupportedVersion) || errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrUnsupportedVersion for a downgraded container, got ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,58 @@
+package container
+
+import (
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// pemType is the type line of an ASCII-armored container.
+const pemType = "GO-CRYPTO-CONTAINER"
+
+// EncodePEM armors a container as a PEM block of type GO-CRYPTO-CONTAINER
+// holding its binary form, for pasting into emails or configuration files.
+// The block's Version and Cipher headers repeat the container's for quick
+// inspection; they are not authenticated.
+func EncodePEM(containerJSON string) (string, error) {
+	container, err := unmarshalContainer([]byte(containerJSON), false)
+	if err != nil {
+		return "", err
+	}
+	data, err := container.MarshalBinary()
+	if err != nil {
+		return "", err
+	}
+	headers := map[string]string{"Version": container.ContainerMeta.Version}
+	if container.EncryptionInfo.Cipher != "" {
+		headers["Cipher"] = container.EncryptionInfo.Cipher
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: pemType, Headers: headers, Bytes: data})), nil
+}
+
+// DecodePEM reverses EncodePEM, returning the container as JSON. It returns
+// ErrMalformedContainer if s is not a single GO-CRYPTO-CONTAINER block or
+// its Version header disagrees with the container.
+func DecodePEM(s string) (string, error) {
+	block, rest := pem.Decode([]byte(s))
+	if block == nil {
+		return "", fmt.Errorf("%w: no PEM block found", ErrMalformedContainer)
+	}
+	if block.Type != pemType {
+		return "", fmt.Errorf("%w: PEM block type %q, want %q", ErrMalformedContainer, block.Type, pemType)
+	}
+	if strings.TrimSpace(string(rest)) != "" {
+		return "", fmt.Errorf("%w: data after PEM block", ErrMalformedContainer)
+	}
+	var container Container
+	if err := container.UnmarshalBinary(block.Bytes); err != nil {
+		return "", err
+	}
+	if v, ok := block.Headers["Version"]; ok && v != container.ContainerMeta.Version {
+		return "", fmt.Errorf("%w: PEM Version header %q does not match container version %q", ErrMalformedContainer, v, container.ContainerMeta.Version)
+	}
+	b, err := container.marshal()
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,69 @@
+package container
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestPEMRoundTrip checks if containers survive EncodePEM and DecodePEM and still decrypt.
+func TestPEMRoundTrip(t *testing.T) {
+	password := "password123"
+	for name, opts := range map[string][]Option{
+		"ctr":      {WithIterations(minIterations)},
+		"gcm":      {WithCipher(CipherAES256GCM), WithIterations(minIterations)},
+		"envelope": {WithEnvelope(), WithIterations(minIterations), WithAnnotations(map[string]string{"owner": "alice"})},
+	} {
+		containerJSON := mustCreate(t, "hello pem", password, opts...)
+		armored, err := EncodePEM(containerJSON)
+		if err != nil {
+			t.Fatalf("%s: error encoding PEM: %v", name, err)
+		}
+		if !strings.HasPrefix(armored, "-----BEGIN GO-CRYPTO-CONTAINER-----\n") {
+			t.Errorf("%s: unexpected PEM type line: %q", name, strings.SplitN(armored, "\n", 2)[0])
+		}
+		header, err := ParseHeader(containerJSON)
+		if err != nil {
+			t.Fatalf("%s: error parsing header: %v", name, err)
+		}
+		if !strings.Contains(armored, "Version: "+header.ContainerMeta.Version+"\n") {
+			t.Errorf("%s: expected a Version header in %q", name, armored)
+		}
+
+		decoded, err := DecodePEM(armored)
+		if err != nil {
+			t.Fatalf("%s: error decoding PEM: %v", name, err)
+		}
+		decrypted, err := Decrypt(decoded, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting decoded container: %v", name, err)
+		}
+		if decrypted != "hello pem" {
+			t.Errorf("%s: expected %q, got %q", name, "hello pem", decrypted)
+		}
+	}
+}
+
+// TestDecodePEMRejects checks if PEM blocks of the wrong type, with a wrong Version header or with trailing data are rejected.
+func TestDecodePEMRejects(t *testing.T) {
+	armored, err := EncodePEM(mustCreate(t, "hello pem", "password123", WithIterations(minIterations)))
+	if err != nil {
+		t.Fatalf("Error encoding PEM: %v", err)
+	}
+
+	tests := map[string]string{
+		"wrong type":    strings.ReplaceAll(armored, "GO-CRYPTO-CONTAINER", "PRIVATE KEY"),
+		"wrong version": strings.Replace(armored, "Version: ", "Version: v9", 1),
+		"trailing data": armored + "junk",
+		"not PEM":       "not pem",
+	}
+	for name, s := range tests {
+		if _, err := DecodePEM(s); !errors.Is(err, ErrMalformedContainer) {
+			t.Errorf("%s: expected ErrMalformedContainer, got %v", name, err)
+		}
+	}
+
+	if _, err := EncodePEM("not json"); err == nil {
+		t.Errorf("Expected an error encoding an invalid container")
+	}
+}
@@ -0,0 +1,77 @@
+package container
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateContainerWithKey encrypts plaintext under key, which the caller
+// already holds, for example from an HSM or another KDF, instead of deriving
+// one from a password. The key must be as long as the cipher's key size, 32
+// bytes unless WithKeySize says otherwise. DeriveInfo.KDF is recorded as
+// KDFNone and no salt is stored. Options selecting or tuning a KDF, and
+// WithEnvelope, are rejected.
+func CreateContainerWithKey(plaintext string, key []byte, opts ...Option) (_ string, err error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	if o.observer != nil {
+		start := time.Now()
+		defer func() { o.observer.ObserveEncrypt(time.Since(start), err) }()
+	}
+	if o.kdf != "" || o.iters != 0 || o.argon2 != nil || o.scrypt != nil || o.prf != "" {
+		return "", errors.New("KDF options do not apply to a raw key")
+	}
+	if o.envelope {
+		return "", errors.New("a raw key cannot be combined with envelope encryption")
+	}
+	if len(key) != o.keySize() {
+		return "", fmt.Errorf("key is %d bytes, need %d", len(key), o.keySize())
+	}
+
+	ivLen, err := ivSize(o.cipher)
+	if err != nil {
+		return "", err
+	}
+	iv, err := readRandomBytes(o.rand, ivLen)
+	if err != nil {
+		return "", fmt.Errorf("generating IV: %w", err)
+	}
+	encKey, macKey, err := splitKey(key)
+	if err != nil {
+		return "", err
+	}
+	defer zeroize(encKey)
+	defer zeroize(macKey)
+
+	pt := []byte(plaintext)
+	defer zeroize(pt)
+	b, err := sealContainer(&Container{DeriveInfo: Derive{KDF: KDFNone}}, pt, nil, iv, encKey, macKey, o)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecryptContainerWithKey decrypts a container created with
+// CreateContainerWithKey. It returns ErrKeyTypeMismatch for containers
+// protected by a password.
+func DecryptContainerWithKey(containerJSON string, key []byte, opts ...DecryptOption) (string, error) {
+	p := newDecryptParams("", opts)
+	p.key = key
+	return decryptString(containerJSON, "", p)
+}
+
+// rawKeys checks that container was created with a raw key of keySize bytes
+// and splits key into encryption and MAC keys.
+func rawKeys(container *Container, key []byte, keySize int) (encKey, macKey []byte, err error) {
+	if container.DeriveInfo.KDF != KDFNone {
+		return nil, nil, fmt.Errorf("%w: the container needs a password, not a raw key", ErrKeyTypeMismatch)
+	}
+	if len(key) != keySize {
+		return nil, nil, fmt.Errorf("key is %d bytes, need %d", len(key), keySize)
+	}
+	return splitKey(key)
+}
@@ -0,0 +1,82 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestRawKeyRoundTrip checks if a container created with a raw key records KDFNone and decrypts with the same key only.
+func TestRawKeyRoundTrip(t *testing.T) {
+	plaintext := "hello raw key"
+	key := bytes.Repeat([]byte{0x42}, keyLen)
+
+	for _, cipherName := range []string{CipherAES256CTR, CipherAES256GCM, CipherXChaCha20Poly1305} {
+		containerJSON, err := CreateContainerWithKey(plaintext, key, WithCipher(cipherName))
+		if err != nil {
+			t.Fatalf("Error creating container: %v", err)
+		}
+		header, err := ParseHeader(containerJSON)
+		if err != nil {
+			t.Fatalf("Error parsing header: %v", err)
+		}
+		if header.DeriveInfo.KDF != KDFNone || header.DeriveInfo.Salt != "" {
+			t.Errorf("Expected KDF %q and no salt, got %+v", KDFNone, header.DeriveInfo)
+		}
+
+		decrypted, err := DecryptContainerWithKey(containerJSON, key)
+		if err != nil {
+			t.Fatalf("Error decrypting container: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("Expected %q, got %q", plaintext, decrypted)
+		}
+
+		otherKey := bytes.Repeat([]byte{0x43}, keyLen)
+		if _, err := DecryptContainerWithKey(containerJSON, otherKey); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("Expected ErrHMACMismatch for another key, got %v", err)
+		}
+	}
+}
+
+// TestRawKeyAndPasswordPathsAreSeparate checks if raw-key containers cannot be opened with a password and password containers cannot be opened with a raw key.
+func TestRawKeyAndPasswordPathsAreSeparate(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, keyLen)
+	rawJSON, err := CreateContainerWithKey("hello raw key", key)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	for _, decrypt := range []func(string, string, ...DecryptOption) (string, error){DecryptContainer, Decrypt} {
+		if _, err := decrypt(rawJSON, string(key)); !errors.Is(err, ErrKeyTypeMismatch) {
+			t.Errorf("Expected ErrKeyTypeMismatch opening a raw-key container with a password, got %v", err)
+		}
+	}
+
+	for _, opts := range [][]Option{nil, {WithEnvelope()}} {
+		passwordJSON := mustCreate(t, "hello password", "password123", append(opts, WithIterations(minIterations))...)
+		if _, err := DecryptContainerWithKey(passwordJSON, key); !errors.Is(err, ErrKeyTypeMismatch) {
+			t.Errorf("Expected ErrKeyTypeMismatch opening a password container with a raw key, got %v", err)
+		}
+	}
+}
+
+// TestRawKeyLength checks if keys that do not match the cipher's key size are rejected.
+func TestRawKeyLength(t *testing.T) {
+	if _, err := CreateContainerWithKey("x", make([]byte, 16)); err == nil {
+		t.Errorf("Expected an error for a 16-byte key with the default key size")
+	}
+	if _, err := CreateContainerWithKey("x", make([]byte, 16), WithKeySize(16)); err != nil {
+		t.Errorf("Expected a 16-byte key to work with WithKeySize(16), got %v", err)
+	}
+	if _, err := CreateContainerWithKey("x", make([]byte, keyLen), WithIterations(minIterations)); err == nil {
+		t.Errorf("Expected an error for KDF options with a raw key")
+	}
+
+	containerJSON, err := CreateContainerWithKey("x", make([]byte, keyLen))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	if _, err := DecryptContainerWithKey(containerJSON, make([]byte, 16)); err == nil {
+		t.Errorf("Expected an error for a short key")
+	}
+}
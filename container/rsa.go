@@ -0,0 +1,66 @@
+package container
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// KeyWrapRSAOAEP wraps the content key with RSA-OAEP using SHA-256.
+const KeyWrapRSAOAEP = "rsa-oaep-sha256"
+
+// CreateContainerRSA encrypts plaintext into an envelope container whose
+// content key is wrapped for pub with RSA-OAEP, so the holder of the matching
+// private key can open it without a shared password. opts are applied as for
+// CreateContainer.
+func CreateContainerRSA(plaintext string, pub *rsa.PublicKey, opts ...Option) (string, error) {
+	if pub == nil {
+		return "", errors.New("RSA public key must not be nil")
+	}
+	o, err := newOptions(append([]Option{WithEnvelope()}, opts...))
+	if err != nil {
+		return "", err
+	}
+	pt := []byte(plaintext)
+	defer zeroize(pt)
+	b, err := createEnvelope(pt, o, func(cek []byte) ([]WrappedKey, error) {
+		wrapped, err := rsa.EncryptOAEP(sha256.New(), o.rand, pub, cek, []byte(KeyWrapRSAOAEP))
+		if err != nil {
+			return nil, err
+		}
+		return []WrappedKey{{Algorithm: KeyWrapRSAOAEP, Key: hex.EncodeToString(wrapped)}}, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecryptContainerRSA decrypts a container created with CreateContainerRSA
+// using the private key matching its recipient. It returns ErrHMACMismatch if
+// priv does not open any of the wrapped keys.
+func DecryptContainerRSA(containerJSON string, priv *rsa.PrivateKey) (string, error) {
+	if priv == nil {
+		return "", errors.New("RSA private key must not be nil")
+	}
+	return decryptString(containerJSON, "", decryptParams{unwrap: func(w *WrappedKey) ([]byte, error) {
+		return unwrapWithRSA(w, priv)
+	}})
+}
+
+func unwrapWithRSA(w *WrappedKey, priv *rsa.PrivateKey) ([]byte, error) {
+	if w.Algorithm != KeyWrapRSAOAEP {
+		return nil, errNotRecipient
+	}
+	wrapped, err := decodeHex(w.Key)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.Key: %w", err)
+	}
+	cek, err := rsa.DecryptOAEP(sha256.New(), nil, priv, wrapped, []byte(KeyWrapRSAOAEP))
+	if err != nil {
+		return nil, ErrHMACMismatch
+	}
+	return cek, nil
+}
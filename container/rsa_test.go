@@ -0,0 +1,48 @@
+package container
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+)
+
+// TestRSARoundTrip checks if an RSA container opens with the matching private key only.
+func TestRSARoundTrip(t *testing.T) {
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %v", err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Error generating RSA key: %v", err)
+	}
+	plaintext := "hello rsa"
+
+	containerJSON, err := CreateContainerRSA(plaintext, &priv.PublicKey, WithCipher(CipherAES256GCM))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if len(header.WrappedKeys) != 1 || header.WrappedKeys[0].Algorithm != KeyWrapRSAOAEP {
+		t.Errorf("Expected one %s wrapped key, got %+v", KeyWrapRSAOAEP, header.WrappedKeys)
+	}
+
+	decryptedText, err := DecryptContainerRSA(containerJSON, priv)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected %q, got %q", plaintext, decryptedText)
+	}
+
+	if _, err := DecryptContainerRSA(containerJSON, other); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong private key, got: %v", err)
+	}
+	if _, err := Decrypt(containerJSON, "password123"); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch when opening an RSA container with a password, got: %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+package container
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SchemaV1 is a JSON Schema (draft 2020-12) describing the JSON containers
+// this package writes, for integrators who want to check input before
+// passing it on. It describes structure only; Container.Validate and
+// decryption check much more.
+const SchemaV1 = `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "go-crypto-container",
+  "type": "object",
+  "required": ["ContainerMeta", "DeriveInfo", "EncryptionInfo", "ContainedData"],
+  "properties": {
+    "ContainerMeta": {
+      "type": "object",
+      "required": ["Version"],
+      "properties": {
+        "Version": {"type": "string", "minLength": 1},
+        "Compression": {"type": "string"},
+        "Encoding": {"type": "string", "enum": ["hex", "base64"]},
+        "CreatedAt": {"type": "string"},
+        "ExpiresAt": {"type": "string"},
+        "AAD": {"type": "boolean"},
+        "KEK": {"type": "boolean"},
+        "Annotations": {"type": "object", "additionalProperties": {"type": "string"}},
+        "ContentType": {"type": "string"},
+        "KeyID": {"type": "string"},
+        "DedupTag": {"type": "string"},
+        "PlaintextLen": {"type": "integer", "minimum": 0},
+        "Padding": {"type": "integer", "minimum": 0},
+        "External": {"type": "boolean"},
+        "MACHash": {"type": "string", "enum": ["sha256", "sha384", "sha512"]},
+        "Checksum": {"type": "string"}
+      }
+    },
+    "DeriveInfo": {"$ref": "#/$defs/derive"},
+    "EncryptionInfo": {
+      "type": "object",
+      "required": ["IV"],
+      "properties": {
+        "IV": {"type": "string", "minLength": 1},
+        "Cipher": {"type": "string"},
+        "KeySize": {"type": "integer", "minimum": 0}
+      }
+    },
+    "WrappedKeys": {
+      "type": "array",
+      "items": {
+        "type": "object",
+        "required": ["Algorithm", "Key"],
+        "properties": {
+          "Algorithm": {"type": "string", "minLength": 1},
+          "DeriveInfo": {"$ref": "#/$defs/derive"},
+          "EphemeralKey": {"type": "string"},
+          "Nonce": {"type": "string"},
+          "Key": {"type": "string", "minLength": 1}
+        }
+      }
+    },
+    "ContainedData": {
+      "type": "object",
+      "required": ["EncryptedData", "HMAC"],
+      "properties": {
+        "EncryptedData": {"type": "string"},
+        "HMAC": {"type": "string"}
+      }
+    },
+    "Signature": {"type": "string"}
+  },
+  "$defs": {
+    "derive": {
+      "type": "object",
+      "required": ["Salt", "Iters"],
+      "properties": {
+        "Salt": {"type": "string"},
+        "Iters": {"type": "integer", "minimum": 0},
+        "KDF": {"type": "string"},
+        "Memory": {"type": "integer", "minimum": 0},
+        "Time": {"type": "integer", "minimum": 0},
+        "Threads": {"type": "integer", "minimum": 0},
+        "N": {"type": "integer", "minimum": 0},
+        "R": {"type": "integer", "minimum": 0},
+        "P": {"type": "integer", "minimum": 0},
+        "ItemSalt": {"type": "string"}
+      }
+    }
+  }
+}`
+
+// schemaNode is the subset of JSON Schema that SchemaV1 uses.
+type schemaNode struct {
+	Ref                  string                 `json:"$ref"`
+	Type                 string                 `json:"type"`
+	Required             []string               `json:"required"`
+	Properties           map[string]*schemaNode `json:"properties"`
+	AdditionalProperties *schemaNode            `json:"additionalProperties"`
+	Items                *schemaNode            `json:"items"`
+	Minimum              *float64               `json:"minimum"`
+	MinLength            *int                   `json:"minLength"`
+	Enum                 []string               `json:"enum"`
+	Defs                 map[string]*schemaNode `json:"$defs"`
+}
+
+var schemaV1 = func() *schemaNode {
+	var root schemaNode
+	if err := json.Unmarshal([]byte(SchemaV1), &root); err != nil {
+		panic("container: invalid SchemaV1: " + err.Error())
+	}
+	return &root
+}()
+
+// ValidateSchema checks that containerJSON conforms to SchemaV1, returning
+// an error wrapping ErrMalformedContainer that names the first offending
+// field. It needs no password and does no cryptography.
+func ValidateSchema(containerJSON string) error {
+	var v any
+	if err := json.Unmarshal([]byte(containerJSON), &v); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if err := schemaV1.check(schemaV1, v, "container"); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	return nil
+}
+
+// check validates v against n, resolving references against root. path
+// names v in errors.
+func (n *schemaNode) check(root *schemaNode, v any, path string) error {
+	if n.Ref != "" {
+		name, found := strings.CutPrefix(n.Ref, "#/$defs/")
+		def, ok := root.Defs[name]
+		if !found || !ok {
+			return fmt.Errorf("%s: unresolved schema reference %q", path, n.Ref)
+		}
+		return def.check(root, v, path)
+	}
+
+	switch n.Type {
+	case "object":
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an object", path)
+		}
+		for _, name := range n.Required {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing required property %s", path, name)
+			}
+		}
+		names := make([]string, 0, len(obj))
+		for name := range obj {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			prop := n.Properties[name]
+			if prop == nil {
+				prop = n.AdditionalProperties
+			}
+			if prop == nil {
+				continue
+			}
+			if err := prop.check(root, obj[name], path+"."+name); err != nil {
+				return err
+			}
+		}
+	case "array":
+		arr, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: must be an array", path)
+		}
+		if n.Items != nil {
+			for i, item := range arr {
+				if err := n.Items.check(root, item, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+		}
+	case "string":
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("%s: must be a string", path)
+		}
+		if n.MinLength != nil && len(s) < *n.MinLength {
+			return fmt.Errorf("%s: must not be shorter than %d", path, *n.MinLength)
+		}
+		if len(n.Enum) > 0 && !containsString(n.Enum, s) {
+			return fmt.Errorf("%s: must be one of %v", path, n.Enum)
+		}
+	case "integer":
+		f, ok := v.(float64)
+		if !ok || f != math.Trunc(f) {
+			return fmt.Errorf("%s: must be an integer", path)
+		}
+		if n.Minimum != nil && f < *n.Minimum {
+			return fmt.Errorf("%s: must be at least %v", path, *n.Minimum)
+		}
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: must be a boolean", path)
+		}
+	}
+	return nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestSchemaV1IsJSON checks if SchemaV1 is a JSON document.
+func TestSchemaV1IsJSON(t *testing.T) {
+	if !json.Valid([]byte(SchemaV1)) {
+		t.Fatalf("SchemaV1 is not valid JSON")
+	}
+}
+
+// TestValidateSchema checks if created containers conform to SchemaV1.
+func TestValidateSchema(t *testing.T) {
+	password := "password123"
+	for name, containerJSON := range map[string]string{
+		"ctr":      mustCreate(t, "hello schema", password, WithIterations(minIterations)),
+		"envelope": mustCreate(t, "hello schema", password, WithEnvelope(), WithIterations(minIterations), WithAnnotations(map[string]string{"owner": "alice"})),
+		"base64":   mustCreate(t, "hello schema", password, WithEncoding(EncodingBase64), WithIterations(minIterations)),
+		"v1.0":     legacyV10Container,
+	} {
+		if err := ValidateSchema(containerJSON); err != nil {
+			t.Errorf("%s: expected a conforming container, got %v", name, err)
+		}
+	}
+}
+
+// TestValidateSchemaRejects checks if missing objects and wrongly typed fields are rejected with ErrMalformedContainer.
+func TestValidateSchemaRejects(t *testing.T) {
+	containerJSON := mustCreate(t, "hello schema", "password123", WithIterations(minIterations))
+
+	tests := []struct {
+		name   string
+		modify func(m map[string]any)
+	}{
+		{"missing DeriveInfo", func(m map[string]any) { delete(m, "DeriveInfo") }},
+		{"missing version", func(m map[string]any) { delete(m["ContainerMeta"].(map[string]any), "Version") }},
+		{"string iters", func(m map[string]any) { m["DeriveInfo"].(map[string]any)["Iters"] = "100000" }},
+		{"fractional iters", func(m map[string]any) { m["DeriveInfo"].(map[string]any)["Iters"] = 1.5 }},
+		{"negative iters", func(m map[string]any) { m["DeriveInfo"].(map[string]any)["Iters"] = -1 }},
+		{"unknown encoding", func(m map[string]any) { m["ContainerMeta"].(map[string]any)["Encoding"] = "rot13" }},
+		{"numeric annotation", func(m map[string]any) {
+			m["ContainerMeta"].(map[string]any)["Annotations"] = map[string]any{"owner": 1}
+		}},
+		{"wrapped key without key", func(m map[string]any) { m["WrappedKeys"] = []any{map[string]any{"Algorithm": "password"}} }},
+		{"array container", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var m map[string]any
+			if err := json.Unmarshal([]byte(containerJSON), &m); err != nil {
+				t.Fatalf("Failed to unmarshal container: %v", err)
+			}
+			var doc any = m
+			if tt.modify != nil {
+				tt.modify(m)
+			} else {
+				doc = []any{m}
+			}
+			b, err := json.Marshal(doc)
+			if err != nil {
+				t.Fatalf("Failed to marshal container: %v", err)
+			}
+			if err := ValidateSchema(string(b)); !errors.Is(err, ErrMalformedContainer) {
+				t.Errorf("Expected ErrMalformedContainer, got %v", err)
+			}
+		})
+	}
+
+	if err := ValidateSchema("not json"); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for invalid JSON, got %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package container
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sync"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Sealed message layout:
+//
+//	salt ‖ uint64 counter ‖ AES-256-GCM ciphertext
+//
+// The GCM key and a 4-byte nonce prefix are derived from the sealer key and
+// the salt with HKDF, and the nonce is the prefix followed by the counter.
+// The salt is random per KeyedSealer, so sealers sharing a key use unrelated
+// GCM keys, and the counter never repeats within one sealer.
+const (
+	sealerSaltLen   = 16
+	sealerHeaderLen = sealerSaltLen + 8
+	sealerPrefixLen = gcmNonceLen - 8
+)
+
+// KeyedSealer encrypts many messages under one key with AES-256-GCM without
+// ever repeating a nonce. It is safe for concurrent use.
+type KeyedSealer struct {
+	key    []byte
+	salt   []byte
+	aead   cipher.AEAD
+	prefix []byte
+
+	mu      sync.Mutex
+	counter uint64
+}
+
+// NewKeyedSealer returns a KeyedSealer for key, which must be 32 bytes.
+func NewKeyedSealer(key []byte) (*KeyedSealer, error) {
+	if len(key) != keyLen {
+		return nil, fmt.Errorf("key is %d bytes, need %d", len(key), keyLen)
+	}
+	salt, err := readRandomBytes(rand.Reader, sealerSaltLen)
+	if err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+	aead, prefix, err := sealerKeys(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	return &KeyedSealer{key: append([]byte(nil), key...), salt: salt, aead: aead, prefix: prefix}, nil
+}
+
+// Seal encrypts plaintext and authenticates it together with aad. The result
+// records the salt and counter needed to open it. Seal fails rather than
+// reuse a nonce once the counter is exhausted.
+func (s *KeyedSealer) Seal(plaintext, aad []byte) ([]byte, error) {
+	s.mu.Lock()
+	if s.counter == math.MaxUint64 {
+		s.mu.Unlock()
+		return nil, errors.New("sealer counter exhausted")
+	}
+	counter := s.counter
+	s.counter++
+	s.mu.Unlock()
+
+	header := binary.BigEndian.AppendUint64(append([]byte(nil), s.salt...), counter)
+	nonce := binary.BigEndian.AppendUint64(append([]byte(nil), s.prefix...), counter)
+	return s.aead.Seal(header, nonce, plaintext, append(header[:sealerHeaderLen:sealerHeaderLen], aad...)), nil
+}
+
+// Open decrypts a message sealed by any KeyedSealer with the same key. It
+// returns ErrHMACMismatch if the message or aad was modified.
+func (s *KeyedSealer) Open(sealed, aad []byte) ([]byte, error) {
+	return OpenSealed(s.key, sealed, aad)
+}
+
+// OpenSealed decrypts a message produced by KeyedSealer.Seal under key.
+func OpenSealed(key, sealed, aad []byte) ([]byte, error) {
+	if len(key) != keyLen {
+		return nil, fmt.Errorf("key is %d bytes, need %d", len(key), keyLen)
+	}
+	if len(sealed) < sealerHeaderLen+aeadTagLen {
+		return nil, fmt.Errorf("%w: sealed message is %d bytes", ErrMalformedContainer, len(sealed))
+	}
+	header := sealed[:sealerHeaderLen:sealerHeaderLen]
+	aead, prefix, err := sealerKeys(key, header[:sealerSaltLen])
+	if err != nil {
+		return nil, err
+	}
+	nonce := append(prefix, header[sealerSaltLen:]...)
+	plaintext, err := aead.Open(nil, nonce, sealed[sealerHeaderLen:], append(header, aad...))
+	if err != nil {
+		return nil, ErrHMACMismatch
+	}
+	return plaintext, nil
+}
+
+// sealerKeys derives the GCM cipher and nonce prefix for key and salt.
+func sealerKeys(key, salt []byte) (cipher.AEAD, []byte, error) {
+	r := hkdf.New(sha256.New, key, salt, []byte("keyed sealer"))
+	gcmKey := make([]byte, keyLen)
+	defer zeroize(gcmKey)
+	prefix := make([]byte, sealerPrefixLen, gcmNonceLen)
+	if _, err := io.ReadFull(r, gcmKey); err != nil {
+		return nil, nil, err
+	}
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, nil, err
+	}
+	aead, err := newAEAD(CipherAES256GCM, gcmKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return aead, prefix, nil
+}
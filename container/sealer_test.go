@@ -0,0 +1,80 @@
+package container
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestKeyedSealerUniqueNonces checks if 100,000 sealed messages all use distinct nonces and open correctly.
+func TestKeyedSealerUniqueNonces(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, keyLen)
+	s, err := NewKeyedSealer(key)
+	if err != nil {
+		t.Fatalf("Error creating sealer: %v", err)
+	}
+
+	const n = 100000
+	seen := make(map[string]bool, n)
+	for i := 0; i < n; i++ {
+		sealed, err := s.Seal([]byte("hello sealer"), nil)
+		if err != nil {
+			t.Fatalf("Error sealing message %d: %v", i, err)
+		}
+		nonce := string(append(append([]byte(nil), s.prefix...), sealed[sealerSaltLen:sealerHeaderLen]...))
+		if seen[nonce] {
+			t.Fatalf("Nonce repeated at message %d", i)
+		}
+		seen[nonce] = true
+
+		if i%10000 == 0 {
+			plaintext, err := OpenSealed(key, sealed, nil)
+			if err != nil || string(plaintext) != "hello sealer" {
+				t.Fatalf("Expected message %d to open, got %q, %v", i, plaintext, err)
+			}
+		}
+	}
+}
+
+// TestKeyedSealerOpen checks if sealed messages open with the right key and aad only, and detect tampering.
+func TestKeyedSealerOpen(t *testing.T) {
+	key := bytes.Repeat([]byte{0x42}, keyLen)
+	s, err := NewKeyedSealer(key)
+	if err != nil {
+		t.Fatalf("Error creating sealer: %v", err)
+	}
+	aad := []byte("file.txt")
+	sealed, err := s.Seal([]byte("hello sealer"), aad)
+	if err != nil {
+		t.Fatalf("Error sealing: %v", err)
+	}
+
+	// Another sealer with the same key opens it.
+	other, err := NewKeyedSealer(key)
+	if err != nil {
+		t.Fatalf("Error creating sealer: %v", err)
+	}
+	if plaintext, err := other.Open(sealed, aad); err != nil || string(plaintext) != "hello sealer" {
+		t.Errorf("Expected the message to open, got %q, %v", plaintext, err)
+	}
+
+	tampered := append([]byte(nil), sealed...)
+	tampered[sealerSaltLen+7] ^= 1
+	for name, tt := range map[string]struct {
+		key, sealed, aad []byte
+	}{
+		"wrong aad":       {key, sealed, []byte("other.txt")},
+		"wrong key":       {bytes.Repeat([]byte{0x43}, keyLen), sealed, aad},
+		"changed counter": {key, tampered, aad},
+	} {
+		if _, err := OpenSealed(tt.key, tt.sealed, tt.aad); !errors.Is(err, ErrHMACMismatch) {
+			t.Errorf("%s: expected ErrHMACMismatch, got %v", name, err)
+		}
+	}
+	if _, err := OpenSealed(key, sealed[:sealerHeaderLen], aad); !errors.Is(err, ErrMalformedContainer) {
+		t.Errorf("Expected ErrMalformedContainer for a truncated message, got %v", err)
+	}
+	if _, err := NewKeyedSealer(make([]byte, 16)); err == nil {
+		t.Errorf("Expected an error for a 16-byte key")
+	}
+}
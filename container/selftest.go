@@ -0,0 +1,72 @@
+package container
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+// Known answers for SelfTest, checked against OpenSSL.
+var selfTestVectors = []struct {
+	name string
+	run  func() ([]byte, error)
+	want string
+}{
+	{
+		// PBKDF2-HMAC-SHA256 with password "password", salt "salt" and
+		// 4096 iterations, as used by KDFPBKDF2.
+		name: "PBKDF2-SHA256",
+		run: func() ([]byte, error) {
+			return pbkdf2Key(context.Background(), sha256.New, []byte("password"), []byte("salt"), 4096, 32)
+		},
+		want: "c5e478d59288c841aa530db6845c4c8d962893a001ce4e11a4963873aa98134a",
+	},
+	{
+		// NIST SP 800-38A F.5.5, CTR-AES256, first block.
+		name: "AES-256-CTR",
+		run: func() ([]byte, error) {
+			return decryptCTR(
+				mustDecodeHex("6bc1bee22e409f96e93d7e117393172a"),
+				mustDecodeHex("f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff"),
+				mustDecodeHex("603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4"),
+			)
+		},
+		want: "601ec313775789a5b7a7f504bbf3d228",
+	},
+	{
+		// RFC 4231 test case 2.
+		name: "HMAC-SHA256",
+		run: func() ([]byte, error) {
+			return computeMAC([]byte("Jefe"), []byte("what do ya want for nothing?")), nil
+		},
+		want: "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+	},
+}
+
+// SelfTest checks PBKDF2, AES-CTR and HMAC, as used by this package, against
+// fixed known answers, for environments that require a power-on self-test.
+// It uses no randomness and returns ErrSelfTestFailed naming the first
+// primitive that gives a wrong answer.
+func SelfTest() error {
+	for _, v := range selfTestVectors {
+		got, err := v.run()
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrSelfTestFailed, v.name, err)
+		}
+		if !bytes.Equal(got, mustDecodeHex(v.want)) {
+			return fmt.Errorf("%w: %s gave %x", ErrSelfTestFailed, v.name, got)
+		}
+	}
+	return nil
+}
+
+// mustDecodeHex decodes a hex constant, panicking if it is invalid.
+func mustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
@@ -0,0 +1,20 @@
+package container
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestSelfTest checks if SelfTest passes and reports ErrSelfTestFailed when a known answer is wrong.
+func TestSelfTest(t *testing.T) {
+	if err := SelfTest(); err != nil {
+		t.Fatalf("Expected the self-test to pass, got: %v", err)
+	}
+
+	saved := selfTestVectors[1].want
+	defer func() { selfTestVectors[1].want = saved }()
+	selfTestVectors[1].want = flipLastHexDigit(saved)
+	if err := SelfTest(); !errors.Is(err, ErrSelfTestFailed) {
+		t.Errorf("Expected ErrSelfTestFailed for a wrong known answer, got: %v", err)
+	}
+}
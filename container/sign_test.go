@@ -0,0 +1,133 @@
+package container
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+// TestSignContainer checks if a signed container verifies, still decrypts, and rejects tampering and other keys.
+func TestSignContainer(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating Ed25519 key: %v", err)
+	}
+	otherPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating Ed25519 key: %v", err)
+	}
+	password := "password123"
+	containerJSON := mustCreate(t, "hello signature", password, WithIterations(minIterations))
+
+	if err := VerifySignature(containerJSON, pub); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for an unsigned container, got: %v", err)
+	}
+
+	signed, err := SignContainer(containerJSON, priv)
+	if err != nil {
+		t.Fatalf("Error signing container: %v", err)
+	}
+	if err := VerifySignature(signed, pub); err != nil {
+		t.Errorf("Expected a valid signature, got: %v", err)
+	}
+	if _, err := DecryptContainer(signed, password); err != nil {
+		t.Errorf("Error decrypting signed container: %v", err)
+	}
+	if err := VerifySignature(signed, otherPub); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for the wrong public key, got: %v", err)
+	}
+
+	for name, tamper := range map[string]func(c *Container){
+		"ciphertext": func(c *Container) { c.ContainedData.EncryptedData = flipLastHexDigit(c.ContainedData.EncryptedData) },
+		"header":     func(c *Container) { c.DeriveInfo.Iters++ },
+	} {
+		var container Container
+		if err := json.Unmarshal([]byte(signed), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		tamper(&container)
+		tampered, err := json.Marshal(container)
+		if err != nil {
+			t.Fatalf("Failed to marshal tampered container: %v", err)
+		}
+		if err := VerifySignature(string(tampered), pub); !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got: %v", name, err)
+		}
+	}
+}
+
+// TestSignatureCoversMAC checks if swapping the content key and HMAC of a signed X25519 envelope, which any holder of the recipient's public key can do, invalidates the signature.
+func TestSignatureCoversMAC(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Error generating Ed25519 key: %v", err)
+	}
+	var recipientPriv [32]byte
+	if _, err := rand.Read(recipientPriv[:]); err != nil {
+		t.Fatalf("Error generating X25519 key: %v", err)
+	}
+	recipientPubBytes, err := curve25519.X25519(recipientPriv[:], curve25519.Basepoint)
+	if err != nil {
+		t.Fatalf("Error deriving X25519 public key: %v", err)
+	}
+	var recipientPub [32]byte
+	copy(recipientPub[:], recipientPubBytes)
+
+	containerJSON, err := CreateContainerX25519("hello signature", recipientPub)
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	signed, err := SignContainer(containerJSON, priv)
+	if err != nil {
+		t.Fatalf("Error signing container: %v", err)
+	}
+
+	// Wrap a new content key for the recipient and MAC the old ciphertext
+	// under it, using only public information.
+	var container Container
+	if err := json.Unmarshal([]byte(signed), &container); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	cek := make([]byte, keyLen)
+	ephemeralPriv := make([]byte, curve25519.ScalarSize)
+	for _, b := range [][]byte{cek, ephemeralPriv} {
+		if _, err := rand.Read(b); err != nil {
+			t.Fatalf("Error generating key: %v", err)
+		}
+	}
+	ephemeralPub, _ := curve25519.X25519(ephemeralPriv, curve25519.Basepoint)
+	shared, _ := curve25519.X25519(ephemeralPriv, recipientPub[:])
+	kek, err := x25519KEK(shared, ephemeralPub, recipientPub[:])
+	if err != nil {
+		t.Fatalf("Error deriving KEK: %v", err)
+	}
+	w := WrappedKey{Algorithm: KeyWrapX25519, EphemeralKey: hex.EncodeToString(ephemeralPub)}
+	if err := sealKey(&w, kek, cek, rand.Reader); err != nil {
+		t.Fatalf("Error wrapping key: %v", err)
+	}
+	container.WrappedKeys = []WrappedKey{w}
+	_, macKey, err := splitKey(cek)
+	if err != nil {
+		t.Fatalf("Error splitting key: %v", err)
+	}
+	f, err := container.decodeFields()
+	if err != nil {
+		t.Fatalf("Error decoding fields: %v", err)
+	}
+	if err := setMACData(&container, f.ciphertext, f.salt, f.iv, nil, macKey); err != nil {
+		t.Fatalf("Error computing MAC: %v", err)
+	}
+	forged := mustMarshal(t, &container)
+
+	if _, err := DecryptContainerX25519(forged, recipientPriv); err != nil {
+		t.Fatalf("Expected the forged envelope to open for the recipient, got: %v", err)
+	}
+	if err := VerifySignature(forged, pub); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Expected ErrInvalidSignature for a swapped key and HMAC, got: %v", err)
+	}
+}
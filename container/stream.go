@@ -0,0 +1,440 @@
+package container
+
+import (
+	"bufio"
+	"context"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"time"
+)
+
+// Stream layout:
+//
+//	magic ‖ uint32 header length ‖ header JSON ‖ frame...
+//
+// Each frame is the AEAD ciphertext of one chunk of streamChunkSize plaintext
+// bytes; only the last chunk may be shorter, and it may be empty. A frame is
+// sealed with the base nonce XORed with its index and with
+// SHA-256(header JSON) ‖ uint64 index ‖ final flag ‖ tag of the previous frame
+// as additional data, so frames cannot be modified, reordered, or dropped from
+// the end. The first frame chains to an empty tag.
+const (
+	streamMagic        = "GOCCSTRM"
+	streamVersion      = "v2"
+	streamChunkSize    = 64 * 1024
+	maxStreamHeaderLen = 64 * 1024
+	maxStreamChunkSize = 16 * 1024 * 1024
+)
+
+type streamHeader struct {
+	Version    string `json:"Version"`
+	Cipher     string `json:"Cipher"`
+	ChunkSize  int    `json:"ChunkSize"`
+	KeySize    int    `json:"KeySize,omitempty"`
+	Nonce      string `json:"Nonce"`
+	DeriveInfo Derive `json:"DeriveInfo"`
+}
+
+// EncryptStream encrypts src to dst in independently authenticated chunks,
+// so arbitrarily large inputs never have to fit in memory. It accepts the same
+// options as CreateContainer, except that the cipher defaults to
+// CipherAES256GCM and must be an AEAD. WithObserver observes the whole
+// stream as one encryption.
+func EncryptStream(dst io.Writer, src io.Reader, password string, opts ...Option) (err error) {
+	o, err := newOptions(append([]Option{WithCipher(CipherAES256GCM)}, opts...))
+	if err != nil {
+		return err
+	}
+	if o.observer != nil {
+		start := time.Now()
+		defer func() { o.observer.ObserveEncrypt(time.Since(start), err) }()
+	}
+	if usesHMAC(o.cipher) {
+		return errors.New("streams require an AEAD cipher")
+	}
+	if o.compression != "" {
+		return errors.New("streams do not support compression")
+	}
+	if len(o.aad) > 0 {
+		return errors.New("streams do not support associated data")
+	}
+	if o.expiresAt != "" {
+		return errors.New("streams do not support expiry")
+	}
+	if len(o.annotations) > 0 {
+		return errors.New("streams do not support annotations")
+	}
+	if o.keyID != "" {
+		return errors.New("streams do not support key IDs")
+	}
+	if o.dedupKey != nil {
+		return errors.New("streams do not support dedup tags")
+	}
+	if o.padding != 0 {
+		return errors.New("streams do not support padding")
+	}
+	if o.plaintextLen {
+		return errors.New("streams do not support recording the plaintext length")
+	}
+	if o.macHash != "" {
+		return errors.New("streams do not support MAC hash selection")
+	}
+	if o.external != nil {
+		return errors.New("streams do not support external ciphertext")
+	}
+	if o.rejectNested {
+		return errors.New("streams do not support rejecting nested containers")
+	}
+	if o.envelope {
+		return errors.New("streams do not support envelope encryption")
+	}
+	if err := o.checkPassword(password); err != nil {
+		return err
+	}
+
+	salt, err := readRandomBytes(o.rand, o.saltLen)
+	if err != nil {
+		return fmt.Errorf("generating salt: %w", err)
+	}
+	nonceLen, err := ivSize(o.cipher)
+	if err != nil {
+		return err
+	}
+	nonce, err := readRandomBytes(o.rand, nonceLen)
+	if err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+
+	header := streamHeader{
+		Version:    streamVersion,
+		Cipher:     o.cipher,
+		ChunkSize:  streamChunkSize,
+		KeySize:    o.keySizeOpt,
+		Nonce:      hex.EncodeToString(nonce),
+		DeriveInfo: o.derive,
+	}
+	header.DeriveInfo.Salt = hex.EncodeToString(salt)
+	if header.DeriveInfo.isPBKDF2() && header.DeriveInfo.Iters == 0 {
+		header.DeriveInfo.Iters = defaultIterations()
+	}
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+
+	s, err := newStreamState(&header, rawHeader, password)
+	if err != nil {
+		return err
+	}
+
+	prefix := append([]byte(streamMagic), binary.BigEndian.AppendUint32(nil, uint32(len(rawHeader)))...)
+	if _, err := dst.Write(append(prefix, rawHeader...)); err != nil {
+		return err
+	}
+
+	br := bufio.NewReader(src)
+	chunk := make([]byte, header.ChunkSize)
+	defer zeroize(chunk)
+	var frame []byte
+	var processed int64
+	for index := uint64(0); ; index++ {
+		n, final, err := readChunk(br, chunk)
+		if err != nil {
+			return err
+		}
+		frame = s.aead.Seal(frame[:0], s.nonce(index), chunk[:n], s.ad(index, final))
+		s.chain(frame)
+		if _, err := dst.Write(frame); err != nil {
+			return err
+		}
+		processed += int64(n)
+		if o.progress != nil {
+			o.progress(processed)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// DecryptStream decrypts a stream produced by EncryptStream from src to dst.
+// Every chunk is authenticated before it is written, and decryption stops at
+// the first chunk that fails with an ErrChunkAuth naming it. Output written
+// before a failure is genuine but incomplete. The only options that apply are
+// WithDecryptProgress and WithDecryptObserver, which observes the whole
+// stream as one decryption.
+func DecryptStream(dst io.Writer, src io.Reader, password string, opts ...DecryptOption) (err error) {
+	p := newDecryptParams("", opts)
+	if p.observer != nil {
+		start := time.Now()
+		defer func() { p.observer.ObserveDecrypt(time.Since(start), err) }()
+	}
+	br := bufio.NewReader(src)
+	header, rawHeader, err := readStreamHeader(br)
+	if err != nil {
+		return err
+	}
+	s, err := newStreamState(header, rawHeader, password)
+	if err != nil {
+		return err
+	}
+
+	frame := make([]byte, header.ChunkSize+s.aead.Overhead())
+	var plaintext []byte
+	defer func() { zeroize(plaintext[:cap(plaintext)]) }()
+	var processed int64
+	for index := uint64(0); ; index++ {
+		n, final, err := readChunk(br, frame)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			// Even an empty final chunk has an authentication tag.
+			return fmt.Errorf("%w: stream truncated", ErrMalformedContainer)
+		}
+		plaintext, err = s.aead.Open(plaintext[:0], s.nonce(index), frame[:n], s.ad(index, final))
+		if err != nil {
+			return ErrChunkAuth{Index: index}
+		}
+		s.chain(frame[:n])
+		if _, err := dst.Write(plaintext); err != nil {
+			return err
+		}
+		processed += int64(len(plaintext))
+		if p.progress != nil {
+			p.progress(processed)
+		}
+		if final {
+			return nil
+		}
+	}
+}
+
+// DecryptRange decrypts length bytes of plaintext starting at offset from a
+// stream produced by EncryptStream, reading and authenticating only the
+// chunks that cover the range. Every chunk but the last has the same size, so
+// their offsets follow from the header and no chunk table is needed. The
+// chunk before the range is not decrypted; only its tag is read, and a wrong
+// tag makes the first chunk fail. Chunks that fail are
+// reported with ErrChunkAuth. A range that extends past the end of the stream
+// returns an error wrapping io.EOF. The only option that applies is
+// WithDecryptObserver.
+func DecryptRange(src io.ReaderAt, password string, offset, length int64, opts ...DecryptOption) (_ []byte, err error) {
+	p := newDecryptParams("", opts)
+	if p.observer != nil {
+		start := time.Now()
+		defer func() { p.observer.ObserveDecrypt(time.Since(start), err) }()
+	}
+	if offset < 0 || length < 0 {
+		return nil, fmt.Errorf("invalid range: offset %d, length %d", offset, length)
+	}
+	header, rawHeader, err := readStreamHeader(io.NewSectionReader(src, 0, math.MaxInt64))
+	if err != nil {
+		return nil, err
+	}
+	s, err := newStreamState(header, rawHeader, password)
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		return []byte{}, nil
+	}
+
+	chunkSize := int64(header.ChunkSize)
+	overhead := int64(s.aead.Overhead())
+	frameLen := chunkSize + overhead
+	dataStart := int64(len(streamMagic) + 4 + len(rawHeader))
+	errPastEnd := fmt.Errorf("range past the end of the stream: %w", io.EOF)
+	if offset > math.MaxInt64-length {
+		return nil, errPastEnd
+	}
+	first, last := offset/chunkSize, (offset+length-1)/chunkSize
+	if last >= (math.MaxInt64-dataStart)/frameLen {
+		return nil, errPastEnd
+	}
+	if first > 0 {
+		s.prevTag = make([]byte, overhead)
+		if _, err := src.ReadAt(s.prevTag, dataStart+first*frameLen-overhead); err == io.EOF {
+			return nil, errPastEnd
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	// The stream may be much shorter than length claims, so out starts at
+	// one chunk and grows as frames are actually read.
+	out := make([]byte, 0, min(length, chunkSize))
+	frame := make([]byte, frameLen)
+	var plaintext []byte
+	defer func() { zeroize(plaintext[:cap(plaintext)]) }()
+	for index := first; index <= last; index++ {
+		n, err := src.ReadAt(frame, dataStart+index*frameLen)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if int64(n) < overhead {
+			return nil, errPastEnd
+		}
+		final := int64(n) < frameLen
+		if !final {
+			// A full frame is final if nothing follows it.
+			var next [1]byte
+			_, err := src.ReadAt(next[:], dataStart+(index+1)*frameLen)
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			final = err == io.EOF
+		}
+		plaintext, err = s.aead.Open(plaintext[:0], s.nonce(uint64(index)), frame[:n], s.ad(uint64(index), final))
+		if err != nil {
+			return nil, ErrChunkAuth{Index: uint64(index)}
+		}
+		s.chain(frame[:n])
+
+		chunkStart := index * chunkSize
+		from := min(max(offset-chunkStart, 0), int64(len(plaintext)))
+		to := min(offset+length-chunkStart, int64(len(plaintext)))
+		out = appendWiped(out, plaintext[from:to])
+		if final {
+			break
+		}
+	}
+	if int64(len(out)) < length {
+		zeroize(out)
+		return nil, errPastEnd
+	}
+	return out, nil
+}
+
+// appendWiped appends b to out like append, but zeroizes the old backing
+// array when it has to grow, so no stray copies of the plaintext are left.
+func appendWiped(out, b []byte) []byte {
+	if len(out)+len(b) > cap(out) {
+		grown := make([]byte, len(out), max(len(out)+len(b), 2*cap(out)))
+		copy(grown, out)
+		zeroize(out)
+		out = grown
+	}
+	return append(out, b...)
+}
+
+// readChunk fills buf from r and reports whether r is exhausted afterwards.
+func readChunk(r *bufio.Reader, buf []byte) (n int, final bool, err error) {
+	n, err = io.ReadFull(r, buf)
+	switch err {
+	case nil:
+		if _, err := r.Peek(1); err == io.EOF {
+			return n, true, nil
+		} else if err != nil {
+			return n, false, err
+		}
+		return n, false, nil
+	case io.EOF, io.ErrUnexpectedEOF:
+		return n, true, nil
+	}
+	return n, false, err
+}
+
+func readStreamHeader(r io.Reader) (*streamHeader, []byte, error) {
+	prefix := make([]byte, len(streamMagic)+4)
+	if _, err := io.ReadFull(r, prefix); err != nil {
+		return nil, nil, fmt.Errorf("reading stream header: %w", err)
+	}
+	if string(prefix[:len(streamMagic)]) != streamMagic {
+		return nil, nil, fmt.Errorf("%w: not an encrypted stream", ErrMalformedContainer)
+	}
+	headerLen := binary.BigEndian.Uint32(prefix[len(streamMagic):])
+	if headerLen > maxStreamHeaderLen {
+		return nil, nil, fmt.Errorf("%w: stream header too large: %d bytes", ErrMalformedContainer, headerLen)
+	}
+	rawHeader := make([]byte, headerLen)
+	if _, err := io.ReadFull(r, rawHeader); err != nil {
+		return nil, nil, fmt.Errorf("reading stream header: %w", err)
+	}
+
+	var header streamHeader
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	if header.Version != streamVersion {
+		return nil, nil, fmt.Errorf("%w: stream version %q", ErrUnsupportedVersion, header.Version)
+	}
+	if header.ChunkSize <= 0 || header.ChunkSize > maxStreamChunkSize {
+		return nil, nil, fmt.Errorf("%w: invalid stream chunk size: %d", ErrMalformedContainer, header.ChunkSize)
+	}
+	return &header, rawHeader, nil
+}
+
+// streamState holds what is needed to seal or open the frames of one stream.
+type streamState struct {
+	aead         cipher.AEAD
+	baseNonce    []byte
+	headerDigest [sha256.Size]byte
+	// prevTag is the tag of the previous frame, bound by the next one.
+	prevTag []byte
+}
+
+func newStreamState(header *streamHeader, rawHeader []byte, password string) (*streamState, error) {
+	salt, err := decodeHex(header.DeriveInfo.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding DeriveInfo.Salt: %w", err)
+	}
+	nonce, err := decodeHex(header.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("decoding Nonce: %w", err)
+	}
+	keySize := header.KeySize
+	if keySize == 0 {
+		keySize = keyLen
+	}
+	if !validKeySize(keySize) {
+		return nil, fmt.Errorf("%w: invalid key size %d", ErrMalformedContainer, keySize)
+	}
+	encKey, macKey, err := deriveKeys(context.Background(), password, salt, &header.DeriveInfo, keySize)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(encKey)
+	zeroize(macKey)
+	aead, err := newAEAD(header.Cipher, encKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid stream nonce length", ErrMalformedContainer)
+	}
+	return &streamState{
+		aead:         aead,
+		baseNonce:    nonce,
+		headerDigest: sha256.Sum256(rawHeader),
+	}, nil
+}
+
+func (s *streamState) nonce(index uint64) []byte {
+	nonce := append([]byte(nil), s.baseNonce...)
+	tail := nonce[len(nonce)-8:]
+	binary.BigEndian.PutUint64(tail, binary.BigEndian.Uint64(tail)^index)
+	return nonce
+}
+
+func (s *streamState) ad(index uint64, final bool) []byte {
+	ad := binary.BigEndian.AppendUint64(s.headerDigest[:len(s.headerDigest):len(s.headerDigest)], index)
+	if final {
+		ad = append(ad, 1)
+	} else {
+		ad = append(ad, 0)
+	}
+	return append(ad, s.prevTag...)
+}
+
+// chain records the tag of frame for the additional data of the next one.
+func (s *streamState) chain(frame []byte) {
+	s.prevTag = append(s.prevTag[:0], frame[len(frame)-s.aead.Overhead():]...)
+}
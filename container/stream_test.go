@@ -0,0 +1,314 @@
+package container
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"testing"
+)
+
+func encryptTestStream(t *testing.T, plaintext []byte, password string, opts ...Option) []byte {
+	t.Helper()
+	var encrypted bytes.Buffer
+	opts = append([]Option{WithIterations(minIterations)}, opts...)
+	if err := EncryptStream(&encrypted, bytes.NewReader(plaintext), password, opts...); err != nil {
+		t.Fatalf("Error encrypting stream: %v", err)
+	}
+	return encrypted.Bytes()
+}
+
+// streamFrameOffset returns the offset of frame index in an encrypted stream with a GCM tag.
+func streamFrameOffset(t *testing.T, encrypted []byte, index int) int {
+	t.Helper()
+	_, rawHeader, err := readStreamHeader(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatalf("Error reading stream header: %v", err)
+	}
+	return len(streamMagic) + 4 + len(rawHeader) + index*(streamChunkSize+16)
+}
+
+// TestStreamRoundTrip checks if streams of various sizes around the chunk boundary round-trip.
+func TestStreamRoundTrip(t *testing.T) {
+	password := "password123"
+	sizes := []int{0, 1, streamChunkSize - 1, streamChunkSize, streamChunkSize + 1, 3*streamChunkSize + 5}
+
+	for _, cipherName := range []string{CipherAES256GCM, CipherXChaCha20Poly1305} {
+		for _, size := range sizes {
+			plaintext := bytes.Repeat([]byte{0x5a}, size)
+			encrypted := encryptTestStream(t, plaintext, password, WithCipher(cipherName))
+
+			var decrypted bytes.Buffer
+			if err := DecryptStream(&decrypted, bytes.NewReader(encrypted), password); err != nil {
+				t.Fatalf("%s/%d: error decrypting stream: %v", cipherName, size, err)
+			}
+			if !bytes.Equal(decrypted.Bytes(), plaintext) {
+				t.Errorf("%s/%d: decrypted stream does not match the plaintext", cipherName, size)
+			}
+		}
+	}
+}
+
+// TestStreamTampering checks if modified, reordered or truncated streams are rejected.
+func TestStreamTampering(t *testing.T) {
+	password := "password123"
+	plaintext := make([]byte, 3*streamChunkSize+100)
+	for i := range plaintext {
+		plaintext[i] = byte(i)
+	}
+	encrypted := encryptTestStream(t, plaintext, password)
+	frame1 := streamFrameOffset(t, encrypted, 1)
+	frame2 := streamFrameOffset(t, encrypted, 2)
+	frame3 := streamFrameOffset(t, encrypted, 3)
+
+	tests := []struct {
+		name   string
+		tamper func(b []byte) []byte
+	}{
+		{"flipped byte", func(b []byte) []byte { b[frame2+10] ^= 1; return b }},
+		{"header byte", func(b []byte) []byte { b[len(streamMagic)+6] ^= 1; return b }},
+		{"swapped frames", func(b []byte) []byte {
+			out := append([]byte(nil), b[:frame1]...)
+			out = append(out, b[frame2:frame3]...)
+			out = append(out, b[frame1:frame2]...)
+			return append(out, b[frame3:]...)
+		}},
+		{"dropped final frame", func(b []byte) []byte { return b[:frame3] }},
+		{"truncated frame", func(b []byte) []byte { return b[:len(b)-1] }},
+		{"no frames", func(b []byte) []byte { return b[:frame1-(streamChunkSize+16)] }},
+		{"truncated header", func(b []byte) []byte { return b[:10] }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := tt.tamper(append([]byte(nil), encrypted...))
+			var decrypted bytes.Buffer
+			if err := DecryptStream(&decrypted, bytes.NewReader(tampered), password); err == nil {
+				t.Errorf("DecryptStream did not return an error")
+			}
+			if !bytes.HasPrefix(plaintext, decrypted.Bytes()) {
+				t.Errorf("DecryptStream wrote data that is not a prefix of the plaintext")
+			}
+		})
+	}
+
+	var decrypted bytes.Buffer
+	err := DecryptStream(&decrypted, bytes.NewReader(encrypted), "wrongpassword")
+	if !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected HMAC mismatch error, got: %v", err)
+	}
+	if decrypted.Len() != 0 {
+		t.Errorf("Expected no output with the wrong password, got %d bytes", decrypted.Len())
+	}
+}
+
+// TestStreamChunkAuth checks if DecryptStream reports the index of the first chunk that fails authentication.
+func TestStreamChunkAuth(t *testing.T) {
+	password := "password123"
+	plaintext := bytes.Repeat([]byte{0x5a}, 5*streamChunkSize+7)
+	encrypted := encryptTestStream(t, plaintext, password)
+	frame3 := streamFrameOffset(t, encrypted, 3)
+	frame4 := streamFrameOffset(t, encrypted, 4)
+
+	tests := []struct {
+		name      string
+		password  string
+		tamper    func(b []byte) []byte
+		wantIndex uint64
+	}{
+		{"flipped byte in chunk 3", password, func(b []byte) []byte { b[frame3+100] ^= 1; return b }, 3},
+		{"flipped tag of chunk 3", password, func(b []byte) []byte { b[frame4-1] ^= 1; return b }, 3},
+		{"swapped chunks 3 and 4", password, func(b []byte) []byte {
+			frame5 := streamFrameOffset(t, b, 5)
+			out := append([]byte(nil), b[:frame3]...)
+			out = append(out, b[frame4:frame5]...)
+			out = append(out, b[frame3:frame4]...)
+			return append(out, b[frame5:]...)
+		}, 3},
+		{"truncated after chunk 3", password, func(b []byte) []byte { return b[:frame4] }, 3},
+		{"wrong password", "wrongpassword", func(b []byte) []byte { return b }, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tampered := tt.tamper(append([]byte(nil), encrypted...))
+			err := DecryptStream(io.Discard, bytes.NewReader(tampered), tt.password)
+			var chunkErr ErrChunkAuth
+			if !errors.As(err, &chunkErr) {
+				t.Fatalf("Expected ErrChunkAuth, got: %v", err)
+			}
+			if chunkErr.Index != tt.wantIndex {
+				t.Errorf("Expected chunk index %d, got %d", tt.wantIndex, chunkErr.Index)
+			}
+			if !errors.Is(err, ErrHMACMismatch) {
+				t.Errorf("Expected ErrChunkAuth to match ErrHMACMismatch")
+			}
+		})
+	}
+}
+
+// TestStreamV1Rejected checks if streams in the unchained v1 format are rejected with ErrUnsupportedVersion.
+func TestStreamV1Rejected(t *testing.T) {
+	header := streamHeader{
+		Version:    "v1",
+		Cipher:     CipherAES256GCM,
+		ChunkSize:  streamChunkSize,
+		Nonce:      strings.Repeat("00", 12),
+		DeriveInfo: Derive{KDF: KDFPBKDF2, Salt: strings.Repeat("11", saltLen), Iters: minIterations},
+	}
+	rawHeader, err := json.Marshal(header)
+	if err != nil {
+		t.Fatalf("Error marshaling header: %v", err)
+	}
+	encrypted := append([]byte(streamMagic), binary.BigEndian.AppendUint32(nil, uint32(len(rawHeader)))...)
+	encrypted = append(encrypted, rawHeader...)
+
+	if err := DecryptStream(io.Discard, bytes.NewReader(encrypted), "password123"); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("Expected ErrUnsupportedVersion for a v1 stream, got: %v", err)
+	}
+}
+
+// TestDecryptRange checks if byte ranges of a 1MB stream decrypt correctly and tampering or out-of-range requests are reported.
+func TestDecryptRange(t *testing.T) {
+	password := "password123"
+	plaintext := make([]byte, 1000000)
+	for i := range plaintext {
+		plaintext[i] = byte(i * 7)
+	}
+	encrypted := encryptTestStream(t, plaintext, password)
+	src := bytes.NewReader(encrypted)
+
+	for _, r := range []struct{ offset, length int64 }{
+		{500000, 100},
+		{0, 1},
+		{streamChunkSize - 10, 20},
+		{3*streamChunkSize - 1, 2*streamChunkSize + 2},
+		{int64(len(plaintext)) - 5, 5},
+		{0, int64(len(plaintext))},
+		{1234, 0},
+	} {
+		got, err := DecryptRange(src, password, r.offset, r.length)
+		if err != nil {
+			t.Fatalf("[%d:+%d]: error decrypting range: %v", r.offset, r.length, err)
+		}
+		if want := plaintext[r.offset : r.offset+r.length]; !bytes.Equal(got, want) {
+			t.Errorf("[%d:+%d]: decrypted range does not match the plaintext", r.offset, r.length)
+		}
+	}
+
+	for _, r := range []struct{ offset, length int64 }{
+		{int64(len(plaintext)) - 5, 6},
+		{int64(len(plaintext)) + streamChunkSize, 1},
+		{math.MaxInt64, 1},
+		// A length far beyond the stream must not be allocated up front.
+		{0, math.MaxInt64 / 2},
+	} {
+		if _, err := DecryptRange(src, password, r.offset, r.length); !errors.Is(err, io.EOF) {
+			t.Errorf("[%d:+%d]: expected io.EOF, got: %v", r.offset, r.length, err)
+		}
+	}
+	if _, err := DecryptRange(src, password, -1, 10); err == nil {
+		t.Errorf("Expected an error for a negative offset")
+	}
+
+	tampered := append([]byte(nil), encrypted...)
+	chunk := int64(500000 / streamChunkSize)
+	tampered[streamFrameOffset(t, tampered, int(chunk))+5] ^= 1
+	_, err := DecryptRange(bytes.NewReader(tampered), password, 500000, 100)
+	var chunkErr ErrChunkAuth
+	if !errors.As(err, &chunkErr) || chunkErr.Index != uint64(chunk) {
+		t.Errorf("Expected ErrChunkAuth for chunk %d, got: %v", chunk, err)
+	}
+	// The tag of the previous chunk is bound, even though it is not decrypted.
+	tampered = append([]byte(nil), encrypted...)
+	tampered[streamFrameOffset(t, tampered, int(chunk))-1] ^= 1
+	if _, err := DecryptRange(bytes.NewReader(tampered), password, 500000, 100); !errors.As(err, &chunkErr) || chunkErr.Index != uint64(chunk) {
+		t.Errorf("Expected ErrChunkAuth for chunk %d after changing the previous tag, got: %v", chunk, err)
+	}
+	// Dropping the final chunk is detected when the range reaches the new end.
+	truncated := encrypted[:streamFrameOffset(t, encrypted, int(chunk)+1)]
+	if _, err := DecryptRange(bytes.NewReader(truncated), password, 500000, 100); !errors.As(err, &chunkErr) {
+		t.Errorf("Expected ErrChunkAuth for a truncated stream, got: %v", err)
+	}
+
+	if _, err := DecryptRange(src, "wrongpassword", 500000, 100); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for the wrong password, got: %v", err)
+	}
+}
+
+// TestEncryptStreamRejectsCTR checks if a non-AEAD cipher is rejected for streams.
+func TestEncryptStreamRejectsCTR(t *testing.T) {
+	var encrypted bytes.Buffer
+	if err := EncryptStream(&encrypted, bytes.NewReader(nil), "password123", WithCipher(CipherAES256CTR)); err == nil {
+		t.Errorf("Expected an error for CTR streams")
+	}
+}
+
+// TestStreamKeySize checks if streams honor WithKeySize.
+func TestStreamKeySize(t *testing.T) {
+	plaintext := []byte("hello aes-128 stream")
+	encrypted := encryptTestStream(t, plaintext, "password123", WithKeySize(16))
+
+	header, _, err := readStreamHeader(bytes.NewReader(encrypted))
+	if err != nil {
+		t.Fatalf("Error reading stream header: %v", err)
+	}
+	if header.KeySize != 16 {
+		t.Errorf("Expected KeySize 16, got %d", header.KeySize)
+	}
+
+	var decrypted bytes.Buffer
+	if err := DecryptStream(&decrypted, bytes.NewReader(encrypted), "password123"); err != nil {
+		t.Fatalf("Error decrypting stream: %v", err)
+	}
+	if !bytes.Equal(decrypted.Bytes(), plaintext) {
+		t.Errorf("Decrypted stream does not match the plaintext")
+	}
+}
+
+// TestStreamProgress checks if the progress callbacks fire once per chunk with increasing counts and not after an error.
+func TestStreamProgress(t *testing.T) {
+	password := "password123"
+	plaintext := bytes.Repeat([]byte{0x5a}, 1024*1024)
+	wantCalls := len(plaintext) / streamChunkSize
+
+	check := func(t *testing.T, counts []int64) {
+		t.Helper()
+		if len(counts) != wantCalls {
+			t.Fatalf("Expected %d progress calls, got %d", wantCalls, len(counts))
+		}
+		for i, n := range counts {
+			if want := int64((i + 1) * streamChunkSize); n != want {
+				t.Errorf("Expected progress call %d to report %d bytes, got %d", i, want, n)
+			}
+		}
+	}
+
+	var encryptCounts []int64
+	encrypted := encryptTestStream(t, plaintext, password, WithProgress(func(n int64) { encryptCounts = append(encryptCounts, n) }))
+	check(t, encryptCounts)
+
+	var decryptCounts []int64
+	var decrypted bytes.Buffer
+	if err := DecryptStream(&decrypted, bytes.NewReader(encrypted), password, WithDecryptProgress(func(n int64) { decryptCounts = append(decryptCounts, n) })); err != nil {
+		t.Fatalf("Error decrypting stream: %v", err)
+	}
+	check(t, decryptCounts)
+
+	// A nil callback is a no-op.
+	encryptTestStream(t, plaintext[:10], password, WithProgress(nil))
+
+	// Nothing is reported for the chunk that fails or after it.
+	tampered := append([]byte(nil), encrypted...)
+	tampered[streamFrameOffset(t, tampered, 2)] ^= 1
+	decryptCounts = nil
+	if err := DecryptStream(io.Discard, bytes.NewReader(tampered), password, WithDecryptProgress(func(n int64) { decryptCounts = append(decryptCounts, n) })); err == nil {
+		t.Fatalf("Expected an error for a tampered stream")
+	}
+	if len(decryptCounts) != 2 {
+		t.Errorf("Expected 2 progress calls before the tampered chunk, got %d", len(decryptCounts))
+	}
+}
@@ -0,0 +1,72 @@
+package container
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// upperHexFields returns containerJSON with every hex field uppercased.
+func upperHexFields(t *testing.T, containerJSON string) string {
+	t.Helper()
+	var c Container
+	if err := json.Unmarshal([]byte(containerJSON), &c); err != nil {
+		t.Fatalf("Failed to unmarshal container: %v", err)
+	}
+	for _, s := range []*string{
+		&c.DeriveInfo.Salt, &c.EncryptionInfo.IV, &c.ContainedData.EncryptedData, &c.ContainedData.HMAC,
+		&c.ContainerMeta.DedupTag, &c.ContainerMeta.Checksum, &c.Signature,
+	} {
+		*s = strings.ToUpper(*s)
+	}
+	for i := range c.WrappedKeys {
+		w := &c.WrappedKeys[i]
+		w.Key, w.Nonce = strings.ToUpper(w.Key), strings.ToUpper(w.Nonce)
+		if w.DeriveInfo != nil {
+			w.DeriveInfo.Salt = strings.ToUpper(w.DeriveInfo.Salt)
+		}
+	}
+	b, err := json.Marshal(&c)
+	if err != nil {
+		t.Fatalf("Failed to marshal container: %v", err)
+	}
+	return string(b)
+}
+
+// TestUppercaseHex checks if containers whose hex fields were uppercased by another tool still decrypt and verify.
+func TestUppercaseHex(t *testing.T) {
+	password := "password123"
+	iters := WithIterations(minIterations)
+	for name, opts := range map[string][]Option{
+		"ctr":      {iters, WithDedupTag(bytes.Repeat([]byte{1}, 16))},
+		"cbc":      {iters, WithCipher(CipherAES256CBC)},
+		"gcm":      {iters, WithCipher(CipherAES256GCM)},
+		"envelope": {iters, WithEnvelope()},
+	} {
+		upper := upperHexFields(t, mustCreate(t, "hello upper", password, opts...))
+		if upper == strings.ToLower(upper) {
+			t.Fatalf("%s: expected some uppercase hex", name)
+		}
+		decryptedText, err := Decrypt(upper, password)
+		if err != nil {
+			t.Fatalf("%s: error decrypting uppercased container: %v", name, err)
+		}
+		if decryptedText != "hello upper" {
+			t.Errorf("%s: expected 'hello upper', got '%s'", name, decryptedText)
+		}
+	}
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("Error generating key: %v", err)
+	}
+	signed, err := SignContainer(mustCreate(t, "hello upper", password, iters), priv)
+	if err != nil {
+		t.Fatalf("Error signing container: %v", err)
+	}
+	if err := VerifySignature(upperHexFields(t, signed), pub); err != nil {
+		t.Errorf("Expected the signature of an uppercased container to verify, got: %v", err)
+	}
+}
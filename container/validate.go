@@ -0,0 +1,126 @@
+package container
+
+import (
+	"crypto/aes"
+	"fmt"
+	"strings"
+)
+
+// Validate checks that c is structurally sound without deriving a key: that
+// its required fields are present, that its version, cipher, KDF and encoding
+// are supported, that its binary fields decode to plausible lengths and that
+// its KDF parameters are within bounds. It returns the first problem found,
+// wrapping ErrMalformedContainer, ErrUnsupportedVersion,
+// ErrUnsupportedAlgorithm, ErrInvalidHex, ErrInvalidBase64,
+// ErrIterationsTooHigh or ErrKDFParamsTooHigh. A known version with a
+// cipher it does not support, as left by a downgrade, is reported as
+// ErrUnsupportedVersion. Decryption calls it before doing anything else.
+func (c *Container) Validate() error {
+	header := Header{
+		ContainerMeta:  c.ContainerMeta,
+		DeriveInfo:     c.DeriveInfo,
+		EncryptionInfo: c.EncryptionInfo,
+		WrappedKeys:    c.WrappedKeys,
+	}
+	if err := header.checkRequired(); err != nil {
+		return err
+	}
+
+	dec, ok := decoders[c.ContainerMeta.Version]
+	if !ok {
+		return fmt.Errorf("%w: %q, supported versions are %s", ErrUnsupportedVersion, c.ContainerMeta.Version, strings.Join(supportedVersions(), ", "))
+	}
+	cipherName := c.cipherName()
+	ivLen, err := ivSize(cipherName)
+	if err != nil {
+		return err
+	}
+	if !dec.supports(cipherName) {
+		// A known version paired with a cipher it never uses means the
+		// container was modified, for example to downgrade it.
+		return fmt.Errorf("%w: %q does not support cipher %s", ErrUnsupportedVersion, c.ContainerMeta.Version, cipherName)
+	}
+	if !c.ContainerMeta.Encoding.valid() {
+		return fmt.Errorf("%w: unknown encoding %q", ErrMalformedContainer, c.ContainerMeta.Encoding)
+	}
+	if keySize := c.EncryptionInfo.keySize(); !validKeySize(keySize) {
+		return fmt.Errorf("%w: invalid key size %d", ErrMalformedContainer, keySize)
+	}
+	if _, err := c.ContainerMeta.expiry(); err != nil {
+		return err
+	}
+	if _, err := macHash(c.ContainerMeta.MACHash); err != nil {
+		return err
+	}
+	if c.ContainerMeta.MACHash != "" && !usesHMAC(cipherName) {
+		return fmt.Errorf("%w: %s has no separate MAC to set MACHash for", ErrMalformedContainer, cipherName)
+	}
+	if p := c.ContainerMeta.Padding; p < 0 || p > maxPadding {
+		return fmt.Errorf("%w: invalid Padding %d", ErrMalformedContainer, p)
+	}
+	if c.ContainerMeta.PlaintextLen < 0 {
+		return fmt.Errorf("%w: negative PlaintextLen %d", ErrMalformedContainer, c.ContainerMeta.PlaintextLen)
+	}
+
+	f, err := c.decodeFields()
+	if err != nil {
+		return err
+	}
+	if len(f.iv) != ivLen {
+		return fmt.Errorf("%w: IV is %d bytes, %s needs %d", ErrMalformedContainer, len(f.iv), cipherName, ivLen)
+	}
+	minCiphertext := dec.minCiphertext
+	if dec.envelope && !usesHMAC(cipherName) {
+		minCiphertext = aeadTagLen
+	}
+	if len(f.ciphertext) < minCiphertext {
+		return fmt.Errorf("%w: ciphertext is %d bytes, need at least %d", ErrMalformedContainer, len(f.ciphertext), minCiphertext)
+	}
+	if cipherName == CipherAES256CBC && (len(f.ciphertext) == 0 || len(f.ciphertext)%aes.BlockSize != 0) {
+		return fmt.Errorf("%w: CBC ciphertext is %d bytes, not a positive multiple of %d", ErrMalformedContainer, len(f.ciphertext), aes.BlockSize)
+	}
+	if len(f.itemSalt) > 0 && (dec.envelope || dec.legacy || c.DeriveInfo.KDF == KDFNone) {
+		return fmt.Errorf("%w: ItemSalt is only used by password containers since %s", ErrMalformedContainer, versionCTR)
+	}
+
+	if dec.envelope {
+		for i := range c.WrappedKeys {
+			if d := c.WrappedKeys[i].DeriveInfo; d != nil {
+				if d.ItemSalt != "" {
+					return fmt.Errorf("%w: wrapped key %d has an ItemSalt", ErrMalformedContainer, i)
+				}
+				if err := d.validate(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return c.DeriveInfo.validate()
+}
+
+// validate checks that d names a supported KDF with parameters in bounds.
+func (d *Derive) validate() error {
+	var err error
+	switch d.kdfName() {
+	case KDFPBKDF2, KDFPBKDF2SHA512:
+		return checkIterations(d.Iters)
+	case KDFArgon2id:
+		p := Argon2Params{Memory: d.Memory, Time: d.Time, Threads: d.Threads}
+		if err = p.validate(); err == nil {
+			return checkArgon2Cost(p)
+		}
+	case KDFScrypt:
+		if err = validateScrypt(d.N, d.R, d.P); err == nil {
+			return checkScryptCost(d.N, d.R, d.P)
+		}
+	case KDFNone:
+		return nil
+	default:
+		return fmt.Errorf("%w: KDF %q", ErrUnsupportedAlgorithm, d.KDF)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedContainer, err)
+	}
+	return nil
+}
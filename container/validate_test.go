@@ -0,0 +1,51 @@
+package container
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestValidate checks if Validate accepts a well-formed container and reports each structural problem with a typed error.
+func TestValidate(t *testing.T) {
+	password := "password123"
+	parse := func(containerJSON string) *Container {
+		var container Container
+		if err := json.Unmarshal([]byte(containerJSON), &container); err != nil {
+			t.Fatalf("Failed to unmarshal container: %v", err)
+		}
+		return &container
+	}
+	ctrJSON := mustCreate(t, "hello world", password, WithIterations(minIterations))
+	gcmJSON := mustCreate(t, "hello world", password, WithCipher(CipherAES256GCM), WithIterations(minIterations))
+
+	for _, containerJSON := range []string{ctrJSON, gcmJSON, legacyV10Container} {
+		if err := parse(containerJSON).Validate(); err != nil {
+			t.Errorf("Expected a valid container, got: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		containerJSON string
+		tamper        func(c *Container)
+		want          error
+	}{
+		{"bad salt hex", ctrJSON, func(c *Container) { c.DeriveInfo.Salt = "zz" + c.DeriveInfo.Salt }, ErrInvalidHex},
+		{"missing salt", ctrJSON, func(c *Container) { c.DeriveInfo.Salt = "" }, ErrMalformedContainer},
+		{"zero iters", ctrJSON, func(c *Container) { c.DeriveInfo.Iters = 0 }, ErrMalformedContainer},
+		{"too many iters", ctrJSON, func(c *Container) { c.DeriveInfo.Iters = MaxIterations + 1 }, ErrIterationsTooHigh},
+		{"short IV", ctrJSON, func(c *Container) { c.EncryptionInfo.IV = c.EncryptionInfo.IV[:16] }, ErrMalformedContainer},
+		{"empty ciphertext", gcmJSON, func(c *Container) { c.ContainedData.EncryptedData = "" }, ErrMalformedContainer},
+		{"missing version", ctrJSON, func(c *Container) { c.ContainerMeta.Version = "" }, ErrMalformedContainer},
+		{"unknown version", ctrJSON, func(c *Container) { c.ContainerMeta.Version = "v99" }, ErrUnsupportedVersion},
+		{"unknown KDF", ctrJSON, func(c *Container) { c.DeriveInfo.KDF = "md5" }, ErrUnsupportedAlgorithm},
+	}
+	for _, tt := range tests {
+		container := parse(tt.containerJSON)
+		tt.tamper(container)
+		if err := container.Validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: expected %v, got: %v", tt.name, tt.want, err)
+		}
+	}
+}
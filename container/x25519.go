@@ -0,0 +1,109 @@
+package container
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeyWrapX25519 wraps the content key with AES-256-GCM under a key agreed
+// between an ephemeral X25519 key pair and the recipient's key, in the style
+// of age.
+const KeyWrapX25519 = "x25519-hkdf-sha256"
+
+// CreateContainerX25519 encrypts plaintext into an envelope container that
+// only the holder of the X25519 private key matching recipientPub can open.
+// A fresh ephemeral key pair is generated for every container and its public
+// half is stored in the wrapped key. opts are applied as for CreateContainer.
+func CreateContainerX25519(plaintext string, recipientPub [32]byte, opts ...Option) (string, error) {
+	o, err := newOptions(append([]Option{WithEnvelope()}, opts...))
+	if err != nil {
+		return "", err
+	}
+	pt := []byte(plaintext)
+	defer zeroize(pt)
+	b, err := createEnvelope(pt, o, func(cek []byte) ([]WrappedKey, error) {
+		ephemeralPriv, err := readRandomBytes(o.rand, curve25519.ScalarSize)
+		if err != nil {
+			return nil, fmt.Errorf("generating ephemeral key: %w", err)
+		}
+		defer zeroize(ephemeralPriv)
+		ephemeralPub, err := curve25519.X25519(ephemeralPriv, curve25519.Basepoint)
+		if err != nil {
+			return nil, err
+		}
+		shared, err := curve25519.X25519(ephemeralPriv, recipientPub[:])
+		if err != nil {
+			return nil, err
+		}
+		defer zeroize(shared)
+		kek, err := x25519KEK(shared, ephemeralPub, recipientPub[:])
+		if err != nil {
+			return nil, err
+		}
+		defer zeroize(kek)
+
+		w := WrappedKey{Algorithm: KeyWrapX25519, EphemeralKey: hex.EncodeToString(ephemeralPub)}
+		if err := sealKey(&w, kek, cek, o.rand); err != nil {
+			return nil, err
+		}
+		return []WrappedKey{w}, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// DecryptContainerX25519 decrypts a container created with
+// CreateContainerX25519. It returns ErrHMACMismatch if recipientPriv does not
+// open any of the wrapped keys.
+func DecryptContainerX25519(containerJSON string, recipientPriv [32]byte) (string, error) {
+	return decryptString(containerJSON, "", decryptParams{unwrap: func(w *WrappedKey) ([]byte, error) {
+		return unwrapWithX25519(w, recipientPriv[:])
+	}})
+}
+
+func unwrapWithX25519(w *WrappedKey, recipientPriv []byte) ([]byte, error) {
+	if w.Algorithm != KeyWrapX25519 {
+		return nil, errNotRecipient
+	}
+	ephemeralPub, err := decodeHex(w.EphemeralKey)
+	if err != nil {
+		return nil, fmt.Errorf("decoding WrappedKey.EphemeralKey: %w", err)
+	}
+	if len(ephemeralPub) != curve25519.PointSize {
+		return nil, fmt.Errorf("%w: ephemeral key is %d bytes", ErrMalformedContainer, len(ephemeralPub))
+	}
+	recipientPub, err := curve25519.X25519(recipientPriv, curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	shared, err := curve25519.X25519(recipientPriv, ephemeralPub)
+	if err != nil {
+		// A low-order ephemeral key cannot come from an honest sender.
+		return nil, ErrHMACMismatch
+	}
+	defer zeroize(shared)
+	kek, err := x25519KEK(shared, ephemeralPub, recipientPub)
+	if err != nil {
+		return nil, err
+	}
+	defer zeroize(kek)
+	return openKey(w, kek)
+}
+
+// x25519KEK derives the key-encryption key from an X25519 shared secret,
+// binding both public keys as the HKDF salt.
+func x25519KEK(shared, ephemeralPub, recipientPub []byte) ([]byte, error) {
+	salt := append(append([]byte(nil), ephemeralPub...), recipientPub...)
+	kek := make([]byte, keyLen)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(KeyWrapX25519)), kek); err != nil {
+		return nil, err
+	}
+	return kek, nil
+}
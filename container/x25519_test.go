@@ -0,0 +1,53 @@
+package container
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+func generateX25519Key(t *testing.T) (priv, pub [32]byte) {
+	t.Helper()
+	if _, err := rand.Read(priv[:]); err != nil {
+		t.Fatalf("Error generating X25519 key: %v", err)
+	}
+	p, err := curve25519.X25519(priv[:], curve25519.Basepoint)
+	if err != nil {
+		t.Fatalf("Error computing X25519 public key: %v", err)
+	}
+	copy(pub[:], p)
+	return priv, pub
+}
+
+// TestX25519RoundTrip checks if an X25519 container opens with the recipient's private key only.
+func TestX25519RoundTrip(t *testing.T) {
+	priv, pub := generateX25519Key(t)
+	otherPriv, _ := generateX25519Key(t)
+	plaintext := "hello x25519"
+
+	containerJSON, err := CreateContainerX25519(plaintext, pub, WithCipher(CipherXChaCha20Poly1305))
+	if err != nil {
+		t.Fatalf("Error creating container: %v", err)
+	}
+	header, err := ParseHeader(containerJSON)
+	if err != nil {
+		t.Fatalf("Error parsing header: %v", err)
+	}
+	if len(header.WrappedKeys) != 1 || header.WrappedKeys[0].Algorithm != KeyWrapX25519 || len(header.WrappedKeys[0].EphemeralKey) != 64 {
+		t.Errorf("Expected one %s wrapped key with an ephemeral key, got %+v", KeyWrapX25519, header.WrappedKeys)
+	}
+
+	decryptedText, err := DecryptContainerX25519(containerJSON, priv)
+	if err != nil {
+		t.Fatalf("Error decrypting container: %v", err)
+	}
+	if decryptedText != plaintext {
+		t.Errorf("Expected %q, got %q", plaintext, decryptedText)
+	}
+
+	if _, err := DecryptContainerX25519(containerJSON, otherPriv); !errors.Is(err, ErrHMACMismatch) {
+		t.Errorf("Expected ErrHMACMismatch for a mismatched private key, got: %v", err)
+	}
+}
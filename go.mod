@@ -2,4 +2,9 @@ module github.com/muzonff/go-crypto-container
 
 go 1.21.7
 
-require golang.org/x/crypto v0.26.0
+require (
+	golang.org/x/crypto v0.26.0
+	golang.org/x/term v0.23.0
+)
+
+require golang.org/x/sys v0.23.0 // indirect